@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/Izumra/SKUD_OKEI/domain/entity"
+	"github.com/Izumra/SKUD_OKEI/internal/lib/req"
+	"github.com/Izumra/SKUD_OKEI/internal/orion"
+	"github.com/Izumra/SKUD_OKEI/internal/services/events"
+	"github.com/Izumra/SKUD_OKEI/internal/storage/main/sqlite"
+	"github.com/Izumra/SKUD_OKEI/lib/config"
+	"github.com/Izumra/SKUD_OKEI/lib/logger"
+)
+
+func main() {
+	configPath := flag.String("config", "./config/local.yaml", "path to the config file")
+	fromParam := flag.String("from", "", "date the events are imported from, for example 2024-01-01")
+	chunk := flag.Duration("chunk", 24*time.Hour, "range of the time imported in the single transaction")
+	flag.Parse()
+
+	logger := logger.New(logger.Local, os.Stdout)
+
+	from, err := time.ParseInLocation(time.DateOnly, *fromParam, time.Local)
+	if err != nil {
+		logger.Error("Неверный формат даты начала импорта", slog.Any("err", err))
+		os.Exit(1)
+	}
+
+	cfg := config.MustLoadByPath(*configPath)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
+	defer cancel()
+
+	db := sqlite.NewConnetion(cfg)
+
+	transport := req.NewTransport(req.TransportConfig{
+		Timeout:          cfg.IntegerServer.Timeout,
+		Retries:          cfg.IntegerServer.Retries,
+		RetryBackoff:     cfg.IntegerServer.RetryBackoff,
+		BreakerThreshold: cfg.IntegerServer.BreakerThreshold,
+		BreakerCooldown:  cfg.IntegerServer.BreakerCooldown,
+		MaxConcurrent:    cfg.IntegerServer.MaxConcurrent,
+	})
+	orionClient := orion.NewClient(cfg.Server.IntegerServAddr, transport)
+
+	ingester := events.NewIngester(logger, orionClient, db, cfg.Archive.Interval, cfg.Archive.Lag)
+
+	err = ingester.Backfill(ctx, from, *chunk, func(synced entity.ArchiveRange) {
+		logger.Info("Импортированы события", slog.Time("с", synced.From), slog.Time("по", synced.To))
+	})
+	if err != nil {
+		logger.Error("Импорт событий прерван, повторный запуск продолжит его с последнего периода", slog.Any("err", err))
+		os.Exit(1)
+	}
+
+	logger.Info("Импорт событий завершен")
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/Izumra/SKUD_OKEI/internal/orion/fake"
+	"github.com/Izumra/SKUD_OKEI/lib/logger"
+)
+
+func main() {
+	port := flag.Int("port", 8090, "port of the fake IntegrServ")
+	seed := flag.Int64("seed", 1, "seed of the generated dataset")
+	persons := flag.Int("persons", 200, "count of the generated persons")
+	days := flag.Int("days", 7, "count of the days with generated events")
+	interval := flag.Duration("interval", 5*time.Second, "interval of the simulated passes, 0 disables them")
+	flag.Parse()
+
+	logger := logger.New(logger.Local, os.Stdout)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
+	defer cancel()
+
+	dataset := fake.Generate(fake.DatasetConfig{
+		Seed:    *seed,
+		Persons: *persons,
+		Days:    *days,
+	})
+	server := fake.NewServer(dataset, *seed)
+
+	if *interval > 0 {
+		go server.Simulate(ctx, *interval)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/soap/IOrionPro", server)
+
+	httpServer := &http.Server{
+		Addr:    fmt.Sprintf(":%d", *port),
+		Handler: mux,
+	}
+	go func() {
+		<-ctx.Done()
+		httpServer.Shutdown(context.Background())
+	}()
+
+	logger.Info("Fake IntegrServ was launched", slog.String("addr", httpServer.Addr), slog.Int("persons", len(dataset.Persons)), slog.Int("events", len(dataset.Events)))
+	err := httpServer.ListenAndServe()
+	if err != nil && err != http.ErrServerClosed {
+		logger.Error("Occured the error while launching the fake IntegrServ", slog.Any("err", err))
+		os.Exit(1)
+	}
+}
@@ -1,5 +1,3 @@
-//go:build windows
-
 package main
 
 import (
@@ -8,14 +6,20 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
 	"github.com/Izumra/SKUD_OKEI/internal/app"
 	"github.com/Izumra/SKUD_OKEI/internal/lib/req"
+	"github.com/Izumra/SKUD_OKEI/internal/orion"
 	"github.com/Izumra/SKUD_OKEI/internal/services/auth"
+	"github.com/Izumra/SKUD_OKEI/internal/services/caching"
+	"github.com/Izumra/SKUD_OKEI/internal/services/evacuation"
 	"github.com/Izumra/SKUD_OKEI/internal/services/events"
+	"github.com/Izumra/SKUD_OKEI/internal/services/health"
 	"github.com/Izumra/SKUD_OKEI/internal/services/key"
 	"github.com/Izumra/SKUD_OKEI/internal/services/persons"
+	"github.com/Izumra/SKUD_OKEI/internal/services/presence"
+	"github.com/Izumra/SKUD_OKEI/internal/services/roles"
+	"github.com/Izumra/SKUD_OKEI/internal/storage/cache"
 	"github.com/Izumra/SKUD_OKEI/internal/storage/cache/embedded"
 	"github.com/Izumra/SKUD_OKEI/internal/storage/main/sqlite"
 	"github.com/Izumra/SKUD_OKEI/lib/config"
@@ -29,15 +33,39 @@ func main() {
 
 	logger := logger.New(logger.Local, os.Stdout)
 
+	err := cfg.Evacuation.LoadSecret()
+	if err != nil {
+		panic(err)
+	}
+	if cfg.Evacuation.Secret == "" {
+		logger.Warn("The secret of the evacuation reports is not set, the reports are not signed until EVACUATION_SECRET or the file of evacuation.secret_file is set")
+	}
+
 	ctx := context.Background()
 
 	db := sqlite.NewConnetion(cfg)
 
-	integrServiceUtil := integrServUtil.RebootManager(cfg.IntegerServer.TitleService, 20*time.Second)
+	transport := req.NewTransport(req.TransportConfig{
+		Timeout:          cfg.IntegerServer.Timeout,
+		Retries:          cfg.IntegerServer.Retries,
+		RetryBackoff:     cfg.IntegerServer.RetryBackoff,
+		BreakerThreshold: cfg.IntegerServer.BreakerThreshold,
+		BreakerCooldown:  cfg.IntegerServer.BreakerCooldown,
+		MaxConcurrent:    cfg.IntegerServer.MaxConcurrent,
+	})
+
+	recoverer, err := integrServUtil.FromConfig(logger, cfg.IntegerServer)
+	if err != nil {
+		panic(err)
+	}
 	go func() {
-		for {
-			<-req.IntegrServiceUtilExitERRChan
-			err := integrServiceUtil(context.Background())
+		for change := range transport.Breaker().Subscribe(1) {
+			if change.To != req.StateOpen {
+				continue
+			}
+
+			logger.Info("Служба IntegrServ недоступна", slog.Any("причина", change.Err))
+			err := recoverer.Recover(context.Background())
 			if err != nil {
 				logger.Info("Служба IntegrServ не перезагружена", slog.Any("причина", err))
 				continue
@@ -46,18 +74,63 @@ func main() {
 		}
 	}()
 
-	sessStore := embedded.NewSessStore()
+	// the sessions are kept in the database, so the users stay logged in
+	// after the restart, the memory is used when it is configured
+	sessTTL := cache.SessionTTL{
+		Absolute: cfg.Session.TTL,
+		Idle:     cfg.Session.IdleTTL,
+	}
+	var sessStore auth.ExpiringSessionStorage = sqlite.NewSessionStorage(db, sessTTL)
+	if cfg.Session.Storage == "memory" {
+		sessStore = embedded.NewSessStore(sessTTL, db)
+	}
+	sweeper := auth.NewSweeper(logger, sessStore, cfg.Session.SweepInterval)
+	go sweeper.Run(ctx)
+
+	orionClient := orion.NewCachedClient(orion.NewClient(cfg.Server.IntegerServAddr, transport), orion.CacheConfig{
+		DepartmentsTTL: cfg.Cache.DepartmentsTTL,
+		PersonsTTL:     cfg.Cache.PersonsTTL,
+		KeysTTL:        cfg.Cache.KeysTTL,
+	})
 
-	authService := auth.NewService(logger, sessStore, db, db)
-	eventsService := events.NewService(logger, sessStore, cfg.Server.IntegerServAddr)
-	cardService := key.NewService(logger, sessStore, eventsService, cfg.Server.IntegerServAddr)
-	personsService := persons.NewService(logger, eventsService, sessStore, cfg.Server.IntegerServAddr)
+	authService := auth.NewService(logger, sessStore, db, db, auth.PasswordPolicy{
+		MinLength:      cfg.Password.MinLength,
+		RequireUpper:   cfg.Password.RequireUpper,
+		RequireLower:   cfg.Password.RequireLower,
+		RequireDigit:   cfg.Password.RequireDigit,
+		RequireSpecial: cfg.Password.RequireSpecial,
+		Cost:           cfg.Password.Cost,
+	})
+	rolesService := roles.NewService(logger, db)
+	err = rolesService.Load(ctx)
+	if err != nil {
+		panic(err)
+	}
+	eventBus := events.NewBus(cfg.Poller.History)
+	eventsService := events.NewService(logger, orionClient, orionClient, db, eventBus, cfg.Monitor.DayStart)
+	poller := events.NewPoller(logger, eventsService, cfg.Poller.Interval, cfg.Poller.Overlap)
+	go poller.Run(ctx)
+	ingester := events.NewIngester(logger, orionClient, db, cfg.Archive.Interval, cfg.Archive.Lag)
+	go ingester.Run(ctx)
+	cardService := key.NewService(logger, sessStore, eventsService, orionClient)
+	personsService := persons.NewService(logger, eventsService, sessStore, orionClient)
+	healthService := health.NewService(logger, orionClient, recoverer, transport.Breaker(), transport.Limiter(), cfg.IntegerServer.HealthInterval)
+	cacheService := caching.NewService(logger, orionClient)
+	go healthService.Run(ctx)
+	presenceService := presence.NewService(logger, eventsService, orionClient, cfg.Presence.ExpireAfter)
+	go presenceService.Run(ctx)
+	evacuationService := evacuation.NewService(logger, db, presenceService, orionClient, cfg.Evacuation.Secret)
 
 	services := app.Services{
-		AuthService:    authService,
-		EventsService:  eventsService,
-		PersonsService: personsService,
-		CardService:    cardService,
+		AuthService:       authService,
+		EventsService:     eventsService,
+		PersonsService:    personsService,
+		CardService:       cardService,
+		HealthService:     healthService,
+		CacheService:      cacheService,
+		PresenceService:   presenceService,
+		EvacuationService: evacuationService,
+		RolesService:      rolesService,
 	}
 
 	server := app.NewServer(logger, sessStore, &services)
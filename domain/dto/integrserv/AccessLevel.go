@@ -0,0 +1,7 @@
+package integrserv
+
+type AccessLevel struct {
+	Id          int64
+	Name        string
+	Description string
+}
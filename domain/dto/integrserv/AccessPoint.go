@@ -0,0 +1,9 @@
+package integrserv
+
+type AccessPoint struct {
+	Id                int64
+	Name              string
+	Description       string
+	EnterAccessZoneId int
+	ExitAccessZoneId  int
+}
@@ -25,4 +25,5 @@ type Event struct {
 	CardNo        string
 	Description   string
 	AccessPointId int
+	EventTypeId   int64
 }
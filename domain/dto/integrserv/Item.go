@@ -0,0 +1,19 @@
+package integrserv
+
+const (
+	ItemTypeSection      = "SECTION"
+	ItemTypeLoop         = "LOOP"
+	ItemTypeDevice       = "DEVICE"
+	ItemTypeReader       = "READER"
+	ItemTypeRelay        = "RELAY"
+	ItemTypeAccessZone   = "ACCESSZONE"
+	ItemTypeAccessPoint  = "ACCESSPOINT"
+	ItemTypeSectionGroup = "SECTIONGROUP"
+)
+
+type Item struct {
+	Id          int64
+	ItemType    string
+	Name        string
+	Description string
+}
@@ -0,0 +1,13 @@
+package reqs
+
+const (
+	MonitorSubscribe = "subscribe"
+	MonitorResync    = "resync"
+)
+
+// MonitorRequest is the message of the client of the monitor, the message
+// without the Type is the subscription of the first version of the protocol.
+type MonitorRequest struct {
+	Type   string
+	Filter MonitorSubscription
+}
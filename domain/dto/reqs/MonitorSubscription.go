@@ -0,0 +1,9 @@
+package reqs
+
+type MonitorSubscription struct {
+	Departments  []int64
+	Persons      []int64
+	AccessPoints []int
+	PassModes    []int
+	EventTypes   []int64
+}
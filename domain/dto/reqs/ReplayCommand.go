@@ -0,0 +1,17 @@
+package reqs
+
+const (
+	ReplayPause = "pause"
+	ReplayPlay  = "play"
+	ReplaySeek  = "seek"
+	ReplaySpeed = "speed"
+)
+
+// ReplayCommand is the message of the client of the replay, At is used by the
+// seek command in the format "2006-01-02T15:04:05", Speed is used by the speed
+// command.
+type ReplayCommand struct {
+	Type  string
+	At    string
+	Speed float64
+}
@@ -0,0 +1,14 @@
+package reqs
+
+type RoleBody struct {
+	Name        string
+	Permissions []string
+}
+
+type UserRoleBody struct {
+	RoleId int64
+}
+
+type UserDepartmentsBody struct {
+	Departments []int64
+}
@@ -0,0 +1,10 @@
+package resp
+
+type CacheStats struct {
+	Name     string
+	Entries  int
+	Hits     int64
+	Misses   int64
+	Stale    int64
+	HitRatio float64
+}
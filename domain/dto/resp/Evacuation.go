@@ -0,0 +1,47 @@
+package resp
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type Evacuation struct {
+	Id         int64
+	StartedAt  time.Time
+	StartedBy  string
+	FinishedAt *time.Time
+	FinishedBy string
+	Total      int
+	Accounted  int
+	Remaining  int
+	// Departments are the persons not accounted for yet grouped by the
+	// departments.
+	Departments      []EvacuationDepartment
+	AccountedPersons []EvacuationPerson
+}
+
+type EvacuationDepartment struct {
+	DepartmentId int64
+	Name         string
+	Persons      []EvacuationPerson
+}
+
+type EvacuationPerson struct {
+	PersonId      int64
+	LastName      string
+	FirstName     string
+	MiddleName    string
+	TabNum        string
+	DepartmentId  int64
+	EnteredAt     time.Time
+	AccessPointId int
+	AccountedAt   *time.Time
+	AccountedBy   string
+}
+
+// EvacuationReport is the final report of the evacuation, Signature is the hex
+// encoded HMAC-SHA256 of the bytes of the Report.
+type EvacuationReport struct {
+	Report    json.RawMessage
+	Signature string
+}
@@ -0,0 +1,16 @@
+package resp
+
+type EventBus struct {
+	Seq           uint64
+	Subscribers   int
+	Subscriptions []EventBusSubscription
+}
+
+type EventBusSubscription struct {
+	Id      uint64
+	Name    string
+	Buffer  int
+	Lag     int
+	Dropped uint64
+	LastSeq uint64
+}
@@ -0,0 +1,52 @@
+package resp
+
+import "time"
+
+type Health struct {
+	Status string `value:"ok|degraded|unknown"`
+	Orion  OrionHealth
+}
+
+type OrionHealth struct {
+	Up        bool
+	CheckedAt time.Time
+	LatencyMs int64
+}
+
+type OrionStatus struct {
+	OrionHealth
+	Since            time.Time
+	LastError        string
+	AvgLatencyMs     int64
+	Breaker          string
+	RecoveryStrategy string
+	Limiter          OrionLimiter
+	Transitions      []OrionTransition
+	Reboots          []OrionReboot
+}
+
+type OrionLimiter struct {
+	MaxConcurrent int
+	InFlight      int
+	Queued        int
+	Calls         int64
+	Coalesced     int64
+	AvgWaitMs     int64
+	MaxWaitMs     int64
+	AvgLatencyMs  int64
+	MaxLatencyMs  int64
+}
+
+type OrionTransition struct {
+	At    time.Time
+	Up    bool
+	Error string
+}
+
+type OrionReboot struct {
+	StartedAt  time.Time
+	DurationMs int64
+	Strategy   string
+	Success    bool
+	Error      string
+}
@@ -0,0 +1,84 @@
+package resp
+
+import (
+	"time"
+
+	"github.com/Izumra/SKUD_OKEI/domain/dto/integrserv"
+)
+
+// MonitorProtocol is the version of the monitor protocol with the deltas, it
+// is selected by the query parameter protocol of the monitor socket.
+const MonitorProtocol = 2
+
+const (
+	// MonitorSnapshot carries the whole state, the client replaces its state
+	// by the snapshot.
+	MonitorSnapshot = "snapshot"
+	// MonitorDelta carries the new events and the changed counters, the client
+	// appends them to its state.
+	MonitorDelta = "delta"
+	// MonitorRollover carries the snapshot of the new operational day, the
+	// counters and the presence of the previous day are reset.
+	MonitorRollover = "rollover"
+	// MonitorResume carries the events missed by the reconnected client and
+	// the counters, the client appends them to the state it kept.
+	MonitorResume = "resume"
+	// MonitorGap tells the reconnected client that the missed events are not
+	// kept anymore, the snapshot replacing the state follows it.
+	MonitorGap = "gap"
+	// MonitorReplay carries the state of the replay of the past events.
+	MonitorReplay = "replay"
+	// MonitorError carries the error, the state of the client stays valid.
+	MonitorError = "error"
+)
+
+// MonitorMessage is the message of the monitor protocol, the field matching
+// the Type is filled. Seq grows by one with every message of the connection,
+// so the client sends the resync request when it sees the gap.
+type MonitorMessage struct {
+	Version int
+	Type    string
+	Seq     uint64
+	// Snapshot is filled by the snapshot and the rollover messages.
+	Snapshot *MonitorState
+	// Delta is filled by the delta and the resume messages.
+	Delta  *MonitorChanges
+	Replay *MonitorReplayState
+	Error  string
+}
+
+type MonitorState struct {
+	// DayBegin and DayEnd are the bounds of the operational day of the state.
+	DayBegin time.Time
+	DayEnd   time.Time
+	Counters MonitorCounters
+	Events   []integrserv.Event
+	// EventSeq is the sequence number of the last event of the bus covered by
+	// the message.
+	EventSeq uint64
+}
+
+type MonitorChanges struct {
+	// Counters is nil when the counters did not change.
+	Counters *MonitorCounters
+	Events   []integrserv.Event
+	EventSeq uint64
+}
+
+type MonitorCounters struct {
+	CountInside  int
+	CountOutside int
+	AnomalyIn    int
+	AnomalyOut   int
+}
+
+// MonitorReplayState is the state of the replay, At is the time of the replayed
+// events which the replay reached.
+type MonitorReplayState struct {
+	Begin    time.Time
+	End      time.Time
+	At       time.Time
+	Speed    float64
+	Paused   bool
+	Finished bool
+}
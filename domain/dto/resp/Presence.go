@@ -0,0 +1,25 @@
+package resp
+
+import "time"
+
+type Presence struct {
+	Count   int
+	Persons []PresentPerson
+}
+
+type PresentPerson struct {
+	PersonId      int64
+	LastName      string
+	FirstName     string
+	MiddleName    string
+	TabNum        string
+	DepartmentId  int64
+	EnteredAt     time.Time
+	AccessPointId int
+}
+
+type PresenceDepartment struct {
+	DepartmentId int64
+	Name         string
+	Count        int
+}
@@ -0,0 +1,10 @@
+package resp
+
+type Role struct {
+	Id          int64
+	Name        string
+	Permissions []string
+	// Protected is true for the role of the administrator, it is not edited
+	// or deleted, so the access to the management is never lost.
+	Protected bool
+}
@@ -0,0 +1,14 @@
+package entity
+
+import "time"
+
+// ArchiveRange is the range of the time [From, To) whose events are fully
+// copied from Orion to the local archive.
+type ArchiveRange struct {
+	From time.Time
+	To   time.Time
+}
+
+func (ar ArchiveRange) Covers(begin, end time.Time) bool {
+	return !begin.Before(ar.From) && !end.After(ar.To)
+}
@@ -0,0 +1,29 @@
+package entity
+
+import "time"
+
+// Evacuation is the roll call of the persons who were inside the building at
+// the moment of the activation, it is active until FinishedAt is set.
+type Evacuation struct {
+	Id         int64
+	StartedAt  time.Time
+	StartedBy  string
+	FinishedAt *time.Time
+	FinishedBy string
+	Persons    []EvacuationPerson
+}
+
+// EvacuationPerson is the person of the roll call, the person is accounted
+// for at the assembly point when AccountedAt is set.
+type EvacuationPerson struct {
+	PersonId      int64
+	LastName      string
+	FirstName     string
+	MiddleName    string
+	TabNum        string
+	DepartmentId  int64
+	EnteredAt     time.Time
+	AccessPointId int
+	AccountedAt   *time.Time
+	AccountedBy   string
+}
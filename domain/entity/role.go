@@ -0,0 +1,9 @@
+package entity
+
+import valueobject "github.com/Izumra/SKUD_OKEI/domain/value-object"
+
+type Role struct {
+	Id          valueobject.Role
+	Name        string
+	Permissions []valueobject.Permission
+}
@@ -7,4 +7,24 @@ type User struct {
 	Username string
 	Password string
 	Role     valueobject.Role
+	// Departments are the departments the user is linked to, the data of the
+	// other departments is hidden from the user.
+	Departments []int64
+	// AllDepartments is the access to the data of all the departments given
+	// by the permission of the role, it is resolved for every request and is
+	// not stored with the user.
+	AllDepartments bool
+}
+
+// Scope returns the departments available to the user, the user with the
+// access to all the departments gets the nil scope. The user without the
+// departments has the access to none of them.
+func (u *User) Scope() *valueobject.Scope {
+	if u == nil {
+		return valueobject.NewScope(nil)
+	}
+	if u.AllDepartments {
+		return nil
+	}
+	return valueobject.NewScope(u.Departments)
 }
@@ -8,5 +8,6 @@ import (
 
 type User interface {
 	AddUser(ctx context.Context, data entity.User) (int64, error)
+	UpdatePassword(ctx context.Context, id int64, password string) error
 	DeleteUserById(ctx context.Context, id int64) error
 }
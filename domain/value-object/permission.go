@@ -0,0 +1,37 @@
+package valueobject
+
+// Permission is the name of the action allowed by the role.
+type Permission string
+
+const (
+	PersonsRead       Permission = "persons.read"
+	PersonsWrite      Permission = "persons.write"
+	KeysRead          Permission = "keys.read"
+	KeysIssue         Permission = "keys.issue"
+	KeysBlock         Permission = "keys.block"
+	EventsRead        Permission = "events.read"
+	MonitorView       Permission = "monitor.view"
+	PresenceView      Permission = "presence.view"
+	EvacuationsManage Permission = "evacuations.manage"
+	UsersManage       Permission = "users.manage"
+	SystemManage      Permission = "system.manage"
+	// DepartmentsAll opens the data of all the departments, the users without
+	// it see only the data of their departments.
+	DepartmentsAll Permission = "departments.all"
+)
+
+// Permissions lists all the permissions known to the system.
+var Permissions = []Permission{
+	PersonsRead,
+	PersonsWrite,
+	KeysRead,
+	KeysIssue,
+	KeysBlock,
+	EventsRead,
+	MonitorView,
+	PresenceView,
+	EvacuationsManage,
+	UsersManage,
+	SystemManage,
+	DepartmentsAll,
+}
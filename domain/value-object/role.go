@@ -1,5 +1,7 @@
 package valueobject
 
+// The ids of the default roles, the permissions of the roles are stored in
+// the database.
 const (
 	AdminRole = iota
 	ModeratorRole
@@ -0,0 +1,33 @@
+package valueobject
+
+import (
+	"errors"
+	"slices"
+)
+
+var ErrOutOfScope = errors.New("данные не относятся к подразделениям пользователя")
+
+// Scope is the set of the departments whose persons, keys and events are
+// available to the user, the nil scope is the access to all the departments
+// and the empty one is the access to none of them.
+type Scope struct {
+	departments []int64
+}
+
+func NewScope(departments []int64) *Scope {
+	return &Scope{
+		departments: slices.Clone(departments),
+	}
+}
+
+func (s *Scope) Allows(departmentId int64) bool {
+	return s == nil || slices.Contains(s.departments, departmentId)
+}
+
+// Departments returns the departments of the scope, the nil scope has none.
+func (s *Scope) Departments() []int64 {
+	if s == nil {
+		return nil
+	}
+	return s.departments
+}
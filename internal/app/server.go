@@ -14,10 +14,15 @@ import (
 )
 
 type Services struct {
-	AuthService    controllers.AuthService
-	PersonsService controllers.PersonsService
-	EventsService  controllers.EventsService
-	CardService    controllers.CardService
+	AuthService       controllers.AuthService
+	PersonsService    controllers.PersonsService
+	EventsService     controllers.EventsService
+	CardService       controllers.CardService
+	HealthService     controllers.HealthService
+	CacheService      controllers.CacheService
+	PresenceService   controllers.PresenceService
+	EvacuationService controllers.EvacuationService
+	RolesService      controllers.RolesService
 }
 
 type Server struct {
@@ -42,6 +47,11 @@ func NewServer(
 		services.PersonsService,
 		services.EventsService,
 		services.CardService,
+		services.HealthService,
+		services.CacheService,
+		services.PresenceService,
+		services.EvacuationService,
+		services.RolesService,
 	)
 
 	return &Server{
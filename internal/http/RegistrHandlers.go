@@ -4,6 +4,7 @@ import (
 	_ "github.com/Izumra/SKUD_OKEI/docs"
 	"github.com/Izumra/SKUD_OKEI/internal/http/controllers"
 	"github.com/Izumra/SKUD_OKEI/internal/http/controllers/ws"
+	"github.com/Izumra/SKUD_OKEI/internal/http/middleware"
 	"github.com/Izumra/SKUD_OKEI/internal/services/auth"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -25,6 +26,11 @@ func RegistrHandlers(
 	personService controllers.PersonsService,
 	eventsService controllers.EventsService,
 	cardService controllers.CardService,
+	healthService controllers.HealthService,
+	cacheService controllers.CacheService,
+	presenceService controllers.PresenceService,
+	evacuationService controllers.EvacuationService,
+	rolesService controllers.RolesService,
 ) {
 	app.Use(cors.New(cors.Config{
 		AllowCredentials: true,
@@ -35,19 +41,41 @@ func RegistrHandlers(
 
 	app.Static("/", "dist")
 
+	controllers.RegistrAuthAPI(app, authService, sessionStorage)
+
 	api := app.Group("/api")
 
-	controllers.RegistrAuthAPI(app, authService, sessionStorage)
+	// the health is checked by the monitoring without the session, so it is
+	// registered before the authentication of the api
+	controllers.RegistrHealthAPI(api, healthService)
+
+	api.Use(middleware.Auth(sessionStorage, rolesService))
+	guard := middleware.Permissions(rolesService)
 
 	personsRouter := api.Group("/persons")
-	controllers.RegistrPersonsAPI(personsRouter, personService)
+	controllers.RegistrPersonsAPI(personsRouter, personService, guard)
+
+	adminRouter := api.Group("/admin")
 
 	eventsRouter := api.Group("/events")
-	controllers.RegistrEventAPI(eventsRouter, eventsService, sessionStorage)
+	controllers.RegistrEventAPI(eventsRouter, adminRouter, eventsService, guard)
+	ws.RegistrStreamAPI(eventsRouter, eventsService, personService, guard)
 
 	cardRouter := api.Group("/cards")
-	controllers.RegistrCardAPI(cardRouter, cardService)
+	controllers.RegistrCardAPI(cardRouter, cardService, guard)
+
+	controllers.RegistrOrionAPI(adminRouter, healthService, guard)
+	controllers.RegistrRolesAPI(adminRouter, rolesService, guard)
+
+	cacheRouter := adminRouter.Group("/cache")
+	controllers.RegistrCacheAPI(cacheRouter, cacheService, guard)
+
+	presenceRouter := api.Group("/presence")
+	controllers.RegistrPresenceAPI(presenceRouter, presenceService, guard)
+
+	evacuationRouter := api.Group("/evacuations")
+	controllers.RegistrEvacuationAPI(evacuationRouter, evacuationService, guard)
 
 	webSocketRouter := api.Group("/ws")
-	ws.RegistrWSAPI(webSocketRouter, eventsService, sessionStorage)
+	ws.RegistrWSAPI(webSocketRouter, eventsService, personService, evacuationService, rolesService)
 }
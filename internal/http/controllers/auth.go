@@ -46,6 +46,10 @@ func RegistrAuthAPI(router fiber.Router, as AuthService, ss auth.SessionStorage)
 // @Success 200 {object} response.Body{data=string,error=nil} "Завершение сессии"
 // @Router /logout [post]
 func (ac *AuthController) Logout(c *fiber.Ctx) error {
+	// the session may be already expired, so the error is ignored
+	if sessionId := c.Cookies("session", ""); sessionId != "" {
+		_ = ac.sessionStorage.DeleteByID(c.Context(), sessionId)
+	}
 	c.ClearCookie("session")
 	return c.JSON(response.SuccessRes("Пользователь вышел"))
 }
@@ -127,7 +131,7 @@ func (ac *AuthController) Registrate(c *fiber.Ctx) error {
 
 	result, err := ac.service.Registrate(c.Context(), data.Username, data.Password)
 	if err != nil {
-		c.Status(fiber.StatusInternalServerError)
+		c.Status(ErrStatus(err))
 		return c.JSON(response.BadRes(err))
 	}
 
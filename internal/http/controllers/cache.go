@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/Izumra/SKUD_OKEI/domain/dto/resp"
+	valueobject "github.com/Izumra/SKUD_OKEI/domain/value-object"
+	"github.com/Izumra/SKUD_OKEI/internal/http/middleware"
+	"github.com/Izumra/SKUD_OKEI/internal/lib/response"
+	"github.com/gofiber/fiber/v2"
+)
+
+type CacheService interface {
+	CacheStats(ctx context.Context) ([]resp.CacheStats, error)
+	FlushCache(ctx context.Context) error
+}
+
+type CacheController struct {
+	service CacheService
+}
+
+func RegistrCacheAPI(router fiber.Router, cs CacheService, guard middleware.Guard) {
+	cc := CacheController{
+		service: cs,
+	}
+
+	router.Get("/", guard(valueobject.SystemManage), cc.CacheStats())
+	router.Delete("/", guard(valueobject.SystemManage), cc.FlushCache())
+}
+
+// @Summary Статистика кэша справочных данных
+// @Description Метод API, позволяющий администратору получить количество записей и попаданий в кэш подразделений, сотрудников и ключей
+// @Tags Admin
+// @Produce  json
+// @Success 200 {object} response.Body{data=[]resp.CacheStats,error=nil} "Структура успешного ответа запроса статистики кэша"
+// @Failure 500 {object} response.Body{data=nil} "Структура неудачного ответа запроса статистики кэша"
+// @Router /api/admin/cache [get]
+func (cc *CacheController) CacheStats() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		result, err := cc.service.CacheStats(c.Context())
+		if err != nil {
+			c.Status(ErrStatus(err))
+			return c.JSON(response.BadRes(err))
+		}
+
+		return c.JSON(response.SuccessRes(result))
+	}
+}
+
+// @Summary Очистка кэша справочных данных
+// @Description Метод API, позволяющий администратору очистить кэш подразделений, сотрудников и ключей
+// @Tags Admin
+// @Produce  json
+// @Success 200 {object} response.Body{data=string,error=nil} "Структура успешного ответа запроса очистки кэша"
+// @Failure 500 {object} response.Body{data=nil} "Структура неудачного ответа запроса очистки кэша"
+// @Router /api/admin/cache [delete]
+func (cc *CacheController) FlushCache() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		err := cc.service.FlushCache(c.Context())
+		if err != nil {
+			c.Status(ErrStatus(err))
+			return c.JSON(response.BadRes(err))
+		}
+
+		return c.JSON(response.SuccessRes("Кэш очищен"))
+	}
+}
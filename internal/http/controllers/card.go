@@ -3,41 +3,46 @@ package controllers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 
 	"github.com/Izumra/SKUD_OKEI/domain/dto/integrserv"
 	"github.com/Izumra/SKUD_OKEI/domain/dto/reqs"
+	valueobject "github.com/Izumra/SKUD_OKEI/domain/value-object"
+	"github.com/Izumra/SKUD_OKEI/internal/http/middleware"
 	"github.com/Izumra/SKUD_OKEI/internal/lib/response"
+	"github.com/Izumra/SKUD_OKEI/internal/storage/cache"
 	"github.com/gofiber/fiber/v2"
 )
 
 type CardService interface {
-	GetKeys(ctx context.Context, sessionId string, offset int64, count int64) ([]*integrserv.KeyData, error)
-	GetKeyData(ctx context.Context, sessionId string, cardNo string) (*integrserv.KeyData, error)
-	UpdateKeyData(ctx context.Context, sessionId string, keyData *integrserv.KeyData) (*integrserv.KeyData, error)
-	AddKey(ctx context.Context, sessionId string, keyData *integrserv.KeyData) (*integrserv.KeyData, error)
-	ReadKeyCode(ctx context.Context, sessionId string, idReader int) (string, error)
-	ConvertWiegandToTouchMemory(ctx context.Context, sessionId string, code int, codeSize int) (string, error)
-	ConvertPinToTouchMemory(ctx context.Context, sessionId string, pin string) (string, error)
+	GetKeys(ctx context.Context, scope *valueobject.Scope, offset int64, count int64) ([]*integrserv.KeyData, error)
+	GetKeyData(ctx context.Context, scope *valueobject.Scope, cardNo string) (*integrserv.KeyData, error)
+	UpdateKeyData(ctx context.Context, scope *valueobject.Scope, keyData *integrserv.KeyData) (*integrserv.KeyData, error)
+	AddKey(ctx context.Context, scope *valueobject.Scope, keyData *integrserv.KeyData) (*integrserv.KeyData, error)
+	ReadKeyCode(ctx context.Context, idReader int) (string, error)
+	ConvertWiegandToTouchMemory(ctx context.Context, code int, codeSize int) (string, error)
+	ConvertPinToTouchMemory(ctx context.Context, pin string) (string, error)
 }
 
 type CardController struct {
 	service CardService
 }
 
-func RegistrCardAPI(router fiber.Router, cs CardService) {
+func RegistrCardAPI(router fiber.Router, cs CardService, guard middleware.Guard) {
 	ac := CardController{
 		service: cs,
 	}
 
-	router.Get("/by_card_number/:card_no", ac.GetKeyData())
-	router.Put("/", ac.UpdateKeyData())
-	router.Post("/", ac.AddKey())
-	router.Get("/read_card_number/:id_reader", ac.ReadCardNumber())
-	router.Post("/wiegand_to_touch_memory", ac.WiegandToTouchMemory())
-	router.Post("/pin_to_touch_memory/:code", ac.PinToTouchMemory())
-	router.Get("/:offset/:count", ac.GetKeys())
+	router.Get("/by_card_number/:card_no", guard(valueobject.KeysRead), ac.GetKeyData())
+	// the key is blocked and put into the stop list by the update
+	router.Put("/", guard(valueobject.KeysBlock), ac.UpdateKeyData())
+	router.Post("/", guard(valueobject.KeysIssue), ac.AddKey())
+	router.Get("/read_card_number/:id_reader", guard(valueobject.KeysIssue), ac.ReadCardNumber())
+	router.Post("/wiegand_to_touch_memory", guard(valueobject.KeysIssue), ac.WiegandToTouchMemory())
+	router.Post("/pin_to_touch_memory/:code", guard(valueobject.KeysIssue), ac.PinToTouchMemory())
+	router.Get("/:offset/:count", guard(valueobject.KeysRead), ac.GetKeys())
 
 }
 
@@ -52,8 +57,6 @@ func RegistrCardAPI(router fiber.Router, cs CardService) {
 // @Router /api/cards/{offset}/{count} [get]
 func (cc *CardController) GetKeys() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		session := c.Cookies("session", "")
-
 		offsetParam := c.Params("offset", "0")
 		countParam := c.Params("count", "0")
 
@@ -69,9 +72,9 @@ func (cc *CardController) GetKeys() fiber.Handler {
 			return c.JSON(response.BadRes(fmt.Errorf("Неверный формат параметра количества ключей")))
 		}
 
-		result, err := cc.service.GetKeys(c.Context(), session, offset, count)
+		result, err := cc.service.GetKeys(c.Context(), middleware.User(c).Scope(), offset, count)
 		if err != nil {
-			c.Status(fiber.StatusInternalServerError)
+			c.Status(ErrStatus(err))
 			return c.JSON(response.BadRes(err))
 		}
 
@@ -89,8 +92,6 @@ func (cc *CardController) GetKeys() fiber.Handler {
 // @Router /api/cards/by_card_number/{card_no} [get]
 func (cc *CardController) GetKeyData() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		session := c.Cookies("session", "")
-
 		cardNumberParam := c.Params("card_no")
 
 		if cardNumberParam == "" {
@@ -98,9 +99,12 @@ func (cc *CardController) GetKeyData() fiber.Handler {
 			return c.JSON(response.BadRes(fmt.Errorf("Номер карты не может быть пустым")))
 		}
 
-		result, err := cc.service.GetKeyData(c.Context(), session, cardNumberParam)
+		result, err := cc.service.GetKeyData(c.Context(), middleware.User(c).Scope(), cardNumberParam)
 		if err != nil {
-			c.Status(fiber.StatusInternalServerError)
+			if errors.Is(err, cache.ErrStaleData) {
+				return c.JSON(response.StaleRes(result))
+			}
+			c.Status(ErrStatus(err))
 			return c.JSON(response.BadRes(err))
 		}
 
@@ -118,8 +122,6 @@ func (cc *CardController) GetKeyData() fiber.Handler {
 // @Router /api/cards/read_card_number/{id_reader} [get]
 func (cc *CardController) ReadCardNumber() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		session := c.Cookies("session", "")
-
 		idReaderParam := c.Params("id_reader", "0")
 
 		idReader, err := strconv.Atoi(idReaderParam)
@@ -128,9 +130,9 @@ func (cc *CardController) ReadCardNumber() fiber.Handler {
 			return c.JSON(response.BadRes(fmt.Errorf("Неверный формат идентификатора считывателя")))
 		}
 
-		result, err := cc.service.ReadKeyCode(c.Context(), session, idReader)
+		result, err := cc.service.ReadKeyCode(c.Context(), idReader)
 		if err != nil {
-			c.Status(fiber.StatusInternalServerError)
+			c.Status(ErrStatus(err))
 			return c.JSON(response.BadRes(err))
 		}
 
@@ -149,8 +151,6 @@ func (cc *CardController) ReadCardNumber() fiber.Handler {
 // @Router /api/cards [post]
 func (cc *CardController) AddKey() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		session := c.Cookies("session")
-
 		var body integrserv.KeyData
 
 		if err := json.Unmarshal(c.Body(), &body); err != nil {
@@ -158,9 +158,9 @@ func (cc *CardController) AddKey() fiber.Handler {
 			return c.JSON(response.BadRes(fmt.Errorf("Неверный формат данных добавляемого ключа")))
 		}
 
-		result, err := cc.service.AddKey(c.Context(), session, &body)
+		result, err := cc.service.AddKey(c.Context(), middleware.User(c).Scope(), &body)
 		if err != nil {
-			c.Status(fiber.StatusInternalServerError)
+			c.Status(ErrStatus(err))
 			return c.JSON(response.BadRes(err))
 		}
 
@@ -179,8 +179,6 @@ func (cc *CardController) AddKey() fiber.Handler {
 // @Router /api/cards [put]
 func (cc *CardController) UpdateKeyData() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		session := c.Cookies("session")
-
 		var body integrserv.KeyData
 
 		if err := json.Unmarshal(c.Body(), &body); err != nil {
@@ -188,9 +186,9 @@ func (cc *CardController) UpdateKeyData() fiber.Handler {
 			return c.JSON(response.BadRes(fmt.Errorf("Неверный формат данных изменяемого ключа")))
 		}
 
-		result, err := cc.service.UpdateKeyData(c.Context(), session, &body)
+		result, err := cc.service.UpdateKeyData(c.Context(), middleware.User(c).Scope(), &body)
 		if err != nil {
-			c.Status(fiber.StatusInternalServerError)
+			c.Status(ErrStatus(err))
 			return c.JSON(response.BadRes(err))
 		}
 
@@ -209,17 +207,15 @@ func (cc *CardController) UpdateKeyData() fiber.Handler {
 // @Router /api/cards/wiegand_to_touch_memory [post]
 func (cc *CardController) WiegandToTouchMemory() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		session := c.Cookies("session")
-
 		var body reqs.WiegandToTouchMemory
 		if err := json.Unmarshal(c.Body(), &body); err != nil {
 			c.Status(fiber.StatusBadRequest)
 			return c.JSON(response.BadRes(ErrBodyParse))
 		}
 
-		code, err := cc.service.ConvertWiegandToTouchMemory(c.Context(), session, body.Code, body.CodeSize)
+		code, err := cc.service.ConvertWiegandToTouchMemory(c.Context(), body.Code, body.CodeSize)
 		if err != nil {
-			c.Status(fiber.StatusInternalServerError)
+			c.Status(ErrStatus(err))
 			return c.JSON(response.BadRes(err))
 		}
 
@@ -229,12 +225,11 @@ func (cc *CardController) WiegandToTouchMemory() fiber.Handler {
 
 func (cc *CardController) PinToTouchMemory() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		session := c.Cookies("session")
 		pinCode := c.Params("code")
 
-		code, err := cc.service.ConvertPinToTouchMemory(c.Context(), session, pinCode)
+		code, err := cc.service.ConvertPinToTouchMemory(c.Context(), pinCode)
 		if err != nil {
-			c.Status(fiber.StatusInternalServerError)
+			c.Status(ErrStatus(err))
 			return c.JSON(response.BadRes(err))
 		}
 
@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+
+	valueobject "github.com/Izumra/SKUD_OKEI/domain/value-object"
+	"github.com/Izumra/SKUD_OKEI/internal/lib/req"
+	"github.com/Izumra/SKUD_OKEI/internal/orion"
+	"github.com/Izumra/SKUD_OKEI/internal/services/auth"
+	"github.com/Izumra/SKUD_OKEI/internal/services/evacuation"
+	"github.com/Izumra/SKUD_OKEI/internal/services/presence"
+	"github.com/Izumra/SKUD_OKEI/internal/services/roles"
+	"github.com/Izumra/SKUD_OKEI/internal/storage"
+	"github.com/gofiber/fiber/v2"
+)
+
+// ErrStatus translates the error of the service to the HTTP status of the
+// response, the unknown errors are reported as internal ones.
+func ErrStatus(err error) int {
+	switch {
+	case errors.Is(err, orion.ErrPersonNotFound), errors.Is(err, orion.ErrKeyNotFound):
+		return fiber.StatusNotFound
+	case errors.Is(err, storage.ErrEvacuationNotFound), errors.Is(err, storage.ErrEvacuationPersonNotFound):
+		return fiber.StatusNotFound
+	case errors.Is(err, storage.ErrRoleNotFound), errors.Is(err, storage.ErrUserNotFound):
+		return fiber.StatusNotFound
+	case errors.Is(err, orion.ErrDuplicateCard):
+		return fiber.StatusConflict
+	case errors.Is(err, storage.ErrEvacuationActive), errors.Is(err, storage.ErrEvacuationFinished):
+		return fiber.StatusConflict
+	case errors.Is(err, storage.ErrRoleExist), errors.Is(err, storage.ErrRoleInUse), errors.Is(err, roles.ErrRoleProtected):
+		return fiber.StatusConflict
+	case errors.Is(err, valueobject.ErrOutOfScope):
+		return fiber.StatusForbidden
+	case errors.Is(err, orion.ErrInvalidParameters):
+		return fiber.StatusBadRequest
+	case errors.Is(err, roles.ErrRoleName), errors.Is(err, roles.ErrUnknownPermission):
+		return fiber.StatusBadRequest
+	case errors.Is(err, auth.ErrWeakPassword):
+		return fiber.StatusBadRequest
+	case errors.Is(err, auth.ErrUserAlreadyRegistered):
+		return fiber.StatusConflict
+	case errors.Is(err, req.ErrOrionUnavailable), errors.Is(err, presence.ErrNotSeeded):
+		return fiber.StatusServiceUnavailable
+	case errors.Is(err, evacuation.ErrNoSecret):
+		return fiber.StatusServiceUnavailable
+	case errors.Is(err, context.DeadlineExceeded):
+		return fiber.StatusGatewayTimeout
+	case errors.Is(err, req.ErrMalformedResponse), errors.Is(err, orion.ErrOrionInternal):
+		return fiber.StatusBadGateway
+	}
+
+	var fault *req.SOAPFault
+	if errors.As(err, &fault) {
+		return fiber.StatusBadGateway
+	}
+
+	return fiber.StatusInternalServerError
+}
@@ -0,0 +1,194 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Izumra/SKUD_OKEI/domain/dto/resp"
+	"github.com/Izumra/SKUD_OKEI/domain/entity"
+	valueobject "github.com/Izumra/SKUD_OKEI/domain/value-object"
+	"github.com/Izumra/SKUD_OKEI/internal/http/middleware"
+	"github.com/Izumra/SKUD_OKEI/internal/lib/response"
+	"github.com/gofiber/fiber/v2"
+)
+
+type EvacuationService interface {
+	Start(ctx context.Context, user *entity.User) (*resp.Evacuation, error)
+	Active(ctx context.Context) (*resp.Evacuation, error)
+	Evacuation(ctx context.Context, id int64) (*resp.Evacuation, error)
+	Account(ctx context.Context, user *entity.User, id int64, personId int64, accounted bool) (*resp.Evacuation, error)
+	Finish(ctx context.Context, user *entity.User, id int64) (*resp.EvacuationReport, error)
+	Report(ctx context.Context, id int64) (*resp.EvacuationReport, error)
+	Watch(id int64) (<-chan struct{}, func())
+}
+
+type EvacuationController struct {
+	service EvacuationService
+}
+
+func RegistrEvacuationAPI(router fiber.Router, es EvacuationService, guard middleware.Guard) {
+	ec := EvacuationController{
+		service: es,
+	}
+
+	router.Use(guard(valueobject.EvacuationsManage))
+	router.Post("/", ec.Start())
+	router.Get("/active", ec.Active())
+	router.Get("/:id", ec.Evacuation())
+	router.Post("/:id/persons/:person", ec.Account(true))
+	router.Delete("/:id/persons/:person", ec.Account(false))
+	router.Post("/:id/finish", ec.Finish())
+	router.Get("/:id/report", ec.Report())
+}
+
+// @Summary Начало эвакуации
+// @Description Метод API, позволяющий начать эвакуацию, список эвакуируемых составляется из людей, вошедших в здание и не вышедших из него на момент начала эвакуации
+// @Tags Evacuation
+// @Produce  json
+// @Success 200 {object} response.Body{data=resp.Evacuation,error=nil} "Структура успешного ответа запроса начала эвакуации"
+// @Failure 409 {object} response.Body{data=nil} "Эвакуация уже проводится"
+// @Router /api/evacuations [post]
+func (ec *EvacuationController) Start() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		result, err := ec.service.Start(c.Context(), middleware.User(c))
+		if err != nil {
+			c.Status(ErrStatus(err))
+			return c.JSON(response.BadRes(err))
+		}
+
+		return c.JSON(response.SuccessRes(result))
+	}
+}
+
+// @Summary Текущая эвакуация
+// @Description Метод API, позволяющий получить проводимую эвакуацию с оставшимися людьми по подразделениям
+// @Tags Evacuation
+// @Produce  json
+// @Success 200 {object} response.Body{data=resp.Evacuation,error=nil} "Структура успешного ответа запроса текущей эвакуации"
+// @Failure 404 {object} response.Body{data=nil} "Эвакуация не проводится"
+// @Router /api/evacuations/active [get]
+func (ec *EvacuationController) Active() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		result, err := ec.service.Active(c.Context())
+		if err != nil {
+			c.Status(ErrStatus(err))
+			return c.JSON(response.BadRes(err))
+		}
+
+		return c.JSON(response.SuccessRes(result))
+	}
+}
+
+// @Summary Эвакуация
+// @Description Метод API, позволяющий получить эвакуацию по идентификатору с оставшимися людьми по подразделениям
+// @Tags Evacuation
+// @Produce  json
+// @Param id path int true "Идентификатор эвакуации"
+// @Success 200 {object} response.Body{data=resp.Evacuation,error=nil} "Структура успешного ответа запроса эвакуации"
+// @Failure 404 {object} response.Body{data=nil} "Эвакуация не найдена"
+// @Router /api/evacuations/{id} [get]
+func (ec *EvacuationController) Evacuation() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := c.ParamsInt("id")
+		if err != nil {
+			c.Status(fiber.StatusBadRequest)
+			return c.JSON(response.BadRes(fmt.Errorf("Неверный формат идентификатора эвакуации")))
+		}
+
+		result, err := ec.service.Evacuation(c.Context(), int64(id))
+		if err != nil {
+			c.Status(ErrStatus(err))
+			return c.JSON(response.BadRes(err))
+		}
+
+		return c.JSON(response.SuccessRes(result))
+	}
+}
+
+// @Summary Отметка человека на месте сбора
+// @Description Метод API, позволяющий отметить человека на месте сбора (POST) или снять отметку (DELETE), остальные устройства получают изменения по WebSocket /api/ws/evacuations/{id}
+// @Tags Evacuation
+// @Produce  json
+// @Param id path int true "Идентификатор эвакуации"
+// @Param person path int true "Идентификатор человека"
+// @Success 200 {object} response.Body{data=resp.Evacuation,error=nil} "Структура успешного ответа запроса отметки человека"
+// @Failure 404 {object} response.Body{data=nil} "Человек не входит в список эвакуации"
+// @Failure 409 {object} response.Body{data=nil} "Эвакуация уже завершена"
+// @Router /api/evacuations/{id}/persons/{person} [post]
+// @Router /api/evacuations/{id}/persons/{person} [delete]
+func (ec *EvacuationController) Account(accounted bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := c.ParamsInt("id")
+		if err != nil {
+			c.Status(fiber.StatusBadRequest)
+			return c.JSON(response.BadRes(fmt.Errorf("Неверный формат идентификатора эвакуации")))
+		}
+
+		person, err := c.ParamsInt("person")
+		if err != nil {
+			c.Status(fiber.StatusBadRequest)
+			return c.JSON(response.BadRes(fmt.Errorf("Неверный формат идентификатора человека")))
+		}
+
+		result, err := ec.service.Account(c.Context(), middleware.User(c), int64(id), int64(person), accounted)
+		if err != nil {
+			c.Status(ErrStatus(err))
+			return c.JSON(response.BadRes(err))
+		}
+
+		return c.JSON(response.SuccessRes(result))
+	}
+}
+
+// @Summary Завершение эвакуации
+// @Description Метод API, позволяющий завершить эвакуацию, итоговый отчет подписывается HMAC-SHA256 и сохраняется на сервере
+// @Tags Evacuation
+// @Produce  json
+// @Param id path int true "Идентификатор эвакуации"
+// @Success 200 {object} response.Body{data=resp.EvacuationReport,error=nil} "Структура успешного ответа запроса завершения эвакуации"
+// @Failure 409 {object} response.Body{data=nil} "Эвакуация уже завершена"
+// @Failure 503 {object} response.Body{data=nil} "Не задан ключ подписи отчета"
+// @Router /api/evacuations/{id}/finish [post]
+func (ec *EvacuationController) Finish() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := c.ParamsInt("id")
+		if err != nil {
+			c.Status(fiber.StatusBadRequest)
+			return c.JSON(response.BadRes(fmt.Errorf("Неверный формат идентификатора эвакуации")))
+		}
+
+		result, err := ec.service.Finish(c.Context(), middleware.User(c), int64(id))
+		if err != nil {
+			c.Status(ErrStatus(err))
+			return c.JSON(response.BadRes(err))
+		}
+
+		return c.JSON(response.SuccessRes(result))
+	}
+}
+
+// @Summary Отчет об эвакуации
+// @Description Метод API, позволяющий получить подписанный итоговый отчет завершенной эвакуации
+// @Tags Evacuation
+// @Produce  json
+// @Param id path int true "Идентификатор эвакуации"
+// @Success 200 {object} response.Body{data=resp.EvacuationReport,error=nil} "Структура успешного ответа запроса отчета об эвакуации"
+// @Failure 409 {object} response.Body{data=nil} "Эвакуация еще проводится"
+// @Router /api/evacuations/{id}/report [get]
+func (ec *EvacuationController) Report() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := c.ParamsInt("id")
+		if err != nil {
+			c.Status(fiber.StatusBadRequest)
+			return c.JSON(response.BadRes(fmt.Errorf("Неверный формат идентификатора эвакуации")))
+		}
+
+		result, err := ec.service.Report(c.Context(), int64(id))
+		if err != nil {
+			c.Status(ErrStatus(err))
+			return c.JSON(response.BadRes(err))
+		}
+
+		return c.JSON(response.SuccessRes(result))
+	}
+}
@@ -1,44 +1,61 @@
 package controllers
 
 import (
+	"bufio"
 	"context"
-	"encoding/xml"
+	"encoding/csv"
+	"errors"
 	"fmt"
+	"io"
 	"strconv"
 	"time"
 
 	"github.com/Izumra/SKUD_OKEI/domain/dto/integrserv"
 	"github.com/Izumra/SKUD_OKEI/domain/dto/reqs"
+	"github.com/Izumra/SKUD_OKEI/domain/dto/resp"
+	valueobject "github.com/Izumra/SKUD_OKEI/domain/value-object"
+	"github.com/Izumra/SKUD_OKEI/internal/http/middleware"
 	"github.com/Izumra/SKUD_OKEI/internal/lib/response"
-	"github.com/Izumra/SKUD_OKEI/internal/services/auth"
+	"github.com/Izumra/SKUD_OKEI/internal/services/events"
 	"github.com/gofiber/fiber/v2"
 )
 
+// maxExportRange limits the period of the single export, so it does not hold
+// the connection and 'Орион Про' for too long.
+const maxExportRange = 31 * 24 * time.Hour
+
+var ErrExportRange = errors.New("Начало периода выгрузки должно предшествовать его окончанию, период не должен превышать 31 день")
+
 type EventsService interface {
-	GetEvents(ctx context.Context, eventsFilter *integrserv.EventFilter) ([]integrserv.Event, error)
-	GetEventsCount(ctx context.Context, eventsFilter *integrserv.EventCountFilter) (int64, error)
+	GetEvents(ctx context.Context, scope *valueobject.Scope, eventsFilter *integrserv.EventFilter) ([]integrserv.Event, error)
+	GetEventsCount(ctx context.Context, scope *valueobject.Scope, eventsFilter *integrserv.EventCountFilter) (int64, error)
+	IterateEvents(ctx context.Context, begin, end time.Time, opts events.IterateOptions, fn func(event integrserv.Event) error) error
+	Subscribe(name string, buffer int, filter func(event integrserv.Event) bool) *events.Subscription
+	SubscribeSince(seq uint64, name string, buffer int, filter func(event integrserv.Event) bool) (*events.Subscription, []events.BusEvent, bool)
+	OperationalDay(moment time.Time) (time.Time, time.Time)
+	DayEvents(ctx context.Context, moment time.Time) (time.Time, time.Time, []integrserv.Event, error)
+	LookupEvent(eventId string) (events.BusEvent, bool)
+	BusStats(ctx context.Context) (*resp.EventBus, error)
 }
 
 type EventsController struct {
-	sessStorage auth.SessionStorage
-	service     EventsService
+	service EventsService
 }
 
-func RegistrEventAPI(router fiber.Router, es EventsService, ss auth.SessionStorage) {
+func RegistrEventAPI(router fiber.Router, adminRouter fiber.Router, es EventsService, guard middleware.Guard) {
 	ec := EventsController{
-		sessStorage: ss,
-		service:     es,
+		service: es,
 	}
 
-	router.Post("/count", ec.GetEventsCount())
-	router.Post("/:offset/:count", ec.GetEvents())
+	router.Post("/count", guard(valueobject.EventsRead), ec.GetEventsCount())
+	router.Post("/export", guard(valueobject.EventsRead), ec.ExportEvents())
+	router.Post("/:offset/:count", guard(valueobject.EventsRead), ec.GetEvents())
+	adminRouter.Get("/events/bus", guard(valueobject.SystemManage), ec.BusStats())
 }
 
 func (ec *EventsController) GetEventsCount() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 
-		_ = c.Cookies("session", "")
-
 		reqBody := reqs.ReqEventFilter{}
 
 		err := c.BodyParser(&reqBody)
@@ -61,9 +78,6 @@ func (ec *EventsController) GetEventsCount() fiber.Handler {
 		}
 
 		filter := integrserv.EventCountFilter{
-			XMLName: xml.Name{
-				Local: "GetEventsCount",
-			},
 			BeginTime: beginTime,
 			EndTime:   endTime,
 			EventTypes: integrserv.EventTypes{
@@ -73,9 +87,9 @@ func (ec *EventsController) GetEventsCount() fiber.Handler {
 				PersonData: reqBody.Persons,
 			},
 		}
-		result, err := ec.service.GetEventsCount(c.Context(), &filter)
+		result, err := ec.service.GetEventsCount(c.Context(), middleware.User(c).Scope(), &filter)
 		if err != nil {
-			c.Status(fiber.StatusInternalServerError)
+			c.Status(ErrStatus(err))
 			return c.JSON(response.BadRes(err))
 		}
 
@@ -86,8 +100,6 @@ func (ec *EventsController) GetEventsCount() fiber.Handler {
 func (ec *EventsController) GetEvents() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 
-		_ = c.Cookies("session", "")
-
 		reqBody := reqs.ReqEventFilter{}
 
 		err := c.BodyParser(&reqBody)
@@ -110,23 +122,20 @@ func (ec *EventsController) GetEvents() fiber.Handler {
 		}
 
 		offsetParam := c.Params("offset", "0")
-		_, err = strconv.ParseInt(offsetParam, 10, 0)
+		offset, err := strconv.ParseInt(offsetParam, 10, 0)
 		if err != nil {
 			c.Status(fiber.StatusBadRequest)
 			return c.JSON(response.BadRes(fmt.Errorf(" Неверный формат шага смещения")))
 		}
 
 		countParam := c.Params("count", "0")
-		_, err = strconv.ParseInt(countParam, 10, 0)
+		count, err := strconv.ParseInt(countParam, 10, 0)
 		if err != nil {
 			c.Status(fiber.StatusBadRequest)
 			return c.JSON(response.BadRes(fmt.Errorf(" Неверный формат количества событий")))
 		}
 
 		filter := integrserv.EventFilter{
-			XMLName: xml.Name{
-				Local: "GetEvents",
-			},
 			BeginTime: beginTime,
 			EndTime:   endTime,
 			EventTypes: integrserv.EventTypes{
@@ -135,11 +144,142 @@ func (ec *EventsController) GetEvents() fiber.Handler {
 			Persons: integrserv.Persons{
 				PersonData: reqBody.Persons,
 			},
+			Offset: offset,
+			Count:  count,
 		}
 
-		result, err := ec.service.GetEvents(c.Context(), &filter)
+		result, err := ec.service.GetEvents(c.Context(), middleware.User(c).Scope(), &filter)
+		if err != nil {
+			c.Status(ErrStatus(err))
+			return c.JSON(response.BadRes(err))
+		}
+
+		return c.JSON(response.SuccessRes(result))
+	}
+}
+
+// @Summary Выгрузка событий СКУД
+// @Description Метод API, позволяющий выгрузить события СКУД за произвольный период в формате CSV, события передаются клиенту по мере получения от 'Орион Про'
+// @Tags Events
+// @Accept  json
+// @Produce  text/csv
+// @Param ReqEventFilter body reqs.ReqEventFilter true "Тело запроса формата 'application/json', содержащее фильтр событий"
+// @Success 200 {string} string "Файл CSV с событиями"
+// @Failure 400 {object} response.Body{data=nil} "Структура неудачного ответа запроса выгрузки событий"
+// @Router /api/events/export [post]
+func (ec *EventsController) ExportEvents() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+
+		reqBody := reqs.ReqEventFilter{}
+
+		err := c.BodyParser(&reqBody)
+		if err != nil {
+			c.Status(fiber.StatusBadRequest)
+			return c.JSON(response.BadRes(ErrBodyParse))
+		}
+
+		layout := "2006-01-02T15:04:05"
+		beginTime, err := time.Parse(layout, reqBody.BeginTime)
+		if err != nil {
+			c.Status(fiber.StatusBadRequest)
+			return c.JSON(response.BadRes(ErrBodyParse))
+		}
+
+		endTime, err := time.Parse(layout, reqBody.EndTime)
+		if err != nil {
+			c.Status(fiber.StatusBadRequest)
+			return c.JSON(response.BadRes(ErrBodyParse))
+		}
+
+		if !beginTime.Before(endTime) || endTime.Sub(beginTime) > maxExportRange {
+			c.Status(fiber.StatusBadRequest)
+			return c.JSON(response.BadRes(ErrExportRange))
+		}
+
+		opts := events.IterateOptions{
+			EventTypes: reqBody.EventTypes,
+			Persons:    reqBody.Persons,
+			Scope:      middleware.User(c).Scope(),
+		}
+
+		// The body is written after the handler returns, the iteration over
+		// 'Орион Про' lives as long as fasthttp reads the stream and stops
+		// when it is closed on the finish of the response or the failed write
+		// to the client.
+		ctx, cancel := context.WithCancel(context.Background())
+		pr, pw := io.Pipe()
+
+		go func() {
+			defer cancel()
+
+			w := bufio.NewWriter(pw)
+			w.WriteString("\uFEFF")
+
+			writer := csv.NewWriter(w)
+			writer.Comma = ';'
+			writer.Write([]string{"EventId", "EventDate", "PassMode", "LastName", "FirstName", "MiddleName", "TabNum", "PersonId", "CardNo", "AccessPointId", "Description"})
+
+			err := ec.service.IterateEvents(ctx, beginTime, endTime, opts, func(event integrserv.Event) error {
+				return writer.Write([]string{
+					event.EventId,
+					event.EventDate.Format(time.DateTime),
+					strconv.Itoa(event.PassMode),
+					event.LastName,
+					event.FirstName,
+					event.MiddleName,
+					event.TabNum,
+					strconv.FormatInt(event.PersonId, 10),
+					event.CardNo,
+					strconv.Itoa(event.AccessPointId),
+					event.Description,
+				})
+			})
+			if err == nil {
+				writer.Flush()
+				err = writer.Error()
+			}
+			if err == nil {
+				err = w.Flush()
+			}
+
+			// The headers are already sent, the failed stream is broken off
+			// without the final chunk, so the client sees the export as
+			// failed instead of the truncated but valid file.
+			pw.CloseWithError(err)
+		}()
+
+		c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+		c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="events_%s_%s.csv"`, beginTime.Format("20060102"), endTime.Format("20060102")))
+		c.Context().SetBodyStream(&exportStream{PipeReader: pr, cancel: cancel}, -1)
+
+		return nil
+	}
+}
+
+// exportStream is the body of the export, closing it stops the iteration
+// over the events.
+type exportStream struct {
+	*io.PipeReader
+	cancel context.CancelFunc
+}
+
+func (s *exportStream) Close() error {
+	s.cancel()
+	return s.PipeReader.Close()
+}
+
+// @Summary Состояние шины событий
+// @Description Метод API, позволяющий администратору узнать количество подписчиков шины событий СКУД, их отставание и количество пропущенных ими событий
+// @Tags Admin
+// @Produce  json
+// @Success 200 {object} response.Body{data=resp.EventBus,error=nil} "Структура успешного ответа запроса состояния шины событий"
+// @Failure 500 {object} response.Body{data=nil} "Структура неудачного ответа запроса состояния шины событий"
+// @Router /api/admin/events/bus [get]
+func (ec *EventsController) BusStats() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		result, err := ec.service.BusStats(c.Context())
 		if err != nil {
-			c.Status(fiber.StatusInternalServerError)
+			c.Status(ErrStatus(err))
 			return c.JSON(response.BadRes(err))
 		}
 
@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/Izumra/SKUD_OKEI/domain/dto/resp"
+	valueobject "github.com/Izumra/SKUD_OKEI/domain/value-object"
+	"github.com/Izumra/SKUD_OKEI/internal/http/middleware"
+	"github.com/Izumra/SKUD_OKEI/internal/lib/response"
+	"github.com/gofiber/fiber/v2"
+)
+
+type HealthService interface {
+	Health(ctx context.Context) *resp.Health
+	OrionStatus(ctx context.Context) (*resp.OrionStatus, error)
+	RestartOrion(ctx context.Context) error
+}
+
+type HealthController struct {
+	service HealthService
+}
+
+func RegistrHealthAPI(router fiber.Router, hs HealthService) {
+	hc := HealthController{
+		service: hs,
+	}
+
+	router.Get("/health", hc.Health())
+}
+
+func RegistrOrionAPI(adminRouter fiber.Router, hs HealthService, guard middleware.Guard) {
+	hc := HealthController{
+		service: hs,
+	}
+
+	adminRouter.Get("/orion/status", guard(valueobject.SystemManage), hc.OrionStatus())
+	adminRouter.Post("/orion/restart", guard(valueobject.SystemManage), hc.RestartOrion())
+}
+
+// @Summary Состояние системы
+// @Description Метод API, позволяющий узнать доступность службы IntegrServ 'Орион Про' и время отклика при последней проверке
+// @Tags Health
+// @Produce  json
+// @Success 200 {object} response.Body{data=resp.Health,error=nil} "Структура ответа запроса состояния системы"
+// @Router /api/health [get]
+func (hc *HealthController) Health() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return c.JSON(response.SuccessRes(hc.service.Health(c.Context())))
+	}
+}
+
+// @Summary Подробное состояние службы IntegrServ
+// @Description Метод API, позволяющий администратору получить историю доступности службы IntegrServ, состояние автомата защиты и историю перезапусков службы
+// @Tags Admin
+// @Produce  json
+// @Success 200 {object} response.Body{data=resp.OrionStatus,error=nil} "Структура успешного ответа запроса состояния службы IntegrServ"
+// @Failure 500 {object} response.Body{data=nil} "Структура неудачного ответа запроса состояния службы IntegrServ"
+// @Router /api/admin/orion/status [get]
+func (hc *HealthController) OrionStatus() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		result, err := hc.service.OrionStatus(c.Context())
+		if err != nil {
+			c.Status(ErrStatus(err))
+			return c.JSON(response.BadRes(err))
+		}
+
+		return c.JSON(response.SuccessRes(result))
+	}
+}
+
+// @Summary Перезапуск службы IntegrServ
+// @Description Метод API, позволяющий администратору принудительно перезапустить службу IntegrServ 'Орион Про'
+// @Tags Admin
+// @Produce  json
+// @Success 200 {object} response.Body{data=string,error=nil} "Структура успешного ответа запроса перезапуска службы IntegrServ"
+// @Failure 500 {object} response.Body{data=nil} "Структура неудачного ответа запроса перезапуска службы IntegrServ"
+// @Router /api/admin/orion/restart [post]
+func (hc *HealthController) RestartOrion() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		err := hc.service.RestartOrion(c.Context())
+		if err != nil {
+			c.Status(ErrStatus(err))
+			return c.JSON(response.BadRes(err))
+		}
+
+		return c.JSON(response.SuccessRes("Служба IntegrServ перезапущена"))
+	}
+}
@@ -10,7 +10,10 @@ import (
 
 	"github.com/Izumra/SKUD_OKEI/domain/dto/integrserv"
 	"github.com/Izumra/SKUD_OKEI/domain/dto/resp"
+	valueobject "github.com/Izumra/SKUD_OKEI/domain/value-object"
+	"github.com/Izumra/SKUD_OKEI/internal/http/middleware"
 	"github.com/Izumra/SKUD_OKEI/internal/lib/response"
+	"github.com/Izumra/SKUD_OKEI/internal/storage/cache"
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -20,35 +23,36 @@ var (
 )
 
 type PersonsService interface {
-	GetPersons(ctx context.Context, sessionId string, offset int64, count int64, filters []string) ([]*integrserv.PersonData, error)
-	GetPersonsCount(ctx context.Context, sessionId string) (int64, error)
-	GetPersonById(ctx context.Context, sessionId string, id int64) (*integrserv.PersonData, error)
-	AddPerson(ctx context.Context, sessionId string, data integrserv.PersonData) (*integrserv.PersonData, error)
-	UpdatePerson(ctx context.Context, sessionId string, data integrserv.PersonData) (*integrserv.PersonData, error)
-	DeletePerson(ctx context.Context, sessionId string, data integrserv.PersonData) (*integrserv.PersonData, error)
-	GetDepartments(ctx context.Context, sessionId string) ([]*integrserv.Department, error)
-	GetDaylyUserStats(ctx context.Context, sessionId string, id int64, date time.Time) ([]*resp.Action, error)
-	GetMonthlyUserStats(ctx context.Context, sessionId string, id int64, monthTime time.Time) ([]*resp.Activity, error)
+	GetPersons(ctx context.Context, scope *valueobject.Scope, offset int64, count int64, filters []string) ([]*integrserv.PersonData, error)
+	GetPersonsCount(ctx context.Context, scope *valueobject.Scope) (int64, error)
+	GetPersonById(ctx context.Context, scope *valueobject.Scope, id int64) (*integrserv.PersonData, error)
+	AddPerson(ctx context.Context, scope *valueobject.Scope, data integrserv.PersonData) (*integrserv.PersonData, error)
+	UpdatePerson(ctx context.Context, scope *valueobject.Scope, data integrserv.PersonData) (*integrserv.PersonData, error)
+	DeletePerson(ctx context.Context, scope *valueobject.Scope, data integrserv.PersonData) (*integrserv.PersonData, error)
+	GetDepartments(ctx context.Context, scope *valueobject.Scope) ([]*integrserv.Department, error)
+	GetDaylyUserStats(ctx context.Context, scope *valueobject.Scope, id int64, date time.Time) ([]*resp.Action, error)
+	GetMonthlyUserStats(ctx context.Context, scope *valueobject.Scope, id int64, monthTime time.Time) ([]*resp.Activity, error)
+	DepartmentPersons(ctx context.Context, departments []int64) (map[int64]bool, error)
 }
 
 type PersonsController struct {
 	service PersonsService
 }
 
-func RegistrPersonsAPI(router fiber.Router, ps PersonsService) {
+func RegistrPersonsAPI(router fiber.Router, ps PersonsService, guard middleware.Guard) {
 	pc := PersonsController{
 		service: ps,
 	}
 
-	router.Get("/count", pc.GetPersonsCount)
-	router.Get("/departments", pc.GetDepartments)
-	router.Post("/", pc.AddPerson)
-	router.Delete("/", pc.DeletePerson)
-	router.Put("/", pc.UpdatePerson)
-	router.Post("/filter/:offset/:count/", pc.GetPersons)
-	router.Get("/:id", pc.GetPersonById)
-	router.Get("/activity/dayly/:date/:id", pc.GetDaylyUserStats)
-	router.Get("/activity/monthly/:date/:id", pc.GetMonthlyUserStats)
+	router.Get("/count", guard(valueobject.PersonsRead), pc.GetPersonsCount)
+	router.Get("/departments", guard(valueobject.PersonsRead), pc.GetDepartments)
+	router.Post("/", guard(valueobject.PersonsWrite), pc.AddPerson)
+	router.Delete("/", guard(valueobject.PersonsWrite), pc.DeletePerson)
+	router.Put("/", guard(valueobject.PersonsWrite), pc.UpdatePerson)
+	router.Post("/filter/:offset/:count/", guard(valueobject.PersonsRead), pc.GetPersons)
+	router.Get("/:id", guard(valueobject.PersonsRead), pc.GetPersonById)
+	router.Get("/activity/dayly/:date/:id", guard(valueobject.PersonsRead), pc.GetDaylyUserStats)
+	router.Get("/activity/monthly/:date/:id", guard(valueobject.PersonsRead), pc.GetMonthlyUserStats)
 }
 
 // @Summary Фильтрация субъектов доступа СКУД
@@ -63,8 +67,6 @@ func RegistrPersonsAPI(router fiber.Router, ps PersonsService) {
 // @Failure 404 {object} response.Body{data=nil} "Структура неудачного ответа запроса фильтрации субъектов"
 // @Router /api/persons/filter/{offset}/{count} [post]
 func (pc *PersonsController) GetPersons(c *fiber.Ctx) error {
-	session := c.Cookies("session", "")
-
 	offsetParam := c.Params("offset", "0")
 	countParam := c.Params("count", "0")
 
@@ -86,9 +88,9 @@ func (pc *PersonsController) GetPersons(c *fiber.Ctx) error {
 		return c.JSON(response.BadRes(fmt.Errorf("Неверный формат фильтров для запроса")))
 	}
 
-	result, err := pc.service.GetPersons(c.Context(), session, offset, count, body)
+	result, err := pc.service.GetPersons(c.Context(), middleware.User(c).Scope(), offset, count, body)
 	if err != nil {
-		c.Status(fiber.StatusInternalServerError)
+		c.Status(ErrStatus(err))
 		return c.JSON(response.BadRes(err))
 	}
 
@@ -96,11 +98,9 @@ func (pc *PersonsController) GetPersons(c *fiber.Ctx) error {
 }
 
 func (pc *PersonsController) GetPersonsCount(c *fiber.Ctx) error {
-	session := c.Cookies("session", "")
-
-	result, err := pc.service.GetPersonsCount(c.Context(), session)
+	result, err := pc.service.GetPersonsCount(c.Context(), middleware.User(c).Scope())
 	if err != nil {
-		c.Status(fiber.StatusInternalServerError)
+		c.Status(ErrStatus(err))
 		return c.JSON(response.BadRes(err))
 	}
 
@@ -115,8 +115,6 @@ func (pc *PersonsController) GetPersonsCount(c *fiber.Ctx) error {
 // @Failure 404 {object} response.Body{data=nil} "Структура неудачного ответа выполнения запроса получения информации о субъекте доступа СКУД"
 // @Router /api/persons/ [get]
 func (pc *PersonsController) GetPersonById(c *fiber.Ctx) error {
-	session := c.Cookies("session", "")
-
 	idParam := c.Params("id", "0")
 
 	id, err := strconv.ParseInt(idParam, 10, 0)
@@ -125,9 +123,12 @@ func (pc *PersonsController) GetPersonById(c *fiber.Ctx) error {
 		return c.JSON(response.BadRes(fmt.Errorf("Неверный формат id пользователя")))
 	}
 
-	result, err := pc.service.GetPersonById(c.Context(), session, id)
+	result, err := pc.service.GetPersonById(c.Context(), middleware.User(c).Scope(), id)
 	if err != nil {
-		c.Status(fiber.StatusInternalServerError)
+		if errors.Is(err, cache.ErrStaleData) {
+			return c.JSON(response.StaleRes(result))
+		}
+		c.Status(ErrStatus(err))
 		return c.JSON(response.BadRes(err))
 	}
 
@@ -144,8 +145,6 @@ func (pc *PersonsController) GetPersonById(c *fiber.Ctx) error {
 // @Failure 400 {object} response.Body{data=nil} "Структура неудачного ответа выполнения запроса добавления субъекта доступа СКУД"
 // @Router /api/persons/ [post]
 func (pc *PersonsController) AddPerson(c *fiber.Ctx) error {
-	session := c.Cookies("session", "")
-
 	var data integrserv.PersonData
 	err := json.Unmarshal(c.Body(), &data)
 	if err != nil {
@@ -153,9 +152,9 @@ func (pc *PersonsController) AddPerson(c *fiber.Ctx) error {
 		return c.JSON(response.BadRes(ErrBodyParse))
 	}
 
-	result, err := pc.service.AddPerson(c.Context(), session, data)
+	result, err := pc.service.AddPerson(c.Context(), middleware.User(c).Scope(), data)
 	if err != nil {
-		c.Status(fiber.StatusInternalServerError)
+		c.Status(ErrStatus(err))
 		return c.JSON(response.BadRes(err))
 	}
 
@@ -172,8 +171,6 @@ func (pc *PersonsController) AddPerson(c *fiber.Ctx) error {
 // @Failure 400 {object} response.Body{data=nil} "Структура неудачного ответа выполнения запроса обновления информации о субъекте доступа СКУД"
 // @Router /api/persons/ [put]
 func (pc *PersonsController) UpdatePerson(c *fiber.Ctx) error {
-	session := c.Cookies("session", "")
-
 	var data integrserv.PersonData
 	err := json.Unmarshal(c.Body(), &data)
 	if err != nil {
@@ -181,9 +178,9 @@ func (pc *PersonsController) UpdatePerson(c *fiber.Ctx) error {
 		return c.JSON(response.BadRes(ErrBodyParse))
 	}
 
-	result, err := pc.service.UpdatePerson(c.Context(), session, data)
+	result, err := pc.service.UpdatePerson(c.Context(), middleware.User(c).Scope(), data)
 	if err != nil {
-		c.Status(fiber.StatusInternalServerError)
+		c.Status(ErrStatus(err))
 		return c.JSON(response.BadRes(err))
 	}
 
@@ -200,8 +197,6 @@ func (pc *PersonsController) UpdatePerson(c *fiber.Ctx) error {
 // @Failure 400 {object} response.Body{data=nil} "Структура неудачного ответа выполнения запроса удаления субъекта доступа СКУД"
 // @Router /api/persons/ [delete]
 func (pc *PersonsController) DeletePerson(c *fiber.Ctx) error {
-	session := c.Cookies("session", "")
-
 	var data integrserv.PersonData
 	err := json.Unmarshal(c.Body(), &data)
 	if err != nil {
@@ -209,9 +204,9 @@ func (pc *PersonsController) DeletePerson(c *fiber.Ctx) error {
 		return c.JSON(response.BadRes(ErrBodyParse))
 	}
 
-	result, err := pc.service.DeletePerson(c.Context(), session, data)
+	result, err := pc.service.DeletePerson(c.Context(), middleware.User(c).Scope(), data)
 	if err != nil {
-		c.Status(fiber.StatusInternalServerError)
+		c.Status(ErrStatus(err))
 		return c.JSON(response.BadRes(err))
 	}
 
@@ -226,11 +221,12 @@ func (pc *PersonsController) DeletePerson(c *fiber.Ctx) error {
 // @Failure 500 {object} response.Body{data=nil} "Структура неудачного ответа выполнения запроса получения информации о группах субъектов доступа СКУД"
 // @Router /api/persons/departments [get]
 func (pc *PersonsController) GetDepartments(c *fiber.Ctx) error {
-	session := c.Cookies("session", "")
-
-	result, err := pc.service.GetDepartments(c.Context(), session)
+	result, err := pc.service.GetDepartments(c.Context(), middleware.User(c).Scope())
 	if err != nil {
-		c.Status(fiber.StatusInternalServerError)
+		if errors.Is(err, cache.ErrStaleData) {
+			return c.JSON(response.StaleRes(result))
+		}
+		c.Status(ErrStatus(err))
 		return c.JSON(response.BadRes(err))
 	}
 
@@ -247,8 +243,6 @@ func (pc *PersonsController) GetDepartments(c *fiber.Ctx) error {
 // @Failure 500 {object} response.Body{data=nil} "Структура неудачного ответа выполнения запроса получения информации о статистике посещаемости субъектом доступа СКУД за конкретный день"
 // @Router /api/persons/activity/dayly/{date}/{id} [get]
 func (pc *PersonsController) GetDaylyUserStats(c *fiber.Ctx) error {
-	session := c.Cookies("session", "")
-
 	idParam := c.Params("id", "0")
 
 	id, err := strconv.ParseInt(idParam, 10, 0)
@@ -264,9 +258,9 @@ func (pc *PersonsController) GetDaylyUserStats(c *fiber.Ctx) error {
 		return c.JSON(response.BadRes(ErrParamParse))
 	}
 
-	result, err := pc.service.GetDaylyUserStats(c.Context(), session, id, date)
+	result, err := pc.service.GetDaylyUserStats(c.Context(), middleware.User(c).Scope(), id, date)
 	if err != nil {
-		c.Status(fiber.StatusInternalServerError)
+		c.Status(ErrStatus(err))
 		return c.JSON(response.BadRes(err))
 	}
 
@@ -283,8 +277,6 @@ func (pc *PersonsController) GetDaylyUserStats(c *fiber.Ctx) error {
 // @Failure 500 {object} response.Body{data=nil} "Структура неудачного ответа выполнения запроса получения информации о статистике посещаемости субъектом доступа СКУД за конкретный месяц"
 // @Router /api/persons/activity/monthly/{date}/{id} [get]
 func (pc *PersonsController) GetMonthlyUserStats(c *fiber.Ctx) error {
-	session := c.Cookies("session", "")
-
 	idParam := c.Params("id", "0")
 
 	id, err := strconv.ParseInt(idParam, 10, 0)
@@ -300,9 +292,9 @@ func (pc *PersonsController) GetMonthlyUserStats(c *fiber.Ctx) error {
 		return c.JSON(response.BadRes(ErrParamParse))
 	}
 
-	result, err := pc.service.GetMonthlyUserStats(c.Context(), session, id, monthTime)
+	result, err := pc.service.GetMonthlyUserStats(c.Context(), middleware.User(c).Scope(), id, monthTime)
 	if err != nil {
-		c.Status(fiber.StatusInternalServerError)
+		c.Status(ErrStatus(err))
 		return c.JSON(response.BadRes(err))
 	}
 
@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/Izumra/SKUD_OKEI/domain/dto/resp"
+	valueobject "github.com/Izumra/SKUD_OKEI/domain/value-object"
+	"github.com/Izumra/SKUD_OKEI/internal/http/middleware"
+	"github.com/Izumra/SKUD_OKEI/internal/lib/response"
+	"github.com/gofiber/fiber/v2"
+)
+
+type PresenceService interface {
+	Inside(ctx context.Context, scope *valueobject.Scope, department int64) (*resp.Presence, error)
+	Departments(ctx context.Context, scope *valueobject.Scope) ([]resp.PresenceDepartment, error)
+}
+
+type PresenceController struct {
+	service PresenceService
+}
+
+func RegistrPresenceAPI(router fiber.Router, ps PresenceService, guard middleware.Guard) {
+	pc := PresenceController{
+		service: ps,
+	}
+
+	router.Get("/", guard(valueobject.PresenceView), pc.Inside())
+	router.Get("/departments", guard(valueobject.PresenceView), pc.Departments())
+}
+
+// @Summary Присутствующие в здании
+// @Description Метод API, позволяющий получить список людей, находящихся в здании, со временем и точкой входа. Люди, не отметившие выход в течение заданного времени, из списка исключаются
+// @Tags Presence
+// @Produce  json
+// @Param department query int false "Идентификатор подразделения, по умолчанию все подразделения"
+// @Success 200 {object} response.Body{data=resp.Presence,error=nil} "Структура успешного ответа запроса присутствующих"
+// @Failure 503 {object} response.Body{data=nil} "Список присутствующих еще не загружен из 'Орион Про'"
+// @Failure 500 {object} response.Body{data=nil} "Структура неудачного ответа запроса присутствующих"
+// @Router /api/presence [get]
+func (pc *PresenceController) Inside() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		department := c.QueryInt("department", 0)
+
+		result, err := pc.service.Inside(c.Context(), middleware.User(c).Scope(), int64(department))
+		if err != nil {
+			c.Status(ErrStatus(err))
+			return c.JSON(response.BadRes(err))
+		}
+
+		return c.JSON(response.SuccessRes(result))
+	}
+}
+
+// @Summary Присутствующие в здании по подразделениям
+// @Description Метод API, позволяющий получить количество людей каждого подразделения, находящихся в здании
+// @Tags Presence
+// @Produce  json
+// @Success 200 {object} response.Body{data=[]resp.PresenceDepartment,error=nil} "Структура успешного ответа запроса присутствующих по подразделениям"
+// @Failure 503 {object} response.Body{data=nil} "Список присутствующих еще не загружен из 'Орион Про'"
+// @Failure 500 {object} response.Body{data=nil} "Структура неудачного ответа запроса присутствующих по подразделениям"
+// @Router /api/presence/departments [get]
+func (pc *PresenceController) Departments() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		result, err := pc.service.Departments(c.Context(), middleware.User(c).Scope())
+		if err != nil {
+			c.Status(ErrStatus(err))
+			return c.JSON(response.BadRes(err))
+		}
+
+		return c.JSON(response.SuccessRes(result))
+	}
+}
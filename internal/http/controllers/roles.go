@@ -0,0 +1,235 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Izumra/SKUD_OKEI/domain/dto/reqs"
+	"github.com/Izumra/SKUD_OKEI/domain/dto/resp"
+	valueobject "github.com/Izumra/SKUD_OKEI/domain/value-object"
+	"github.com/Izumra/SKUD_OKEI/internal/http/middleware"
+	"github.com/Izumra/SKUD_OKEI/internal/lib/response"
+	"github.com/gofiber/fiber/v2"
+)
+
+type RolesService interface {
+	middleware.PermissionChecker
+	Permissions() []string
+	Roles(ctx context.Context) ([]resp.Role, error)
+	AddRole(ctx context.Context, name string, permissions []string) (*resp.Role, error)
+	UpdateRole(ctx context.Context, id int64, name string, permissions []string) (*resp.Role, error)
+	DeleteRole(ctx context.Context, id int64) error
+	SetUserRole(ctx context.Context, userId int64, roleId int64) error
+	SetUserDepartments(ctx context.Context, userId int64, departments []int64) error
+}
+
+type RolesController struct {
+	service RolesService
+}
+
+func RegistrRolesAPI(adminRouter fiber.Router, rs RolesService, guard middleware.Guard) {
+	rc := RolesController{
+		service: rs,
+	}
+
+	adminRouter.Get("/permissions", guard(valueobject.UsersManage), rc.Permissions())
+	adminRouter.Get("/roles", guard(valueobject.UsersManage), rc.Roles())
+	adminRouter.Post("/roles", guard(valueobject.UsersManage), rc.AddRole())
+	adminRouter.Put("/roles/:id", guard(valueobject.UsersManage), rc.UpdateRole())
+	adminRouter.Delete("/roles/:id", guard(valueobject.UsersManage), rc.DeleteRole())
+	adminRouter.Put("/users/:id/role", guard(valueobject.UsersManage), rc.SetUserRole())
+	adminRouter.Put("/users/:id/departments", guard(valueobject.UsersManage), rc.SetUserDepartments())
+}
+
+// @Summary Разрешения
+// @Description Метод API, позволяющий администратору получить список разрешений, из которых составляются роли
+// @Tags Admin
+// @Produce  json
+// @Success 200 {object} response.Body{data=[]string,error=nil} "Структура успешного ответа запроса разрешений"
+// @Router /api/admin/permissions [get]
+func (rc *RolesController) Permissions() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return c.JSON(response.SuccessRes(rc.service.Permissions()))
+	}
+}
+
+// @Summary Роли пользователей
+// @Description Метод API, позволяющий администратору получить список ролей с их разрешениями
+// @Tags Admin
+// @Produce  json
+// @Success 200 {object} response.Body{data=[]resp.Role,error=nil} "Структура успешного ответа запроса ролей"
+// @Failure 500 {object} response.Body{data=nil} "Структура неудачного ответа запроса ролей"
+// @Router /api/admin/roles [get]
+func (rc *RolesController) Roles() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		result, err := rc.service.Roles(c.Context())
+		if err != nil {
+			c.Status(ErrStatus(err))
+			return c.JSON(response.BadRes(err))
+		}
+
+		return c.JSON(response.SuccessRes(result))
+	}
+}
+
+// @Summary Создание роли
+// @Description Метод API, позволяющий администратору создать роль с переданными разрешениями
+// @Tags Admin
+// @Accept  json
+// @Produce  json
+// @Param RoleBody body reqs.RoleBody true "Тело запроса формата 'application/json', содержащее название роли и ее разрешения"
+// @Success 200 {object} response.Body{data=resp.Role,error=nil} "Структура успешного ответа запроса создания роли"
+// @Failure 400 {object} response.Body{data=nil} "Неизвестное разрешение или пустое название роли"
+// @Failure 409 {object} response.Body{data=nil} "Роль с таким названием уже существует"
+// @Router /api/admin/roles [post]
+func (rc *RolesController) AddRole() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var body reqs.RoleBody
+		err := c.BodyParser(&body)
+		if err != nil {
+			c.Status(fiber.StatusBadRequest)
+			return c.JSON(response.BadRes(ErrBodyParse))
+		}
+
+		result, err := rc.service.AddRole(c.Context(), body.Name, body.Permissions)
+		if err != nil {
+			c.Status(ErrStatus(err))
+			return c.JSON(response.BadRes(err))
+		}
+
+		return c.JSON(response.SuccessRes(result))
+	}
+}
+
+// @Summary Изменение роли
+// @Description Метод API, позволяющий администратору переименовать роль и заменить ее разрешения, роль администратора не изменяется
+// @Tags Admin
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Идентификатор роли"
+// @Param RoleBody body reqs.RoleBody true "Тело запроса формата 'application/json', содержащее название роли и ее разрешения"
+// @Success 200 {object} response.Body{data=resp.Role,error=nil} "Структура успешного ответа запроса изменения роли"
+// @Failure 404 {object} response.Body{data=nil} "Роль не найдена"
+// @Failure 409 {object} response.Body{data=nil} "Роль администратора не может быть изменена"
+// @Router /api/admin/roles/{id} [put]
+func (rc *RolesController) UpdateRole() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := c.ParamsInt("id")
+		if err != nil {
+			c.Status(fiber.StatusBadRequest)
+			return c.JSON(response.BadRes(fmt.Errorf("Неверный формат идентификатора роли")))
+		}
+
+		var body reqs.RoleBody
+		err = c.BodyParser(&body)
+		if err != nil {
+			c.Status(fiber.StatusBadRequest)
+			return c.JSON(response.BadRes(ErrBodyParse))
+		}
+
+		result, err := rc.service.UpdateRole(c.Context(), int64(id), body.Name, body.Permissions)
+		if err != nil {
+			c.Status(ErrStatus(err))
+			return c.JSON(response.BadRes(err))
+		}
+
+		return c.JSON(response.SuccessRes(result))
+	}
+}
+
+// @Summary Удаление роли
+// @Description Метод API, позволяющий администратору удалить роль, не назначенную пользователям
+// @Tags Admin
+// @Produce  json
+// @Param id path int true "Идентификатор роли"
+// @Success 200 {object} response.Body{data=string,error=nil} "Структура успешного ответа запроса удаления роли"
+// @Failure 404 {object} response.Body{data=nil} "Роль не найдена"
+// @Failure 409 {object} response.Body{data=nil} "Роль назначена пользователям"
+// @Router /api/admin/roles/{id} [delete]
+func (rc *RolesController) DeleteRole() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := c.ParamsInt("id")
+		if err != nil {
+			c.Status(fiber.StatusBadRequest)
+			return c.JSON(response.BadRes(fmt.Errorf("Неверный формат идентификатора роли")))
+		}
+
+		err = rc.service.DeleteRole(c.Context(), int64(id))
+		if err != nil {
+			c.Status(ErrStatus(err))
+			return c.JSON(response.BadRes(err))
+		}
+
+		return c.JSON(response.SuccessRes("Роль удалена"))
+	}
+}
+
+// @Summary Назначение роли пользователю
+// @Description Метод API, позволяющий администратору назначить роль пользователю, новая роль действует и в открытых сессиях пользователя
+// @Tags Admin
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Идентификатор пользователя"
+// @Param UserRoleBody body reqs.UserRoleBody true "Тело запроса формата 'application/json', содержащее идентификатор роли"
+// @Success 200 {object} response.Body{data=string,error=nil} "Структура успешного ответа запроса назначения роли"
+// @Failure 404 {object} response.Body{data=nil} "Роль или пользователь не найдены"
+// @Router /api/admin/users/{id}/role [put]
+func (rc *RolesController) SetUserRole() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := c.ParamsInt("id")
+		if err != nil {
+			c.Status(fiber.StatusBadRequest)
+			return c.JSON(response.BadRes(fmt.Errorf("Неверный формат идентификатора пользователя")))
+		}
+
+		var body reqs.UserRoleBody
+		err = c.BodyParser(&body)
+		if err != nil {
+			c.Status(fiber.StatusBadRequest)
+			return c.JSON(response.BadRes(ErrBodyParse))
+		}
+
+		err = rc.service.SetUserRole(c.Context(), int64(id), body.RoleId)
+		if err != nil {
+			c.Status(ErrStatus(err))
+			return c.JSON(response.BadRes(err))
+		}
+
+		return c.JSON(response.SuccessRes("Роль назначена"))
+	}
+}
+
+// @Summary Подразделения пользователя
+// @Description Метод API, позволяющий администратору ограничить данные, доступные пользователю, субъектами переданных подразделений. При пустом списке данные подразделений недоступны, если роль пользователя не имеет разрешения на доступ ко всем подразделениям, ограничение действует и в открытых сессиях пользователя
+// @Tags Admin
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Идентификатор пользователя"
+// @Param UserDepartmentsBody body reqs.UserDepartmentsBody true "Тело запроса формата 'application/json', содержащее идентификаторы подразделений"
+// @Success 200 {object} response.Body{data=string,error=nil} "Структура успешного ответа запроса назначения подразделений"
+// @Failure 404 {object} response.Body{data=nil} "Пользователь не найден"
+// @Router /api/admin/users/{id}/departments [put]
+func (rc *RolesController) SetUserDepartments() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := c.ParamsInt("id")
+		if err != nil {
+			c.Status(fiber.StatusBadRequest)
+			return c.JSON(response.BadRes(fmt.Errorf("Неверный формат идентификатора пользователя")))
+		}
+
+		var body reqs.UserDepartmentsBody
+		err = c.BodyParser(&body)
+		if err != nil {
+			c.Status(fiber.StatusBadRequest)
+			return c.JSON(response.BadRes(ErrBodyParse))
+		}
+
+		err = rc.service.SetUserDepartments(c.Context(), int64(id), body.Departments)
+		if err != nil {
+			c.Status(ErrStatus(err))
+			return c.JSON(response.BadRes(err))
+		}
+
+		return c.JSON(response.SuccessRes("Подразделения назначены"))
+	}
+}
@@ -0,0 +1,78 @@
+package ws
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/Izumra/SKUD_OKEI/domain/dto/resp"
+	"github.com/Izumra/SKUD_OKEI/internal/lib/response"
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+)
+
+var ErrWrongEvacuationId = errors.New("Неверный формат идентификатора эвакуации")
+
+type EvacuationWatcher interface {
+	Evacuation(ctx context.Context, id int64) (*resp.Evacuation, error)
+	Watch(id int64) (<-chan struct{}, func())
+}
+
+// Evacuation sends the state of the evacuation on the connection and on every
+// change made by the other devices, the socket is closed when the evacuation
+// is finished.
+func (mc *WSController) Evacuation() fiber.Handler {
+	return websocket.New(func(c *websocket.Conn) {
+
+		ctx, cancel := context.WithCancel(context.TODO())
+		defer cancel()
+
+		id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+		if err != nil {
+			c.WriteJSON(response.BadRes(ErrWrongEvacuationId))
+			return
+		}
+
+		changes, stop := mc.evacuations.Watch(id)
+		defer stop()
+
+		// the messages of the client are not expected, the reading detects
+		// the closed connection
+		go func() {
+			defer cancel()
+			for {
+				if _, _, err := c.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+
+		send := func() bool {
+			evacuation, err := mc.evacuations.Evacuation(ctx, id)
+			if err != nil {
+				c.WriteJSON(response.BadRes(err))
+				return false
+			}
+
+			if c.WriteJSON(response.SuccessRes(evacuation)) != nil {
+				return false
+			}
+			return evacuation.FinishedAt == nil
+		}
+
+		if !send() {
+			return
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-changes:
+				if !send() {
+					return
+				}
+			}
+		}
+	})
+}
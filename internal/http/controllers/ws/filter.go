@@ -0,0 +1,122 @@
+package ws
+
+import (
+	"context"
+
+	"github.com/Izumra/SKUD_OKEI/domain/dto/integrserv"
+	"github.com/Izumra/SKUD_OKEI/domain/dto/reqs"
+	"github.com/Izumra/SKUD_OKEI/domain/entity"
+	"github.com/Izumra/SKUD_OKEI/internal/http/middleware"
+)
+
+type PersonsDirectory interface {
+	DepartmentPersons(ctx context.Context, departments []int64) (map[int64]bool, error)
+}
+
+// Filter selects the events shown by the monitor, the nil set passes any
+// value of its field.
+type Filter struct {
+	// scope is the set of the persons of the departments of the user, it is
+	// kept when the subscription is changed.
+	scope        map[int64]bool
+	persons      map[int64]bool
+	accessPoints map[int]bool
+	passModes    map[int]bool
+	eventTypes   map[int64]bool
+}
+
+func (f *Filter) Match(event integrserv.Event) bool {
+	if f == nil {
+		return true
+	}
+	if f.scope != nil && !f.scope[event.PersonId] {
+		return false
+	}
+	if f.persons != nil && !f.persons[event.PersonId] {
+		return false
+	}
+	if f.accessPoints != nil && !f.accessPoints[event.AccessPointId] {
+		return false
+	}
+	if f.passModes != nil && !f.passModes[event.PassMode] {
+		return false
+	}
+	if f.eventTypes != nil && !f.eventTypes[event.EventTypeId] {
+		return false
+	}
+
+	return true
+}
+
+// buildFilter builds the filter by the subscription, the persons of the
+// departments are added to the persons of the subscription. The departments
+// are allowed only to the users who may read the lists of the persons.
+func buildFilter(ctx context.Context, directory PersonsDirectory, readPersons bool, scope map[int64]bool, subscription reqs.MonitorSubscription) (*Filter, error) {
+	var err error
+	filter := Filter{
+		scope:        scope,
+		accessPoints: toSet(subscription.AccessPoints),
+		passModes:    toSet(subscription.PassModes),
+		eventTypes:   toSet(subscription.EventTypes),
+	}
+
+	if len(subscription.Departments) != 0 {
+		if !readPersons {
+			return nil, middleware.ErrAccessDenied
+		}
+
+		filter.persons, err = directory.DepartmentPersons(ctx, subscription.Departments)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(subscription.Persons) != 0 {
+		if filter.persons == nil {
+			filter.persons = map[int64]bool{}
+		}
+		for _, id := range subscription.Persons {
+			filter.persons[id] = true
+		}
+	}
+
+	return &filter, nil
+}
+
+// scoped returns the persons of the departments of the user, the nil set
+// means the access to all the departments.
+func (f *Filter) scoped() map[int64]bool {
+	if f == nil {
+		return nil
+	}
+	return f.scope
+}
+
+// scopeFilter returns the filter of the events of the persons of the
+// departments of the user, the nil filter is returned for the user with the
+// access to all the departments. The persons are resolved once, so the
+// persons moved to the departments later are shown after the reconnection.
+func scopeFilter(ctx context.Context, directory PersonsDirectory, user *entity.User) (*Filter, error) {
+	scope := user.Scope()
+	if scope == nil {
+		return nil, nil
+	}
+
+	persons, err := directory.DepartmentPersons(ctx, scope.Departments())
+	if err != nil {
+		return nil, err
+	}
+
+	return &Filter{scope: persons}, nil
+}
+
+func toSet[T comparable](values []T) map[T]bool {
+	if len(values) == 0 {
+		return nil
+	}
+
+	set := make(map[T]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
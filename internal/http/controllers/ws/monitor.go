@@ -2,14 +2,17 @@ package ws
 
 import (
 	"context"
-	"encoding/xml"
 	"errors"
+	"strconv"
 	"time"
 
 	"github.com/Izumra/SKUD_OKEI/domain/dto/integrserv"
+	"github.com/Izumra/SKUD_OKEI/domain/dto/reqs"
 	"github.com/Izumra/SKUD_OKEI/domain/dto/resp"
-	"github.com/Izumra/SKUD_OKEI/internal/lib/response"
-	"github.com/Izumra/SKUD_OKEI/internal/services/auth"
+	"github.com/Izumra/SKUD_OKEI/domain/entity"
+	valueobject "github.com/Izumra/SKUD_OKEI/domain/value-object"
+	"github.com/Izumra/SKUD_OKEI/internal/http/middleware"
+	"github.com/Izumra/SKUD_OKEI/internal/services/events"
 	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
 )
@@ -20,31 +23,41 @@ var (
 )
 
 type WSService interface {
-	GetEvents(ctx context.Context, eventsFilter *integrserv.EventFilter) ([]integrserv.Event, error)
-	GetEventsCount(ctx context.Context, eventsFilter *integrserv.EventCountFilter) (int64, error)
+	GetEvents(ctx context.Context, scope *valueobject.Scope, eventsFilter *integrserv.EventFilter) ([]integrserv.Event, error)
+	GetEventsCount(ctx context.Context, scope *valueobject.Scope, eventsFilter *integrserv.EventCountFilter) (int64, error)
+	IterateEvents(ctx context.Context, begin, end time.Time, opts events.IterateOptions, fn func(event integrserv.Event) error) error
+	Subscribe(name string, buffer int, filter func(event integrserv.Event) bool) *events.Subscription
+	SubscribeSince(seq uint64, name string, buffer int, filter func(event integrserv.Event) bool) (*events.Subscription, []events.BusEvent, bool)
+	OperationalDay(moment time.Time) (time.Time, time.Time)
+	DayEvents(ctx context.Context, moment time.Time) (time.Time, time.Time, []integrserv.Event, error)
+	LookupEvent(eventId string) (events.BusEvent, bool)
 }
 
 type WSController struct {
-	sessStorage auth.SessionStorage
+	checker     middleware.PermissionChecker
 	service     WSService
+	directory   PersonsDirectory
+	evacuations EvacuationWatcher
 }
 
-func RegistrWSAPI(router fiber.Router, ws WSService, sessStorage auth.SessionStorage) {
+func RegistrWSAPI(router fiber.Router, ws WSService, directory PersonsDirectory, evacuations EvacuationWatcher, checker middleware.PermissionChecker) {
 	mc := WSController{
-		sessStorage: sessStorage,
+		checker:     checker,
 		service:     ws,
+		directory:   directory,
+		evacuations: evacuations,
 	}
 
 	router.Use(mc.CheckRegisteredUpgrade())
-	router.Get("/monitor", mc.Monitor())
+	guard := middleware.Permissions(checker)
+	router.Get("/monitor", guard(valueobject.MonitorView), mc.Monitor())
+	router.Get("/replay", guard(valueobject.EventsRead), mc.Replay())
+	router.Get("/evacuations/:id", guard(valueobject.EvacuationsManage), mc.Evacuation())
 }
 
 func (mc *WSController) CheckRegisteredUpgrade() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		sessionId := c.Cookies("session", "")
-
 		if websocket.IsWebSocketUpgrade(c) {
-			c.Locals("sessionID", sessionId)
 			return c.Next()
 		}
 		return fiber.ErrUpgradeRequired
@@ -57,97 +70,222 @@ func (mc *WSController) Monitor() fiber.Handler {
 		ctx, cancel := context.WithCancel(context.TODO())
 		defer cancel()
 
-		var lastUpdate time.Time
+		user, _ := c.Locals(middleware.UserKey).(*entity.User)
+		readPersons := user != nil && mc.checker.Allowed(user.Role, valueobject.PersonsRead)
+		protocol, _ := strconv.Atoi(c.Query("protocol", "1"))
+		writer := newMonitorWriter(c, protocol)
+
+		scope, err := scopeFilter(ctx, mc.directory, user)
+		if err != nil {
+			writer.Error(err)
+			return
+		}
+
+		// the requests of the client are read by the goroutine, the socket
+		// is written only by the loop below
+		type request struct {
+			resync bool
+			filter *Filter
+			err    error
+		}
+		requests := make(chan request)
+		go func() {
+			defer cancel()
+			for {
+				_, msg, err := c.ReadMessage()
+				if err != nil {
+					return
+				}
+
+				var req request
+				parsed, err := parseRequest(msg)
+				if err != nil {
+					req.err = err
+				} else if parsed.Type == reqs.MonitorResync {
+					req.resync = true
+				} else {
+					req.filter, req.err = buildFilter(ctx, mc.directory, readPersons, scope.scoped(), parsed.Filter)
+				}
+
+				select {
+				case requests <- req:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+
+		// the reconnected client of the second version of the protocol passes
+		// the id of the last event it saw, it receives only the missed events
+		// when they are still kept by the bus
+		lastEventId := c.Query("lastEventId")
+		var sub *events.Subscription
+		var missed []events.BusEvent
+		var lastSeen time.Time
+		resumed := false
+		if lastEventId != "" && protocol == resp.MonitorProtocol {
+			if last, ok := mc.service.LookupEvent(lastEventId); ok {
+				lastSeen = last.Event.EventDate
+				sub, missed, resumed = mc.service.SubscribeSince(last.Seq, "monitor", 256, nil)
+			}
+		}
 
-		now := time.Now()
-		lastUpdate = time.Date(now.Year(), now.Month(), now.Day(), 6, 0, 0, 0, now.Location())
+		// the subscription is made before the snapshot, so the events
+		// published while the snapshot is loading are not lost
+		if sub == nil {
+			sub = mc.service.Subscribe("monitor", 256, nil)
+		}
+		defer sub.Close()
 
-		recentlyRecords := map[string]bool{}
+		tracker := NewTracker()
+		tracker.SetFilter(scope)
+		eventSeq := sub.StartSeq()
 
-		var evnts []integrserv.Event
-		users := make(map[int64]integrserv.Event)
-		stats := &resp.Stats{}
+		// the rollover fires at the end of the operational day of the stats
+		rollover := time.NewTimer(time.Hour)
+		defer rollover.Stop()
 
-		var closeHandlerSetted bool
+		load := func(write func(tracker *Tracker, eventSeq uint64) error) bool {
+			err := tracker.Load(ctx, mc.service)
+			if err != nil {
+				if ctx.Err() == nil {
+					writer.Error(err)
+				}
+				return false
+			}
+
+			_, dayEnd := tracker.Day()
+			resetTimer(rollover, time.Until(dayEnd))
+
+			if write(tracker, eventSeq) != nil {
+				return false
+			}
+			return true
+		}
+		snapshot := func() bool {
+			return load(writer.Snapshot)
+		}
+
+		// the gap is signaled when the missed events are not kept anymore or
+		// the client kept the state of the previous operational day
+		gap := func(tracker *Tracker, eventSeq uint64) error {
+			err := writer.Gap()
+			if err != nil {
+				return err
+			}
+			return writer.Snapshot(tracker, eventSeq)
+		}
+		resume := func(tracker *Tracker, eventSeq uint64) error {
+			dayBegin, _ := tracker.Day()
+			if lastSeen.Before(dayBegin) {
+				return gap(tracker, eventSeq)
+			}
+
+			var fresh []integrserv.Event
+			for _, busEvent := range missed {
+				if !busEvent.Event.EventDate.Before(dayBegin) && tracker.filter.Match(busEvent.Event) {
+					fresh = append(fresh, busEvent.Event)
+				}
+				tracker.Apply(busEvent.Event)
+			}
+			return writer.Resume(tracker, fresh, eventSeq)
+		}
+
+		switch {
+		case resumed:
+			if !load(resume) {
+				return
+			}
+		case lastEventId != "" && protocol == resp.MonitorProtocol:
+			if !load(gap) {
+				return
+			}
+		default:
+			if !snapshot() {
+				return
+			}
+		}
+
+		dropped := sub.Dropped()
 		for {
 			select {
 			case <-ctx.Done():
 				return
-			default:
-				if !closeHandlerSetted {
-					go func() {
-						_, _, err := c.ReadMessage()
-						if err != nil {
-							if websocket.IsUnexpectedCloseError(err) || websocket.IsCloseError(err) {
-								cancel()
-							}
-						}
-					}()
-					closeHandlerSetted = true
-				}
-
-				if len(evnts) != 0 {
-					time.Sleep(1 * time.Second)
-				}
-
-				filter := integrserv.EventFilter{
-					XMLName: xml.Name{
-						Local: "GetEvents",
-					},
-					BeginTime: lastUpdate,
-					EndTime:   lastUpdate.Add(time.Hour),
-					Offset:    0,
-					Count:     100,
-				}
-
-				events, err := mc.service.GetEvents(ctx, &filter)
-				if err != nil {
-					c.WriteJSON(response.BadRes(err))
-					return
+			case <-rollover.C:
+				if !tracker.Expired(time.Now()) {
+					_, dayEnd := tracker.Day()
+					rollover.Reset(time.Until(dayEnd))
+					continue
 				}
 
-				if len(events) != 0 {
-					if len(recentlyRecords) == 0 {
-						for i := range events {
-							recentlyRecords[events[i].EventId] = true
-
-							UpdateStats(stats, events[i], users)
-
-							users[events[i].PersonId] = events[i]
-						}
-						evnts = events
-					} else {
-						for i := range events {
-							if _, ok := recentlyRecords[events[i].EventId]; !ok {
-								recentlyRecords[events[i].EventId] = true
-								evnts = append(evnts, events[i])
+				if !load(writer.Rollover) {
+					return
+				}
+			case req := <-requests:
+				if req.err != nil {
+					writer.Error(req.err)
+					continue
+				}
 
-								UpdateStats(stats, events[i], users)
+				if !req.resync {
+					tracker.SetFilter(req.filter)
+				}
+				if !snapshot() {
+					return
+				}
+			case busEvent, ok := <-sub.Events():
+				if !ok {
+					return
+				}
 
-								users[events[i].PersonId] = events[i]
+				batch := []events.BusEvent{busEvent}
+				for pending := len(sub.Events()); pending > 0; pending-- {
+					batch = append(batch, <-sub.Events())
+				}
+				eventSeq = batch[len(batch)-1].Seq
 
-							}
-						}
+				// the events of the new day are loaded with the rollover
+				if tracker.Expired(time.Now()) {
+					if !load(writer.Rollover) {
+						return
 					}
+					continue
+				}
 
-					stats.Events = evnts
-					lastUpdate = evnts[len(evnts)-1].EventDate
+				// the monitor missed the events, so the snapshot is sent again
+				if current := sub.Dropped(); current != dropped {
+					dropped = current
+					if !snapshot() {
+						return
+					}
+					continue
+				}
 
-					err = c.Conn.WriteJSON(response.SuccessRes(stats))
-					if err != nil {
-						if websocket.IsUnexpectedCloseError(err) || websocket.IsCloseError(err) {
-							cancel()
-							break
-						}
+				var fresh []integrserv.Event
+				for _, busEvent := range batch {
+					if tracker.Apply(busEvent.Event) {
+						fresh = append(fresh, busEvent.Event)
 					}
-				} else {
-					lastUpdate = lastUpdate.Add(time.Hour)
+				}
+				if writer.Delta(tracker, fresh, eventSeq) != nil {
+					return
 				}
 			}
 		}
 	})
 }
 
+// resetTimer resets the timer which may be fired but not drained.
+func resetTimer(timer *time.Timer, d time.Duration) {
+	if !timer.Stop() {
+		select {
+		case <-timer.C:
+		default:
+		}
+	}
+	timer.Reset(d)
+}
+
 func UpdateStats(stats *resp.Stats, event integrserv.Event, users map[int64]integrserv.Event) {
 	if lastUsrEvent, ok := users[event.PersonId]; ok {
 		if lastUsrEvent.PassMode == 1 && event.PassMode == 1 {
@@ -0,0 +1,183 @@
+package ws
+
+import (
+	"encoding/json"
+
+	"github.com/Izumra/SKUD_OKEI/domain/dto/integrserv"
+	"github.com/Izumra/SKUD_OKEI/domain/dto/reqs"
+	"github.com/Izumra/SKUD_OKEI/domain/dto/resp"
+	"github.com/Izumra/SKUD_OKEI/internal/lib/response"
+	"github.com/gofiber/contrib/websocket"
+)
+
+type monitorWriter interface {
+	Snapshot(tracker *Tracker, eventSeq uint64) error
+	Rollover(tracker *Tracker, eventSeq uint64) error
+	Delta(tracker *Tracker, events []integrserv.Event, eventSeq uint64) error
+	Resume(tracker *Tracker, events []integrserv.Event, eventSeq uint64) error
+	Gap() error
+	Replay(state resp.MonitorReplayState) error
+	Error(err error) error
+}
+
+func newMonitorWriter(c *websocket.Conn, protocol int) monitorWriter {
+	if protocol == resp.MonitorProtocol {
+		return &deltaWriter{conn: c}
+	}
+	return &statsWriter{conn: c}
+}
+
+// statsWriter writes the whole stats of the day with every message, it is
+// the first version of the protocol.
+type statsWriter struct {
+	conn *websocket.Conn
+}
+
+func (w *statsWriter) Snapshot(tracker *Tracker, eventSeq uint64) error {
+	return w.conn.WriteJSON(response.SuccessRes(tracker.Stats()))
+}
+
+func (w *statsWriter) Rollover(tracker *Tracker, eventSeq uint64) error {
+	return w.conn.WriteJSON(response.SuccessRes(tracker.Stats()))
+}
+
+func (w *statsWriter) Delta(tracker *Tracker, events []integrserv.Event, eventSeq uint64) error {
+	if len(events) == 0 {
+		return nil
+	}
+	return w.conn.WriteJSON(response.SuccessRes(tracker.Stats()))
+}
+
+// Resume writes the whole stats, the first version of the protocol does not
+// resume the connections.
+func (w *statsWriter) Resume(tracker *Tracker, events []integrserv.Event, eventSeq uint64) error {
+	return w.conn.WriteJSON(response.SuccessRes(tracker.Stats()))
+}
+
+func (w *statsWriter) Gap() error {
+	return nil
+}
+
+func (w *statsWriter) Replay(state resp.MonitorReplayState) error {
+	return nil
+}
+
+func (w *statsWriter) Error(err error) error {
+	return w.conn.WriteJSON(response.BadRes(err))
+}
+
+type deltaWriter struct {
+	conn     *websocket.Conn
+	seq      uint64
+	counters resp.MonitorCounters
+}
+
+func (w *deltaWriter) Snapshot(tracker *Tracker, eventSeq uint64) error {
+	return w.state(resp.MonitorSnapshot, tracker, eventSeq)
+}
+
+func (w *deltaWriter) Rollover(tracker *Tracker, eventSeq uint64) error {
+	return w.state(resp.MonitorRollover, tracker, eventSeq)
+}
+
+func (w *deltaWriter) state(msgType string, tracker *Tracker, eventSeq uint64) error {
+	w.counters = tracker.Counters()
+	dayBegin, dayEnd := tracker.Day()
+
+	return w.write(resp.MonitorMessage{
+		Type: msgType,
+		Snapshot: &resp.MonitorState{
+			DayBegin: dayBegin,
+			DayEnd:   dayEnd,
+			Counters: w.counters,
+			Events:   tracker.Stats().Events,
+			EventSeq: eventSeq,
+		},
+	})
+}
+
+func (w *deltaWriter) Delta(tracker *Tracker, events []integrserv.Event, eventSeq uint64) error {
+	changes := resp.MonitorChanges{
+		Events:   events,
+		EventSeq: eventSeq,
+	}
+	if counters := tracker.Counters(); counters != w.counters {
+		w.counters = counters
+		changes.Counters = &counters
+	}
+
+	if len(changes.Events) == 0 && changes.Counters == nil {
+		return nil
+	}
+
+	return w.write(resp.MonitorMessage{
+		Type:  resp.MonitorDelta,
+		Delta: &changes,
+	})
+}
+
+func (w *deltaWriter) Resume(tracker *Tracker, events []integrserv.Event, eventSeq uint64) error {
+	w.counters = tracker.Counters()
+	counters := w.counters
+
+	return w.write(resp.MonitorMessage{
+		Type: resp.MonitorResume,
+		Delta: &resp.MonitorChanges{
+			Counters: &counters,
+			Events:   events,
+			EventSeq: eventSeq,
+		},
+	})
+}
+
+func (w *deltaWriter) Gap() error {
+	return w.write(resp.MonitorMessage{
+		Type: resp.MonitorGap,
+	})
+}
+
+func (w *deltaWriter) Replay(state resp.MonitorReplayState) error {
+	return w.write(resp.MonitorMessage{
+		Type:   resp.MonitorReplay,
+		Replay: &state,
+	})
+}
+
+func (w *deltaWriter) Error(err error) error {
+	return w.write(resp.MonitorMessage{
+		Type:  resp.MonitorError,
+		Error: err.Error(),
+	})
+}
+
+func (w *deltaWriter) write(msg resp.MonitorMessage) error {
+	w.seq++
+	msg.Version = resp.MonitorProtocol
+	msg.Seq = w.seq
+
+	return w.conn.WriteJSON(msg)
+}
+
+// parseRequest parses the message of the client, the message without the
+// type is the subscription of the first version of the protocol.
+func parseRequest(msg []byte) (*reqs.MonitorRequest, error) {
+	var request reqs.MonitorRequest
+	err := json.Unmarshal(msg, &request)
+	if err != nil {
+		return nil, ErrWrongReqBody
+	}
+
+	switch request.Type {
+	case "":
+		request.Type = reqs.MonitorSubscribe
+		err = json.Unmarshal(msg, &request.Filter)
+		if err != nil {
+			return nil, ErrWrongReqBody
+		}
+	case reqs.MonitorSubscribe, reqs.MonitorResync:
+	default:
+		return nil, ErrWrongReqBody
+	}
+
+	return &request, nil
+}
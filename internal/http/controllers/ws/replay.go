@@ -0,0 +1,245 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/Izumra/SKUD_OKEI/domain/dto/integrserv"
+	"github.com/Izumra/SKUD_OKEI/domain/dto/reqs"
+	"github.com/Izumra/SKUD_OKEI/domain/dto/resp"
+	"github.com/Izumra/SKUD_OKEI/domain/entity"
+	"github.com/Izumra/SKUD_OKEI/internal/http/middleware"
+	"github.com/Izumra/SKUD_OKEI/internal/services/events"
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	replayTick     = 200 * time.Millisecond
+	replayMaxRange = 24 * time.Hour
+	replayMaxSpeed = 3600
+)
+
+var (
+	ErrWrongReplayRange = errors.New("Неверный период воспроизведения, период не должен превышать сутки")
+	ErrWrongReplaySpeed = errors.New("Неверная скорость воспроизведения")
+	ErrWrongReplaySeek  = errors.New("Время перемотки должно входить в период воспроизведения")
+)
+
+// replay is the playback of the loaded events by the virtual clock.
+type replay struct {
+	events   []integrserv.Event
+	next     int
+	begin    time.Time
+	end      time.Time
+	at       time.Time
+	speed    float64
+	paused   bool
+	finished bool
+}
+
+func (r *replay) state() resp.MonitorReplayState {
+	return resp.MonitorReplayState{
+		Begin:    r.begin,
+		End:      r.end,
+		At:       r.at,
+		Speed:    r.speed,
+		Paused:   r.paused,
+		Finished: r.finished,
+	}
+}
+
+// advance moves the virtual clock and returns the events reached by it.
+func (r *replay) advance(elapsed time.Duration) []integrserv.Event {
+	if r.paused || r.finished {
+		return nil
+	}
+
+	r.at = r.at.Add(time.Duration(float64(elapsed) * r.speed))
+	if !r.at.Before(r.end) {
+		r.at = r.end
+		r.finished = true
+	}
+
+	from := r.next
+	for r.next < len(r.events) && !r.events[r.next].EventDate.After(r.at) {
+		r.next++
+	}
+
+	return r.events[from:r.next]
+}
+
+// seek moves the virtual clock to the moment, the tracker is started again
+// with the events before it.
+func (r *replay) seek(at time.Time, tracker *Tracker) {
+	r.at = at
+	r.finished = !at.Before(r.end)
+
+	tracker.Start(r.begin, r.end)
+	r.next = 0
+	for r.next < len(r.events) && !r.events[r.next].EventDate.After(at) {
+		tracker.Apply(r.events[r.next])
+		r.next++
+	}
+}
+
+// @Summary Воспроизведение событий за прошедший период
+// @Description WebSocket, воспроизводящий события СКУД за прошедший период в формате монитора с заданной скоростью. Клиент управляет воспроизведением сообщениями pause, play, seek и speed
+// @Tags Events
+// @Param begin query string true "Начало периода в формате 2006-01-02T15:04:05"
+// @Param end query string true "Конец периода в формате 2006-01-02T15:04:05"
+// @Param speed query number false "Скорость воспроизведения, по умолчанию 1"
+// @Param protocol query int false "Версия протокола монитора"
+// @Router /api/ws/replay [get]
+func (mc *WSController) Replay() fiber.Handler {
+	return websocket.New(func(c *websocket.Conn) {
+
+		ctx, cancel := context.WithCancel(context.TODO())
+		defer cancel()
+
+		user, _ := c.Locals(middleware.UserKey).(*entity.User)
+		protocol, _ := strconv.Atoi(c.Query("protocol", "1"))
+		writer := newMonitorWriter(c, protocol)
+
+		layout := "2006-01-02T15:04:05"
+		begin, errBegin := time.Parse(layout, c.Query("begin"))
+		end, errEnd := time.Parse(layout, c.Query("end"))
+		if errBegin != nil || errEnd != nil || !begin.Before(end) || end.Sub(begin) > replayMaxRange {
+			writer.Error(ErrWrongReplayRange)
+			return
+		}
+
+		speed, err := strconv.ParseFloat(c.Query("speed", "1"), 64)
+		if err != nil || speed <= 0 || speed > replayMaxSpeed {
+			writer.Error(ErrWrongReplaySpeed)
+			return
+		}
+
+		commands := make(chan reqs.ReplayCommand)
+		go func() {
+			defer cancel()
+			for {
+				_, msg, err := c.ReadMessage()
+				if err != nil {
+					return
+				}
+
+				var command reqs.ReplayCommand
+				if json.Unmarshal(msg, &command) != nil {
+					command = reqs.ReplayCommand{}
+				}
+
+				select {
+				case commands <- command:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+
+		r := replay{
+			begin: begin,
+			end:   end,
+			at:    begin,
+			speed: speed,
+		}
+		// the events of the persons of the other departments are not loaded
+		opts := events.IterateOptions{Scope: user.Scope()}
+		err = mc.service.IterateEvents(ctx, begin, end, opts, func(event integrserv.Event) error {
+			r.events = append(r.events, event)
+			return nil
+		})
+		if err != nil {
+			if ctx.Err() == nil {
+				writer.Error(err)
+			}
+			return
+		}
+		sort.SliceStable(r.events, func(i, j int) bool {
+			return r.events[i].EventDate.Before(r.events[j].EventDate)
+		})
+
+		tracker := NewTracker()
+		tracker.Start(begin, end)
+		if writer.Snapshot(tracker, 0) != nil || writer.Replay(r.state()) != nil {
+			return
+		}
+
+		ticker := time.NewTicker(replayTick)
+		defer ticker.Stop()
+
+		lastTick := time.Now()
+		lastState := lastTick
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case command := <-commands:
+				switch command.Type {
+				case reqs.ReplayPause:
+					r.paused = true
+				case reqs.ReplayPlay:
+					r.paused = false
+					if r.finished {
+						r.seek(r.begin, tracker)
+						if writer.Snapshot(tracker, 0) != nil {
+							return
+						}
+					}
+				case reqs.ReplaySpeed:
+					if command.Speed <= 0 || command.Speed > replayMaxSpeed {
+						writer.Error(ErrWrongReplaySpeed)
+						continue
+					}
+					r.speed = command.Speed
+				case reqs.ReplaySeek:
+					at, err := time.Parse(layout, command.At)
+					if err != nil || at.Before(r.begin) || at.After(r.end) {
+						writer.Error(ErrWrongReplaySeek)
+						continue
+					}
+
+					r.seek(at, tracker)
+					if writer.Snapshot(tracker, 0) != nil {
+						return
+					}
+				default:
+					writer.Error(ErrWrongReqBody)
+					continue
+				}
+
+				if writer.Replay(r.state()) != nil {
+					return
+				}
+			case now := <-ticker.C:
+				elapsed := now.Sub(lastTick)
+				lastTick = now
+				if r.paused || r.finished {
+					continue
+				}
+
+				var fresh []integrserv.Event
+				for _, event := range r.advance(elapsed) {
+					if tracker.Apply(event) {
+						fresh = append(fresh, event)
+					}
+				}
+				if writer.Delta(tracker, fresh, 0) != nil {
+					return
+				}
+
+				// the clock of the replay is sent every second and at the end
+				if r.finished || now.Sub(lastState) >= time.Second {
+					lastState = now
+					if writer.Replay(r.state()) != nil {
+						return
+					}
+				}
+			}
+		}
+	})
+}
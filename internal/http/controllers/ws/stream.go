@@ -0,0 +1,202 @@
+package ws
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	valueobject "github.com/Izumra/SKUD_OKEI/domain/value-object"
+	"github.com/Izumra/SKUD_OKEI/internal/http/middleware"
+	"github.com/Izumra/SKUD_OKEI/internal/lib/response"
+	"github.com/Izumra/SKUD_OKEI/internal/services/events"
+	"github.com/gofiber/fiber/v2"
+)
+
+const streamHeartbeat = 15 * time.Second
+
+func RegistrStreamAPI(router fiber.Router, ws WSService, directory PersonsDirectory, guard middleware.Guard) {
+	mc := WSController{
+		service:   ws,
+		directory: directory,
+	}
+
+	router.Get("/stream", guard(valueobject.MonitorView), mc.Stream())
+}
+
+// @Summary Поток событий монитора
+// @Description Метод API, передающий статистику и события СКУД текущего дня в формате Server-Sent Events. Первым передается снимок 'snapshot', далее события 'event' и статистика 'stats'. По окончании операционного дня передается снимок нового дня 'rollover'. При переподключении с заголовком Last-Event-ID передаются пропущенные события, если они еще хранятся сервером, иначе передается снимок
+// @Tags Events
+// @Produce  text/event-stream
+// @Param Last-Event-ID header string false "Идентификатор последнего полученного события"
+// @Success 200 {object} response.Body{data=resp.Stats,error=nil} "Структура данных снимка монитора"
+// @Router /api/events/stream [get]
+func (mc *WSController) Stream() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// the id of the event is the id of Orion, the seq of the bus is not
+		// known to the client and is started again with the restart
+		lastEventId := c.Get("Last-Event-ID")
+
+		// the persons of the departments of the user are resolved before the
+		// stream, the context of the request is not valid in the writer
+		scope, err := scopeFilter(c.Context(), mc.directory, middleware.User(c))
+		if err != nil {
+			c.Status(fiber.StatusInternalServerError)
+			return c.JSON(response.BadRes(err))
+		}
+
+		c.Set(fiber.HeaderContentType, "text/event-stream")
+		c.Set(fiber.HeaderCacheControl, "no-cache")
+		c.Set(fiber.HeaderConnection, "keep-alive")
+		c.Set("X-Accel-Buffering", "no")
+
+		c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			var sub *events.Subscription
+			var missed []events.BusEvent
+			var lastSeen time.Time
+			resumed := false
+			if lastEventId != "" {
+				if last, ok := mc.service.LookupEvent(lastEventId); ok {
+					lastSeen = last.Event.EventDate
+					sub, missed, resumed = mc.service.SubscribeSince(last.Seq, "stream", 256, nil)
+				}
+			}
+			if sub == nil {
+				sub = mc.service.Subscribe("stream", 256, nil)
+			}
+			defer sub.Close()
+
+			tracker := NewTracker()
+			tracker.SetFilter(scope)
+			err := tracker.Load(ctx, mc.service)
+			if err != nil {
+				writeSSE(w, "", "error", response.BadRes(err))
+				return
+			}
+
+			fmt.Fprintf(w, "retry: %d\n\n", 3000)
+
+			// lastId is the id of the last event of the bus known to the
+			// stream, the snapshots carry it, so the client resumes after them
+			lastId := sub.StartEventId()
+			dayBegin, _ := tracker.Day()
+			switch {
+			case resumed && !lastSeen.Before(dayBegin):
+				for _, busEvent := range missed {
+					if busEvent.Event.EventDate.Before(dayBegin) || !scope.Match(busEvent.Event) {
+						continue
+					}
+					writeSSE(w, busEvent.Event.EventId, "event", response.SuccessRes(busEvent.Event))
+				}
+				writeSSE(w, "", "stats", response.SuccessRes(tracker.Counters()))
+			case resumed:
+				// the client kept the state of the previous operational day
+				writeSSE(w, lastId, "rollover", response.SuccessRes(tracker.Stats()))
+			default:
+				writeSSE(w, lastId, "snapshot", response.SuccessRes(tracker.Stats()))
+			}
+			if w.Flush() != nil {
+				return
+			}
+
+			heartbeat := time.NewTicker(streamHeartbeat)
+			defer heartbeat.Stop()
+
+			_, dayEnd := tracker.Day()
+			rollover := time.NewTimer(time.Until(dayEnd))
+			defer rollover.Stop()
+
+			// reload loads the stats again and sends them as the event of the
+			// kind, the rollover timer is moved to the end of the loaded day
+			reload := func(kind string) bool {
+				err := tracker.Load(ctx, mc.service)
+				if err != nil {
+					writeSSE(w, "", "error", response.BadRes(err))
+					w.Flush()
+					return false
+				}
+
+				_, dayEnd := tracker.Day()
+				resetTimer(rollover, time.Until(dayEnd))
+
+				writeSSE(w, lastId, kind, response.SuccessRes(tracker.Stats()))
+				return true
+			}
+
+			dropped := sub.Dropped()
+			for {
+				select {
+				case <-heartbeat.C:
+					fmt.Fprint(w, ": ping\n\n")
+				case <-rollover.C:
+					if !tracker.Expired(time.Now()) {
+						_, dayEnd := tracker.Day()
+						rollover.Reset(time.Until(dayEnd))
+						break
+					}
+
+					if !reload("rollover") {
+						return
+					}
+				case busEvent, ok := <-sub.Events():
+					if !ok {
+						return
+					}
+
+					batch := []events.BusEvent{busEvent}
+					for pending := len(sub.Events()); pending > 0; pending-- {
+						batch = append(batch, <-sub.Events())
+					}
+
+					lastId = batch[len(batch)-1].Event.EventId
+
+					// the events of the new day are loaded with the rollover
+					if tracker.Expired(time.Now()) {
+						if !reload("rollover") {
+							return
+						}
+						break
+					}
+
+					// the stream missed the events, so the snapshot is sent again
+					if current := sub.Dropped(); current != dropped {
+						dropped = current
+						if !reload("snapshot") {
+							return
+						}
+						break
+					}
+
+					for _, busEvent := range batch {
+						if tracker.Apply(busEvent.Event) {
+							writeSSE(w, busEvent.Event.EventId, "event", response.SuccessRes(busEvent.Event))
+						}
+					}
+					writeSSE(w, "", "stats", response.SuccessRes(tracker.Counters()))
+				}
+
+				if w.Flush() != nil {
+					return
+				}
+			}
+		})
+
+		return nil
+	}
+}
+
+func writeSSE(w *bufio.Writer, id string, event string, data any) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+
+	if id != "" {
+		fmt.Fprintf(w, "id: %s\n", id)
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+}
@@ -0,0 +1,102 @@
+package ws
+
+import (
+	"context"
+	"time"
+
+	"github.com/Izumra/SKUD_OKEI/domain/dto/integrserv"
+	"github.com/Izumra/SKUD_OKEI/domain/dto/resp"
+)
+
+// Tracker keeps the stats of the operational day shown by the live monitor.
+type Tracker struct {
+	begin           time.Time
+	end             time.Time
+	recentlyRecords map[string]bool
+	evnts           []integrserv.Event
+	users           map[int64]integrserv.Event
+	stats           *resp.Stats
+	filter          *Filter
+}
+
+func NewTracker() *Tracker {
+	t := &Tracker{}
+	t.Reset()
+	return t
+}
+
+func (t *Tracker) Reset() {
+	t.recentlyRecords = map[string]bool{}
+	t.evnts = nil
+	t.users = make(map[int64]integrserv.Event)
+	t.stats = &resp.Stats{}
+}
+
+// SetFilter sets the filter of the events, the stats have to be loaded again.
+func (t *Tracker) SetFilter(filter *Filter) {
+	t.filter = filter
+}
+
+// Apply updates the stats by the event and reports whether the event is new
+// and matches the filter, the late events of the previous day are skipped.
+func (t *Tracker) Apply(event integrserv.Event) bool {
+	if event.EventDate.Before(t.begin) || t.recentlyRecords[event.EventId] || !t.filter.Match(event) {
+		return false
+	}
+	t.recentlyRecords[event.EventId] = true
+	t.evnts = append(t.evnts, event)
+
+	UpdateStats(t.stats, event, t.users)
+
+	t.users[event.PersonId] = event
+	return true
+}
+
+// Start resets the tracker for the stats of the range, the events of the
+// range are applied by the caller.
+func (t *Tracker) Start(begin, end time.Time) {
+	t.Reset()
+	t.begin, t.end = begin, end
+}
+
+// Load resets the tracker and applies the events of the current operational
+// day from its beginning, the events of the day are shared by all the
+// trackers.
+func (t *Tracker) Load(ctx context.Context, service WSService) error {
+	begin, end, events, err := service.DayEvents(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	t.Start(begin, end)
+	for _, event := range events {
+		t.Apply(event)
+	}
+	return nil
+}
+
+// Day returns the bounds of the operational day of the stats.
+func (t *Tracker) Day() (time.Time, time.Time) {
+	return t.begin, t.end
+}
+
+// Expired reports whether the operational day of the stats is over, then the
+// tracker has to be loaded again.
+func (t *Tracker) Expired(now time.Time) bool {
+	return !now.Before(t.end)
+}
+
+func (t *Tracker) Stats() *resp.Stats {
+	t.stats.Events = t.evnts
+	return t.stats
+}
+
+// Counters returns the counters of the stats without the list of the events.
+func (t *Tracker) Counters() resp.MonitorCounters {
+	return resp.MonitorCounters{
+		CountInside:  t.stats.CountInside,
+		CountOutside: t.stats.CountOutside,
+		AnomalyIn:    t.stats.AnomalyIn,
+		AnomalyOut:   t.stats.AnomalyOut,
+	}
+}
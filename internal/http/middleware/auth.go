@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"errors"
+
+	"github.com/Izumra/SKUD_OKEI/domain/entity"
+	valueobject "github.com/Izumra/SKUD_OKEI/domain/value-object"
+	"github.com/Izumra/SKUD_OKEI/internal/lib/response"
+	"github.com/Izumra/SKUD_OKEI/internal/services/auth"
+	"github.com/Izumra/SKUD_OKEI/internal/storage/cache"
+	"github.com/gofiber/fiber/v2"
+)
+
+// UserKey is the key of the locals keeping the user of the session.
+const UserKey = "user"
+
+var (
+	ErrUnauthorized = errors.New("для продолжения действия требуется авторизация")
+	ErrAccessDenied = errors.New("вам отказано в доступе")
+)
+
+// Auth resolves the session of the request once and puts its user into the
+// locals, the requests without the valid session are rejected. The access to
+// the departments is resolved by the current permissions of the role.
+func Auth(sessStorage auth.SessionStorage, checker PermissionChecker) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		sessionId := c.Cookies("session", "")
+		if sessionId == "" {
+			c.Status(fiber.StatusUnauthorized)
+			return c.JSON(response.BadRes(ErrUnauthorized))
+		}
+
+		user, err := sessStorage.GetByID(c.Context(), sessionId)
+		if err != nil {
+			if errors.Is(err, cache.ErrSessionNotFound) {
+				c.Status(fiber.StatusUnauthorized)
+				return c.JSON(response.BadRes(ErrUnauthorized))
+			}
+			c.Status(fiber.StatusInternalServerError)
+			return c.JSON(response.BadRes(err))
+		}
+
+		// the user of the session is shared by its requests, so the copy is
+		// changed
+		current := *user
+		current.AllDepartments = checker.Allowed(user.Role, valueobject.DepartmentsAll)
+
+		c.Locals(UserKey, &current)
+		return c.Next()
+	}
+}
+
+// PermissionChecker reports whether the role has the permission.
+type PermissionChecker interface {
+	Allowed(role valueobject.Role, permission valueobject.Permission) bool
+}
+
+// Guard returns the handler which rejects the users without the permission,
+// every route declares the permission its handler needs by the guard.
+type Guard func(permission valueobject.Permission) fiber.Handler
+
+// Permissions builds the guard checking the permissions of the roles, it is
+// used after Auth.
+func Permissions(checker PermissionChecker) Guard {
+	return func(permission valueobject.Permission) fiber.Handler {
+		return func(c *fiber.Ctx) error {
+			user := User(c)
+			if user == nil {
+				c.Status(fiber.StatusUnauthorized)
+				return c.JSON(response.BadRes(ErrUnauthorized))
+			}
+
+			if !checker.Allowed(user.Role, permission) {
+				c.Status(fiber.StatusForbidden)
+				return c.JSON(response.BadRes(ErrAccessDenied))
+			}
+
+			return c.Next()
+		}
+	}
+}
+
+// User returns the user put into the locals by Auth.
+func User(c *fiber.Ctx) *entity.User {
+	user, _ := c.Locals(UserKey).(*entity.User)
+	return user
+}
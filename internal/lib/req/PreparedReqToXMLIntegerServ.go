@@ -8,26 +8,14 @@ import (
 	"github.com/Izumra/SKUD_OKEI/domain/dto/integrserv"
 )
 
-func PreparedReqToXMLIntegerServ(
+func (t *Transport) PreparedReqToXMLIntegerServ(
 	ctx context.Context,
 	serverMethod string,
 	serverAddres string,
 	data any,
 	respBody any,
 ) error {
-	headers := map[string]string{
-		"Content-Type": "text/xml; charset=utf-8",
-		"SOAPAction":   fmt.Sprintf("urn:OrionProIntf-IOrionPro#%s", serverMethod),
-	}
-
-	preparedBody := integrserv.EnvelopeReq{
-		XmlsSoap: "http://schemas.xmlsoap.org/soap/envelope/",
-		BodySoap: integrserv.BodyReq{
-			Data: data,
-		},
-	}
-
-	body, err := xml.Marshal(preparedBody)
+	headers, body, err := prepareReq(serverMethod, data)
 	if err != nil {
 		return err
 	}
@@ -40,9 +28,9 @@ func PreparedReqToXMLIntegerServ(
 		OperationResult: respBody,
 	}
 
-	err = ReqToXMLIntegerServ(
+	err = t.ReqToXMLIntegerServ(
 		ctx,
-		"POST",
+		serverMethod,
 		serverAddres,
 		headers,
 		body,
@@ -51,3 +39,24 @@ func PreparedReqToXMLIntegerServ(
 
 	return err
 }
+
+func prepareReq(serverMethod string, data any) (map[string]string, []byte, error) {
+	headers := map[string]string{
+		"Content-Type": "text/xml; charset=utf-8",
+		"SOAPAction":   fmt.Sprintf("urn:OrionProIntf-IOrionPro#%s", serverMethod),
+	}
+
+	preparedBody := integrserv.EnvelopeReq{
+		XmlsSoap: "http://schemas.xmlsoap.org/soap/envelope/",
+		BodySoap: integrserv.BodyReq{
+			Data: data,
+		},
+	}
+
+	body, err := xml.Marshal(preparedBody)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return headers, body, nil
+}
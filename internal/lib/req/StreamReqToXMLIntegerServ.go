@@ -0,0 +1,103 @@
+package req
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/Izumra/SKUD_OKEI/domain/dto/integrserv"
+)
+
+// Yield receives every element of the response body with the expected name,
+// the element is decoded by the callback with dec.DecodeElement.
+type Yield func(dec *xml.Decoder, start *xml.StartElement) error
+
+func (t *Transport) PreparedStreamReqToXMLIntegerServ(
+	ctx context.Context,
+	serverMethod string,
+	serverAddres string,
+	data any,
+	element string,
+	yield Yield,
+) error {
+	headers, body, err := prepareReq(serverMethod, data)
+	if err != nil {
+		return err
+	}
+
+	return t.StreamReqToXMLIntegerServ(ctx, serverMethod, serverAddres, headers, body, element, yield)
+}
+
+// StreamReqToXMLIntegerServ decodes the response token by token, so the
+// memory does not depend on the count of the elements in the response.
+func (t *Transport) StreamReqToXMLIntegerServ(ctx context.Context, serverMethod string, url string, headers map[string]string, body []byte, element string, yield Yield) error {
+	resp, closeResp, err := t.open(ctx, serverMethod, url, headers, body)
+	if err != nil {
+		return err
+	}
+	defer closeResp()
+
+	if resp.StatusCode != http.StatusOK {
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrMalformedResponse, err)
+		}
+		return checkResponse(serverMethod, resp.StatusCode, data)
+	}
+
+	dec := xml.NewDecoder(resp.Body)
+	depth := 0
+	for {
+		token, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("%w: %v", ErrMalformedResponse, err)
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			depth++
+			if depth == 1 && token.Name.Local != "Envelope" {
+				return fmt.Errorf("%w: ожидался конверт SOAP, получен элемент %s", ErrMalformedResponse, token.Name.Local)
+			}
+			if depth != 3 {
+				continue
+			}
+
+			switch token.Name.Local {
+			case element:
+				err = yield(dec, &token)
+			case "Fault":
+				var fault soapFault
+				err = dec.DecodeElement(&fault, &token)
+				if err == nil {
+					err = fault.toError(resp.StatusCode)
+				}
+			case "ServiceError":
+				var serviceErr integrserv.ServiceError
+				err = dec.DecodeElement(&serviceErr, &token)
+				if err == nil {
+					err = toServiceError(serverMethod, &serviceErr)
+				}
+			default:
+				err = dec.Skip()
+			}
+			if err != nil {
+				return err
+			}
+			depth--
+		case xml.EndElement:
+			depth--
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,149 @@
+package req
+
+import (
+	"sync"
+	"time"
+)
+
+type BreakerState int
+
+const (
+	StateClosed BreakerState = iota
+	StateOpen
+	StateHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+type StateChange struct {
+	From BreakerState
+	To   BreakerState
+	Err  error
+	At   time.Time
+}
+
+// Breaker stops the calls to IntegrServ after threshold consecutive failures
+// and lets a single probe call through once the cooldown has passed.
+type Breaker struct {
+	mu          sync.Mutex
+	threshold   int
+	cooldown    time.Duration
+	state       BreakerState
+	failures    int
+	openedAt    time.Time
+	probing     bool
+	subscribers []chan StateChange
+}
+
+func NewBreaker(threshold int, cooldown time.Duration) *Breaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+
+	return &Breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+	}
+}
+
+// Subscribe returns the channel with the state changes of the breaker. The
+// changes are dropped when the subscriber does not keep up with them.
+func (b *Breaker) Subscribe(buffer int) <-chan StateChange {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan StateChange, buffer)
+	b.subscribers = append(b.subscribers, ch)
+
+	return ch
+}
+
+func (b *Breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return ErrOrionUnavailable
+		}
+		b.setState(StateHalfOpen, nil)
+		b.probing = true
+		return nil
+	case StateHalfOpen:
+		if b.probing {
+			return ErrOrionUnavailable
+		}
+		b.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.probing = false
+	if b.state != StateClosed {
+		b.setState(StateClosed, nil)
+	}
+}
+
+// Release frees the probe slot without judging the state of IntegrServ, for
+// the calls cancelled by the caller.
+func (b *Breaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+func (b *Breaker) Failure(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.probing = false
+	if b.state == StateHalfOpen || (b.state == StateClosed && b.failures >= b.threshold) {
+		b.openedAt = time.Now()
+		b.setState(StateOpen, err)
+	}
+}
+
+func (b *Breaker) setState(state BreakerState, err error) {
+	change := StateChange{
+		From: b.state,
+		To:   state,
+		Err:  err,
+		At:   time.Now(),
+	}
+	b.state = state
+
+	for _, ch := range b.subscribers {
+		select {
+		case ch <- change:
+		default:
+		}
+	}
+}
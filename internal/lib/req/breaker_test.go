@@ -0,0 +1,122 @@
+package req
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	tests := []struct {
+		name      string
+		threshold int
+		cooldown  time.Duration
+		// steps are "fail", "success", "allow" and "wait", the last waits
+		// for the cooldown to pass
+		steps     []string
+		wantState BreakerState
+		wantAllow error
+	}{
+		{
+			name:      "stays closed below the threshold",
+			threshold: 3,
+			cooldown:  time.Minute,
+			steps:     []string{"fail", "fail"},
+			wantState: StateClosed,
+		},
+		{
+			name:      "opens at the threshold",
+			threshold: 3,
+			cooldown:  time.Minute,
+			steps:     []string{"fail", "fail", "fail"},
+			wantState: StateOpen,
+			wantAllow: ErrOrionUnavailable,
+		},
+		{
+			name:      "success resets the failures",
+			threshold: 3,
+			cooldown:  time.Minute,
+			steps:     []string{"fail", "fail", "success", "fail", "fail"},
+			wantState: StateClosed,
+		},
+		{
+			name:      "lets the probe through after the cooldown",
+			threshold: 1,
+			cooldown:  10 * time.Millisecond,
+			steps:     []string{"fail", "wait"},
+			wantState: StateHalfOpen,
+		},
+		{
+			name:      "lets a single probe through",
+			threshold: 1,
+			cooldown:  10 * time.Millisecond,
+			steps:     []string{"fail", "wait", "allow"},
+			wantState: StateHalfOpen,
+			wantAllow: ErrOrionUnavailable,
+		},
+		{
+			name:      "closes on the successful probe",
+			threshold: 1,
+			cooldown:  10 * time.Millisecond,
+			steps:     []string{"fail", "wait", "allow", "success"},
+			wantState: StateClosed,
+		},
+		{
+			name:      "opens again on the failed probe",
+			threshold: 2,
+			cooldown:  10 * time.Millisecond,
+			steps:     []string{"fail", "fail", "wait", "allow", "fail"},
+			wantState: StateOpen,
+			wantAllow: ErrOrionUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBreaker(tt.threshold, tt.cooldown)
+
+			for _, step := range tt.steps {
+				switch step {
+				case "fail":
+					b.Failure(errFailed)
+				case "success":
+					b.Success()
+				case "allow":
+					if err := b.Allow(); err != nil {
+						t.Fatalf("Allow() = %v, want nil", err)
+					}
+				case "wait":
+					time.Sleep(2 * tt.cooldown)
+				}
+			}
+
+			// the probe after the cooldown is let through by Allow, so the
+			// state is checked after it
+			err := b.Allow()
+			if !errors.Is(err, tt.wantAllow) {
+				t.Errorf("Allow() = %v, want %v", err, tt.wantAllow)
+			}
+			if state := b.State(); state != tt.wantState {
+				t.Errorf("State() = %s, want %s", state, tt.wantState)
+			}
+		})
+	}
+}
+
+func TestBreakerSubscribe(t *testing.T) {
+	b := NewBreaker(1, time.Minute)
+	changes := b.Subscribe(1)
+
+	b.Failure(errors.New("failed"))
+
+	select {
+	case change := <-changes:
+		if change.From != StateClosed || change.To != StateOpen {
+			t.Errorf("change = %s -> %s, want closed -> open", change.From, change.To)
+		}
+	default:
+		t.Fatal("the change of the state was not sent")
+	}
+}
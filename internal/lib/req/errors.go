@@ -0,0 +1,114 @@
+package req
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Izumra/SKUD_OKEI/domain/dto/integrserv"
+)
+
+var (
+	ErrMalformedResponse = errors.New("некорректный ответ службы IntegrServ")
+)
+
+// SOAPFault is the <SOAP-ENV:Fault> sent by IntegrServ when it could not
+// process the request at all.
+type SOAPFault struct {
+	HTTPStatus int
+	Code       string
+	String     string
+	Detail     string
+}
+
+func (f *SOAPFault) Error() string {
+	return fmt.Sprintf("ошибка SOAP %s: %s", f.Code, f.String)
+}
+
+// ServiceError is the error result of the Orion method, the request itself
+// was processed by IntegrServ.
+type ServiceError struct {
+	Method                string
+	ErrorCode             string
+	Description           string
+	InnerExceptionMessage string
+}
+
+func (e *ServiceError) Error() string {
+	if e.Description == "" {
+		return e.InnerExceptionMessage
+	}
+	if e.InnerExceptionMessage == "" || e.InnerExceptionMessage == e.Description {
+		return e.Description
+	}
+
+	return fmt.Sprintf("%s: %s", e.Description, e.InnerExceptionMessage)
+}
+
+type soapFault struct {
+	Code   string `xml:"faultcode"`
+	String string `xml:"faultstring"`
+	Detail struct {
+		Content string `xml:",innerxml"`
+	} `xml:"detail"`
+}
+
+func (f *soapFault) toError(status int) error {
+	return &SOAPFault{
+		HTTPStatus: status,
+		Code:       f.Code,
+		String:     f.String,
+		Detail:     f.Detail.Content,
+	}
+}
+
+func toServiceError(serverMethod string, serviceErr *integrserv.ServiceError) error {
+	if serviceErr.Description == "" && serviceErr.InnerExceptionMessage == "" {
+		return nil
+	}
+
+	return &ServiceError{
+		Method:                serverMethod,
+		ErrorCode:             serviceErr.ErrorCode,
+		Description:           serviceErr.Description,
+		InnerExceptionMessage: serviceErr.InnerExceptionMessage,
+	}
+}
+
+type probeEnvelope struct {
+	XMLName xml.Name `xml:"Envelope"`
+	Body    struct {
+		Fault        *soapFault               `xml:"Fault"`
+		ServiceError *integrserv.ServiceError `xml:"ServiceError"`
+	} `xml:"Body"`
+}
+
+// checkResponse looks for the SOAP fault or the Orion service error in the
+// response before it is decoded into the expected result.
+func checkResponse(serverMethod string, status int, data []byte) error {
+	var probe probeEnvelope
+	err := xml.Unmarshal(data, &probe)
+	if err != nil {
+		if status < 200 || status >= 300 {
+			return fmt.Errorf("%w: статус %d", ErrMalformedResponse, status)
+		}
+		return fmt.Errorf("%w: %v", ErrMalformedResponse, err)
+	}
+
+	if probe.Body.Fault != nil {
+		return probe.Body.Fault.toError(status)
+	}
+
+	if probe.Body.ServiceError != nil {
+		if err := toServiceError(serverMethod, probe.Body.ServiceError); err != nil {
+			return err
+		}
+	}
+
+	if status != http.StatusOK {
+		return fmt.Errorf("%w: статус %d", ErrMalformedResponse, status)
+	}
+
+	return nil
+}
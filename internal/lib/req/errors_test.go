@@ -0,0 +1,178 @@
+package req
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Izumra/SKUD_OKEI/domain/dto/integrserv"
+)
+
+const (
+	faultBody = `<?xml version="1.0"?><SOAP-ENV:Envelope xmlns:SOAP-ENV="http://schemas.xmlsoap.org/soap/envelope/"><SOAP-ENV:Body>` +
+		`<SOAP-ENV:Fault><faultcode>SOAP-ENV:Server</faultcode><faultstring>Access violation</faultstring></SOAP-ENV:Fault>` +
+		`</SOAP-ENV:Body></SOAP-ENV:Envelope>`
+	serviceErrorBody = `<?xml version="1.0"?><SOAP-ENV:Envelope xmlns:SOAP-ENV="http://schemas.xmlsoap.org/soap/envelope/" xmlns:NS2="urn:OrionProIntf"><SOAP-ENV:Body>` +
+		`<NS2:ServiceError><Description>Ключ не найден</Description><InnerExceptionMessage>Key not found</InnerExceptionMessage></NS2:ServiceError>` +
+		`</SOAP-ENV:Body></SOAP-ENV:Envelope>`
+	emptyServiceErrorBody = `<?xml version="1.0"?><SOAP-ENV:Envelope xmlns:SOAP-ENV="http://schemas.xmlsoap.org/soap/envelope/" xmlns:NS2="urn:OrionProIntf"><SOAP-ENV:Body>` +
+		`<NS2:ServiceError><Description></Description><InnerExceptionMessage></InnerExceptionMessage></NS2:ServiceError>` +
+		`</SOAP-ENV:Body></SOAP-ENV:Envelope>`
+	resultBody = `<?xml version="1.0"?><SOAP-ENV:Envelope xmlns:SOAP-ENV="http://schemas.xmlsoap.org/soap/envelope/"><SOAP-ENV:Body>` +
+		`<NS1:GetPersonsCountResponse xmlns:NS1="urn:OrionProIntf-IOrionPro"></NS1:GetPersonsCountResponse>` +
+		`</SOAP-ENV:Body></SOAP-ENV:Envelope>`
+)
+
+func TestCheckResponse(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		wantErr     error
+		wantFault   *SOAPFault
+		wantService *ServiceError
+	}{
+		{
+			name:   "result",
+			status: http.StatusOK,
+			body:   resultBody,
+		},
+		{
+			name:   "fault with the error status",
+			status: http.StatusInternalServerError,
+			body:   faultBody,
+			wantFault: &SOAPFault{
+				HTTPStatus: http.StatusInternalServerError,
+				Code:       "SOAP-ENV:Server",
+				String:     "Access violation",
+			},
+		},
+		{
+			name:   "service error",
+			status: http.StatusOK,
+			body:   serviceErrorBody,
+			wantService: &ServiceError{
+				Method:                "GetKeyData",
+				Description:           "Ключ не найден",
+				InnerExceptionMessage: "Key not found",
+			},
+		},
+		{
+			name:   "empty service error",
+			status: http.StatusOK,
+			body:   emptyServiceErrorBody,
+		},
+		{
+			name:    "result with the error status",
+			status:  http.StatusInternalServerError,
+			body:    resultBody,
+			wantErr: ErrMalformedResponse,
+		},
+		{
+			name:    "malformed body",
+			status:  http.StatusOK,
+			body:    "<html><body>Proxy error",
+			wantErr: ErrMalformedResponse,
+		},
+		{
+			name:    "malformed body with the error status",
+			status:  http.StatusInternalServerError,
+			body:    "Internal Server Error",
+			wantErr: ErrMalformedResponse,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkResponse("GetKeyData", tt.status, []byte(tt.body))
+
+			switch {
+			case tt.wantFault != nil:
+				var fault *SOAPFault
+				if !errors.As(err, &fault) || fault.HTTPStatus != tt.wantFault.HTTPStatus || fault.Code != tt.wantFault.Code || fault.String != tt.wantFault.String {
+					t.Errorf("checkResponse() = %#v, want %#v", err, tt.wantFault)
+				}
+			case tt.wantService != nil:
+				var serviceErr *ServiceError
+				if !errors.As(err, &serviceErr) || *serviceErr != *tt.wantService {
+					t.Errorf("checkResponse() = %#v, want %#v", err, tt.wantService)
+				}
+			default:
+				if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+					t.Errorf("checkResponse() = %v, want %v", err, tt.wantErr)
+				}
+			}
+		})
+	}
+}
+
+// TestResponseErrors checks that the errors answered by IntegrServ are passed
+// to the caller without being counted by the breaker, IntegrServ is alive.
+func TestResponseErrors(t *testing.T) {
+	server, fakeURL, _ := newFakeServer(t, 5)
+	server.FailNext("GetPersonsCount", integrserv.Error{Description: "Ошибка базы данных"})
+
+	malformed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html><body>Proxy error"))
+	}))
+	t.Cleanup(malformed.Close)
+
+	tests := []struct {
+		name    string
+		url     string
+		method  string
+		wantErr any
+	}{
+		{
+			name:    "service error",
+			url:     fakeURL,
+			method:  "GetPersonsCount",
+			wantErr: new(*ServiceError),
+		},
+		{
+			name:    "fault of the unknown method",
+			url:     fakeURL,
+			method:  "GetUnknown",
+			wantErr: new(*SOAPFault),
+		},
+		{
+			name:    "malformed body",
+			url:     malformed.URL,
+			method:  "GetPersonsCount",
+			wantErr: &ErrMalformedResponse,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := NewTransport(TransportConfig{
+				Timeout:          time.Second,
+				Retries:          2,
+				RetryBackoff:     time.Millisecond,
+				BreakerThreshold: 1,
+			})
+
+			var respBody struct{}
+			err := transport.PreparedReqToXMLIntegerServ(context.Background(), tt.method, tt.url, struct {
+				XMLName xml.Name
+			}{
+				XMLName: xml.Name{Local: tt.method},
+			}, &respBody)
+
+			if target, ok := tt.wantErr.(*error); ok {
+				if !errors.Is(err, *target) {
+					t.Errorf("err = %v, want %v", err, *target)
+				}
+			} else if !errors.As(err, tt.wantErr) {
+				t.Errorf("err = %#v, want %T", err, tt.wantErr)
+			}
+			if state := transport.Breaker().State(); state != StateClosed {
+				t.Errorf("breaker = %s, want %s", state, StateClosed)
+			}
+		})
+	}
+}
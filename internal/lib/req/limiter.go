@@ -0,0 +1,183 @@
+package req
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type LimiterStats struct {
+	MaxConcurrent int
+	InFlight      int
+	Queued        int
+	Calls         int64
+	Coalesced     int64
+	AvgWait       time.Duration
+	MaxWait       time.Duration
+	AvgLatency    time.Duration
+	MaxLatency    time.Duration
+}
+
+type flight struct {
+	done    chan struct{}
+	cancel  context.CancelFunc
+	waiters int
+	data    []byte
+	status  int
+	err     error
+}
+
+// Limiter caps the count of the concurrent requests to IntegrServ and
+// coalesces the identical requests which are in flight at the same time.
+type Limiter struct {
+	slots chan struct{}
+
+	mu           sync.Mutex
+	flights      map[string]*flight
+	queued       int
+	calls        int64
+	completed    int64
+	coalesced    int64
+	totalWait    time.Duration
+	maxWait      time.Duration
+	totalLatency time.Duration
+	maxLatency   time.Duration
+}
+
+func NewLimiter(maxConcurrent int) *Limiter {
+	if maxConcurrent <= 0 {
+		maxConcurrent = 4
+	}
+
+	return &Limiter{
+		slots:   make(chan struct{}, maxConcurrent),
+		flights: map[string]*flight{},
+	}
+}
+
+// Acquire waits for a free slot, the returned func frees the slot and
+// accounts the latency of the call.
+func (l *Limiter) Acquire(ctx context.Context) (func(), error) {
+	queuedAt := time.Now()
+
+	l.mu.Lock()
+	l.queued++
+	l.mu.Unlock()
+
+	select {
+	case l.slots <- struct{}{}:
+	case <-ctx.Done():
+		l.mu.Lock()
+		l.queued--
+		l.mu.Unlock()
+		return nil, ctx.Err()
+	}
+
+	startedAt := time.Now()
+	wait := startedAt.Sub(queuedAt)
+
+	l.mu.Lock()
+	l.queued--
+	l.calls++
+	l.totalWait += wait
+	l.maxWait = max(l.maxWait, wait)
+	l.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			latency := time.Since(startedAt)
+			<-l.slots
+
+			l.mu.Lock()
+			l.completed++
+			l.totalLatency += latency
+			l.maxLatency = max(l.maxLatency, latency)
+			l.mu.Unlock()
+		})
+	}, nil
+}
+
+// Do runs fn once for all the callers with the same key, the call is
+// canceled when every caller has left.
+func (l *Limiter) Do(ctx context.Context, key string, fn func(ctx context.Context) ([]byte, int, error)) ([]byte, int, error) {
+	l.mu.Lock()
+	f, ok := l.flights[key]
+	if ok {
+		f.waiters++
+		l.coalesced++
+		l.mu.Unlock()
+	} else {
+		flightCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		f = &flight{
+			done:    make(chan struct{}),
+			cancel:  cancel,
+			waiters: 1,
+		}
+		l.flights[key] = f
+		l.mu.Unlock()
+
+		go l.run(flightCtx, key, f, fn)
+	}
+
+	select {
+	case <-f.done:
+		return f.data, f.status, f.err
+	case <-ctx.Done():
+		l.mu.Lock()
+		f.waiters--
+		if f.waiters == 0 {
+			// The canceled flight must not be joined by the new callers,
+			// they start their own one.
+			if l.flights[key] == f {
+				delete(l.flights, key)
+			}
+			f.cancel()
+		}
+		l.mu.Unlock()
+		return nil, 0, ctx.Err()
+	}
+}
+
+func (l *Limiter) run(ctx context.Context, key string, f *flight, fn func(ctx context.Context) ([]byte, int, error)) {
+	defer f.cancel()
+
+	release, err := l.Acquire(ctx)
+	if err == nil {
+		f.data, f.status, f.err = fn(ctx)
+		release()
+	} else {
+		f.err = err
+	}
+
+	l.mu.Lock()
+	if l.flights[key] == f {
+		delete(l.flights, key)
+	}
+	l.mu.Unlock()
+
+	close(f.done)
+}
+
+func (l *Limiter) Stats() LimiterStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stats := LimiterStats{
+		MaxConcurrent: cap(l.slots),
+		InFlight:      len(l.slots),
+		Queued:        l.queued,
+		Calls:         l.calls,
+		Coalesced:     l.coalesced,
+		MaxWait:       l.maxWait,
+		MaxLatency:    l.maxLatency,
+	}
+	if l.calls != 0 {
+		stats.AvgWait = l.totalWait / time.Duration(l.calls)
+	}
+	if l.completed != 0 {
+		stats.AvgLatency = l.totalLatency / time.Duration(l.completed)
+	}
+
+	return stats
+}
@@ -0,0 +1,162 @@
+package req
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLimiterDo(t *testing.T) {
+	tests := []struct {
+		name      string
+		keys      []string
+		wantCalls int64
+	}{
+		{
+			name:      "coalesces the identical calls",
+			keys:      []string{"a", "a", "a", "a"},
+			wantCalls: 1,
+		},
+		{
+			name:      "runs the different calls",
+			keys:      []string{"a", "b", "c"},
+			wantCalls: 3,
+		},
+		{
+			name:      "coalesces the calls by the key",
+			keys:      []string{"a", "b", "a", "b"},
+			wantCalls: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLimiter(len(tt.keys))
+
+			var calls atomic.Int64
+			release := make(chan struct{})
+			fn := func(ctx context.Context) ([]byte, int, error) {
+				calls.Add(1)
+				<-release
+				return []byte("data"), 200, nil
+			}
+
+			var wg sync.WaitGroup
+			results := make([]string, len(tt.keys))
+			for i, key := range tt.keys {
+				wg.Add(1)
+				go func(i int, key string) {
+					defer wg.Done()
+					data, _, err := l.Do(context.Background(), key, fn)
+					if err != nil {
+						t.Errorf("Do(%s) err = %v", key, err)
+					}
+					results[i] = string(data)
+				}(i, key)
+			}
+
+			waitFor(t, func() bool {
+				return l.Stats().Coalesced == int64(len(tt.keys))-tt.wantCalls
+			})
+			close(release)
+			wg.Wait()
+
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", got, tt.wantCalls)
+			}
+			for i, data := range results {
+				if data != "data" {
+					t.Errorf("result %d = %q, want %q", i, data, "data")
+				}
+			}
+		})
+	}
+}
+
+func TestLimiterCap(t *testing.T) {
+	l := NewLimiter(2)
+
+	var running, maxRunning atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := l.Acquire(context.Background())
+			if err != nil {
+				t.Errorf("Acquire() err = %v", err)
+				return
+			}
+			defer release()
+
+			n := running.Add(1)
+			for {
+				current := maxRunning.Load()
+				if n <= current || maxRunning.CompareAndSwap(current, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			running.Add(-1)
+		}()
+	}
+	wg.Wait()
+
+	if got := maxRunning.Load(); got > 2 {
+		t.Errorf("concurrent calls = %d, want at most 2", got)
+	}
+	if stats := l.Stats(); stats.Calls != 6 || stats.InFlight != 0 {
+		t.Errorf("stats = %+v, want 6 calls and none in flight", stats)
+	}
+}
+
+func TestLimiterCanceledFlight(t *testing.T) {
+	l := NewLimiter(2)
+
+	canceled := make(chan struct{})
+	finish := make(chan struct{})
+	defer close(finish)
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	_, _, err := l.Do(ctx, "a", func(ctx context.Context) ([]byte, int, error) {
+		<-ctx.Done()
+		close(canceled)
+		<-finish
+		return nil, 0, ctx.Err()
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want %v", err, context.Canceled)
+	}
+
+	// the call is canceled when its last caller has left, the new caller does
+	// not join it while it finishes and starts its own one
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("the call was not canceled")
+	}
+	data, _, err := l.Do(context.Background(), "a", func(ctx context.Context) ([]byte, int, error) {
+		return []byte("data"), 200, nil
+	})
+	if err != nil || string(data) != "data" {
+		t.Errorf("Do() = %q, %v, want %q, nil", data, err, "data")
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("the condition was not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
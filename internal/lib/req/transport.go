@@ -0,0 +1,203 @@
+package req
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	ErrOrionUnavailable = errors.New("служба IntegrServ недоступна")
+)
+
+type TransportConfig struct {
+	Timeout          time.Duration
+	Retries          int
+	RetryBackoff     time.Duration
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+	MaxConcurrent    int
+}
+
+type Transport struct {
+	client  *http.Client
+	cfg     TransportConfig
+	breaker *Breaker
+	limiter *Limiter
+}
+
+func NewTransport(cfg TransportConfig) *Transport {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = 500 * time.Millisecond
+	}
+	if cfg.BreakerCooldown <= 0 {
+		cfg.BreakerCooldown = 30 * time.Second
+	}
+
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	base.ResponseHeaderTimeout = cfg.Timeout
+
+	return &Transport{
+		client: &http.Client{
+			Transport: base,
+		},
+		cfg:     cfg,
+		breaker: NewBreaker(cfg.BreakerThreshold, cfg.BreakerCooldown),
+		limiter: NewLimiter(cfg.MaxConcurrent),
+	}
+}
+
+func (t *Transport) Breaker() *Breaker {
+	return t.breaker
+}
+
+func (t *Transport) Limiter() *Limiter {
+	return t.limiter
+}
+
+// isIdempotent reports whether the IntegrServ method only reads the data, so
+// it is safe to send it again after a failure.
+func isIdempotent(serverMethod string) bool {
+	return strings.HasPrefix(serverMethod, "Get") || strings.HasPrefix(serverMethod, "Convert")
+}
+
+// do sends the request within the limits of the limiter, the identical
+// reading requests share the single call.
+func (t *Transport) do(ctx context.Context, serverMethod string, url string, headers map[string]string, body []byte) ([]byte, int, error) {
+	if isIdempotent(serverMethod) {
+		key := serverMethod + "\x00" + string(body)
+		return t.limiter.Do(ctx, key, func(ctx context.Context) ([]byte, int, error) {
+			return t.read(ctx, serverMethod, url, headers, body)
+		})
+	}
+
+	release, err := t.limiter.Acquire(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer release()
+
+	return t.read(ctx, serverMethod, url, headers, body)
+}
+
+func (t *Transport) read(ctx context.Context, serverMethod string, url string, headers map[string]string, body []byte) ([]byte, int, error) {
+	var data []byte
+	var status int
+	err := t.retry(ctx, serverMethod, func() error {
+		ctx, cancel := context.WithTimeout(ctx, t.cfg.Timeout)
+		defer cancel()
+
+		resp, err := t.send(ctx, url, headers, body)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		data, err = io.ReadAll(resp.Body)
+		status = resp.StatusCode
+		return err
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return data, status, nil
+}
+
+// open returns the response as soon as its headers arrived, the body is read
+// by the caller and is not covered by the retries. The slot of the limiter is
+// held until the returned func is called.
+func (t *Transport) open(ctx context.Context, serverMethod string, url string, headers map[string]string, body []byte) (*http.Response, func(), error) {
+	release, err := t.limiter.Acquire(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var resp *http.Response
+	err = t.retry(ctx, serverMethod, func() error {
+		var err error
+		resp, err = t.send(ctx, url, headers, body)
+		return err
+	})
+	if err != nil {
+		release()
+		return nil, nil, err
+	}
+
+	return resp, func() {
+		resp.Body.Close()
+		release()
+	}, nil
+}
+
+func (t *Transport) retry(ctx context.Context, serverMethod string, attemptFn func() error) error {
+	attempts := 1
+	if isIdempotent(serverMethod) {
+		attempts += t.cfg.Retries
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt != 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(t.cfg.RetryBackoff << (attempt - 1)):
+			}
+		}
+
+		if err := t.breaker.Allow(); err != nil {
+			if lastErr != nil {
+				return fmt.Errorf("%w: %v", ErrOrionUnavailable, lastErr)
+			}
+			return err
+		}
+
+		err := attemptFn()
+		if err == nil {
+			t.breaker.Success()
+			return nil
+		}
+		if ctx.Err() != nil {
+			t.breaker.Release()
+			return ctx.Err()
+		}
+
+		t.breaker.Failure(err)
+		lastErr = err
+	}
+
+	return fmt.Errorf("%w: %v", ErrOrionUnavailable, lastErr)
+}
+
+func (t *Transport) send(ctx context.Context, url string, headers map[string]string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	for header, value := range headers {
+		req.Header.Add(header, value)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		resp.Body.Close()
+		return nil, fmt.Errorf("IntegrServ ответил статусом %s", resp.Status)
+	}
+
+	return resp, nil
+}
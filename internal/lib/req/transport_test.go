@@ -0,0 +1,170 @@
+package req
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Izumra/SKUD_OKEI/domain/dto/integrserv"
+	"github.com/Izumra/SKUD_OKEI/internal/orion/fake"
+)
+
+// newFakeServer serves the fake IntegrServ and counts the requests reached it.
+func newFakeServer(t *testing.T, persons int) (*fake.Server, string, *atomic.Int64) {
+	t.Helper()
+
+	server := fake.NewServer(fake.Generate(fake.DatasetConfig{
+		Seed:    1,
+		Persons: persons,
+		Days:    1,
+	}), 1)
+
+	var requests atomic.Int64
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		server.ServeHTTP(w, r)
+	}))
+	t.Cleanup(httpServer.Close)
+
+	return server, httpServer.URL + "/soap/IOrionPro", &requests
+}
+
+func personsCount(ctx context.Context, transport *Transport, url string) (int64, error) {
+	var count struct {
+		OperationResult int64
+	}
+	err := transport.PreparedReqToXMLIntegerServ(ctx, "GetPersonsCount", url, struct {
+		XMLName xml.Name `xml:"GetPersonsCount"`
+	}{}, &integrserv.OperationResultInt{
+		Result: &count,
+	})
+
+	return count.OperationResult, err
+}
+
+func TestTransportRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		down         bool
+		retries      int
+		threshold    int
+		wantRequests int64
+		wantErr      error
+		wantState    BreakerState
+	}{
+		{
+			name:         "reads at once",
+			method:       "GetPersonsCount",
+			retries:      2,
+			threshold:    5,
+			wantRequests: 1,
+			wantState:    StateClosed,
+		},
+		{
+			name:         "retries the reading",
+			method:       "GetPersonsCount",
+			down:         true,
+			retries:      2,
+			threshold:    5,
+			wantRequests: 3,
+			wantErr:      ErrOrionUnavailable,
+			wantState:    StateClosed,
+		},
+		{
+			name:         "does not retry the changing",
+			method:       "AddPerson",
+			down:         true,
+			retries:      2,
+			threshold:    5,
+			wantRequests: 1,
+			wantErr:      ErrOrionUnavailable,
+			wantState:    StateClosed,
+		},
+		{
+			name:         "stops the retries on the opened breaker",
+			method:       "GetPersonsCount",
+			down:         true,
+			retries:      5,
+			threshold:    2,
+			wantRequests: 2,
+			wantErr:      ErrOrionUnavailable,
+			wantState:    StateOpen,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, url, requests := newFakeServer(t, 10)
+			server.SetDown(tt.down)
+
+			transport := NewTransport(TransportConfig{
+				Timeout:          time.Second,
+				Retries:          tt.retries,
+				RetryBackoff:     time.Millisecond,
+				BreakerThreshold: tt.threshold,
+				BreakerCooldown:  time.Minute,
+			})
+
+			var respBody struct{}
+			err := transport.PreparedReqToXMLIntegerServ(context.Background(), tt.method, url, struct {
+				XMLName xml.Name
+			}{
+				XMLName: xml.Name{Local: tt.method},
+			}, &respBody)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+			if got := requests.Load(); got != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", got, tt.wantRequests)
+			}
+			if state := transport.Breaker().State(); state != tt.wantState {
+				t.Errorf("breaker = %s, want %s", state, tt.wantState)
+			}
+		})
+	}
+}
+
+func TestTransportOpenBreaker(t *testing.T) {
+	server, url, requests := newFakeServer(t, 10)
+
+	transport := NewTransport(TransportConfig{
+		Timeout:          time.Second,
+		RetryBackoff:     time.Millisecond,
+		BreakerThreshold: 1,
+		BreakerCooldown:  50 * time.Millisecond,
+	})
+
+	server.SetDown(true)
+	_, err := personsCount(context.Background(), transport, url)
+	if !errors.Is(err, ErrOrionUnavailable) {
+		t.Fatalf("err = %v, want %v", err, ErrOrionUnavailable)
+	}
+
+	// the opened breaker does not let the calls reach IntegrServ
+	server.SetDown(false)
+	_, err = personsCount(context.Background(), transport, url)
+	if !errors.Is(err, ErrOrionUnavailable) {
+		t.Fatalf("err = %v, want %v", err, ErrOrionUnavailable)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Fatalf("requests = %d, want 1", got)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	count, err := personsCount(context.Background(), transport, url)
+	if err != nil {
+		t.Fatalf("err = %v, want nil", err)
+	}
+	if count != 10 {
+		t.Errorf("count = %d, want 10", count)
+	}
+	if state := transport.Breaker().State(); state != StateClosed {
+		t.Errorf("breaker = %s, want %s", state, StateClosed)
+	}
+}
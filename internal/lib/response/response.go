@@ -20,3 +20,12 @@ func SuccessRes(data any) fiber.Map {
 		"error": nil,
 	}
 }
+
+// StaleRes marks the data served from the cache while IntegrServ is unavailable.
+func StaleRes(data any) fiber.Map {
+	return fiber.Map{
+		"data":  data,
+		"error": nil,
+		"stale": true,
+	}
+}
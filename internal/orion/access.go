@@ -0,0 +1,60 @@
+package orion
+
+import (
+	"context"
+	"encoding/xml"
+
+	"github.com/Izumra/SKUD_OKEI/domain/dto/integrserv"
+)
+
+type getAccessLevelsReq struct {
+	XMLName xml.Name `xml:"GetAccessLevels"`
+}
+
+type getAccessPointsReq struct {
+	XMLName xml.Name `xml:"GetAccessPoints"`
+}
+
+type getItemsReq struct {
+	XMLName  xml.Name `xml:"GetItems"`
+	ItemType string
+}
+
+func (c *Client) GetAccessLevels(ctx context.Context) ([]*integrserv.AccessLevel, error) {
+	var levels []*integrserv.AccessLevel
+	respBody := &integrserv.OperationResultAccessLevels{
+		Result: &levels,
+	}
+	err := c.call(ctx, "GetAccessLevels", getAccessLevelsReq{}, respBody)
+	if err != nil {
+		return nil, err
+	}
+
+	return levels, nil
+}
+
+func (c *Client) GetAccessPoints(ctx context.Context) ([]*integrserv.AccessPoint, error) {
+	var points []*integrserv.AccessPoint
+	respBody := &integrserv.OperationResultAccessPoints{
+		Result: &points,
+	}
+	err := c.call(ctx, "GetAccessPoints", getAccessPointsReq{}, respBody)
+	if err != nil {
+		return nil, err
+	}
+
+	return points, nil
+}
+
+func (c *Client) GetItems(ctx context.Context, itemType string) ([]*integrserv.Item, error) {
+	var items []*integrserv.Item
+	respBody := &integrserv.OperationResultItems{
+		Result: &items,
+	}
+	err := c.call(ctx, "GetItems", getItemsReq{ItemType: itemType}, respBody)
+	if err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
@@ -0,0 +1,198 @@
+package orion
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/Izumra/SKUD_OKEI/domain/dto/integrserv"
+	"github.com/Izumra/SKUD_OKEI/internal/lib/req"
+	"github.com/Izumra/SKUD_OKEI/internal/storage/cache"
+	"github.com/Izumra/SKUD_OKEI/internal/storage/cache/embedded"
+)
+
+type CacheConfig struct {
+	DepartmentsTTL time.Duration
+	PersonsTTL     time.Duration
+	KeysTTL        time.Duration
+}
+
+// CachedClient caches the reference data which changes rarely, the rest of
+// the methods are passed to the client as is.
+type CachedClient struct {
+	*Client
+	departments *embedded.TTLCache[struct{}, []*integrserv.Department]
+	persons     *embedded.TTLCache[int64, *integrserv.PersonData]
+	keys        *embedded.TTLCache[string, *integrserv.KeyData]
+	// personDepartments is the index of the departments of all the persons,
+	// the access of the users is limited by it. It is read only here, so it
+	// is not cloned.
+	personDepartments *embedded.TTLCache[struct{}, map[int64]int64]
+}
+
+func NewCachedClient(client *Client, cfg CacheConfig) *CachedClient {
+	if cfg.DepartmentsTTL <= 0 {
+		cfg.DepartmentsTTL = 10 * time.Minute
+	}
+	if cfg.PersonsTTL <= 0 {
+		cfg.PersonsTTL = 5 * time.Minute
+	}
+	if cfg.KeysTTL <= 0 {
+		cfg.KeysTTL = 5 * time.Minute
+	}
+
+	return &CachedClient{
+		Client:      client,
+		departments: embedded.NewTTLCache[struct{}, []*integrserv.Department]("departments", cfg.DepartmentsTTL, unavailable, cloneDepartments),
+		persons:     embedded.NewTTLCache[int64, *integrserv.PersonData]("persons", cfg.PersonsTTL, unavailable, clonePtr[integrserv.PersonData]),
+		keys:        embedded.NewTTLCache[string, *integrserv.KeyData]("keys", cfg.KeysTTL, unavailable, clonePtr[integrserv.KeyData]),
+
+		personDepartments: embedded.NewTTLCache[struct{}, map[int64]int64]("person_departments", cfg.PersonsTTL, unavailable, nil),
+	}
+}
+
+func unavailable(err error) bool {
+	return errors.Is(err, req.ErrOrionUnavailable) || errors.Is(err, context.DeadlineExceeded)
+}
+
+func clonePtr[T any](value *T) *T {
+	if value == nil {
+		return nil
+	}
+
+	cloned := *value
+	return &cloned
+}
+
+func cloneDepartments(departments []*integrserv.Department) []*integrserv.Department {
+	if departments == nil {
+		return nil
+	}
+
+	cloned := make([]*integrserv.Department, len(departments))
+	for i, department := range departments {
+		cloned[i] = clonePtr(department)
+	}
+	return cloned
+}
+
+func (cc *CachedClient) GetDepartments(ctx context.Context) ([]*integrserv.Department, error) {
+	return cc.departments.Get(ctx, struct{}{}, cc.Client.GetDepartments)
+}
+
+func (cc *CachedClient) GetPersonById(ctx context.Context, id int64) (*integrserv.PersonData, error) {
+	return cc.persons.Get(ctx, id, func(ctx context.Context) (*integrserv.PersonData, error) {
+		return cc.Client.GetPersonById(ctx, id)
+	})
+}
+
+// PersonDepartments returns the index of the departments of all the persons,
+// the index is shared by the callers, so it must not be changed.
+func (cc *CachedClient) PersonDepartments(ctx context.Context) (map[int64]int64, error) {
+	return cc.personDepartments.Get(ctx, struct{}{}, cc.Client.PersonDepartments)
+}
+
+// DepartmentPersons returns the ids of the persons of the departments. The
+// stale index is used when Orion is unavailable, the persons moved since
+// then keep their previous departments.
+func (cc *CachedClient) DepartmentPersons(ctx context.Context, departments []int64) (map[int64]bool, error) {
+	index, err := cc.PersonDepartments(ctx)
+	if err != nil && !errors.Is(err, cache.ErrStaleData) {
+		return nil, err
+	}
+
+	result := map[int64]bool{}
+	for personId, departmentId := range index {
+		if slices.Contains(departments, departmentId) {
+			result[personId] = true
+		}
+	}
+	return result, nil
+}
+
+func (cc *CachedClient) GetKeyData(ctx context.Context, cardNo string) (*integrserv.KeyData, error) {
+	return cc.keys.Get(ctx, cardNo, func(ctx context.Context) (*integrserv.KeyData, error) {
+		return cc.Client.GetKeyData(ctx, cardNo)
+	})
+}
+
+func (cc *CachedClient) AddPerson(ctx context.Context, data integrserv.PersonData) (*integrserv.PersonData, error) {
+	person, err := cc.Client.AddPerson(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+
+	cc.persons.Invalidate(person.Id)
+	cc.personDepartments.Flush()
+	return person, nil
+}
+
+func (cc *CachedClient) UpdatePerson(ctx context.Context, data integrserv.PersonData) (*integrserv.PersonData, error) {
+	person, err := cc.Client.UpdatePerson(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+
+	cc.persons.Invalidate(data.Id, person.Id)
+	cc.personDepartments.Flush()
+	return person, nil
+}
+
+func (cc *CachedClient) DeletePerson(ctx context.Context, data integrserv.PersonData) (*integrserv.PersonData, error) {
+	person, err := cc.Client.DeletePerson(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+
+	cc.persons.Invalidate(data.Id)
+	cc.personDepartments.Flush()
+	cc.keys.InvalidateFunc(func(_ string, key *integrserv.KeyData) bool {
+		return key != nil && key.PersonId == data.Id
+	})
+	return person, nil
+}
+
+func (cc *CachedClient) AddKey(ctx context.Context, keyData *integrserv.KeyData) (*integrserv.KeyData, error) {
+	key, err := cc.Client.AddKey(ctx, keyData)
+	if err != nil {
+		return nil, err
+	}
+
+	cc.invalidateKey(keyData)
+	return key, nil
+}
+
+func (cc *CachedClient) UpdateKeyData(ctx context.Context, keyData *integrserv.KeyData) (*integrserv.KeyData, error) {
+	key, err := cc.Client.UpdateKeyData(ctx, keyData)
+	if err != nil {
+		return nil, err
+	}
+
+	cc.invalidateKey(keyData)
+	return key, nil
+}
+
+// invalidateKey drops the entry by the code and by the id, because the code
+// of the key could be changed by the update.
+func (cc *CachedClient) invalidateKey(keyData *integrserv.KeyData) {
+	cc.keys.InvalidateFunc(func(code string, key *integrserv.KeyData) bool {
+		return code == keyData.Code || (key != nil && keyData.Id != 0 && key.Id == keyData.Id)
+	})
+}
+
+func (cc *CachedClient) Flush() {
+	cc.departments.Flush()
+	cc.persons.Flush()
+	cc.keys.Flush()
+	cc.personDepartments.Flush()
+}
+
+func (cc *CachedClient) CacheStats() []cache.Stats {
+	return []cache.Stats{
+		cc.departments.Stats(),
+		cc.persons.Stats(),
+		cc.keys.Stats(),
+		cc.personDepartments.Stats(),
+	}
+}
@@ -0,0 +1,33 @@
+package orion
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Izumra/SKUD_OKEI/internal/lib/req"
+)
+
+type Client struct {
+	addr      string
+	transport *req.Transport
+}
+
+func NewClient(integrServAddr string, transport *req.Transport) *Client {
+	return &Client{
+		addr:      fmt.Sprintf("%s/soap/IOrionPro", integrServAddr),
+		transport: transport,
+	}
+}
+
+func (c *Client) call(ctx context.Context, method string, data any, respBody any) error {
+	err := c.transport.PreparedReqToXMLIntegerServ(ctx, method, c.addr, data, respBody)
+	if err != nil {
+		return classify(err)
+	}
+
+	return nil
+}
+
+type operationResultCount struct {
+	OperationResult int64
+}
@@ -0,0 +1,25 @@
+package orion
+
+import (
+	"context"
+	"encoding/xml"
+
+	"github.com/Izumra/SKUD_OKEI/domain/dto/integrserv"
+)
+
+type getDepartmentsReq struct {
+	XMLName xml.Name `xml:"GetDepartments"`
+}
+
+func (c *Client) GetDepartments(ctx context.Context) ([]*integrserv.Department, error) {
+	var departments []*integrserv.Department
+	respBody := &integrserv.OperationResultDepartments{
+		Result: &departments,
+	}
+	err := c.call(ctx, "GetDepartments", getDepartmentsReq{}, respBody)
+	if err != nil {
+		return nil, err
+	}
+
+	return departments, nil
+}
@@ -0,0 +1,94 @@
+package orion
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/Izumra/SKUD_OKEI/internal/lib/req"
+)
+
+var (
+	ErrPersonNotFound    = errors.New("субъект доступа не найден в 'Орион Про'")
+	ErrKeyNotFound       = errors.New("ключ не найден в 'Орион Про'")
+	ErrDuplicateCard     = errors.New("ключ с таким кодом уже зарегистрирован в 'Орион Про'")
+	ErrInvalidParameters = errors.New("неверные параметры запроса к 'Орион Про'")
+	ErrOrionInternal     = errors.New("внутренняя ошибка 'Орион Про'")
+)
+
+var (
+	keyMethods    = []string{"GetKeyData", "UpdateKeyData"}
+	personMethods = []string{"GetPersonById", "UpdatePerson", "DeletePerson"}
+)
+
+// knownErrors maps the fragments of the Orion error messages to the typed
+// errors, the first rule whose fragments are all present wins. The rule with
+// methods applies only to the errors of these methods, so the generic "not
+// found" is classified by the entity the method works with.
+var knownErrors = []struct {
+	kind      error
+	methods   []string
+	fragments []string
+}{
+	{ErrDuplicateCard, nil, []string{"ключ", "уже"}},
+	{ErrDuplicateCard, nil, []string{"key", "already"}},
+	{ErrDuplicateCard, nil, []string{"duplicate"}},
+	{ErrKeyNotFound, nil, []string{"ключ", "не найден"}},
+	{ErrKeyNotFound, nil, []string{"key", "not found"}},
+	{ErrPersonNotFound, nil, []string{"сотрудник", "не найден"}},
+	{ErrPersonNotFound, nil, []string{"субъект", "не найден"}},
+	{ErrPersonNotFound, nil, []string{"person", "not found"}},
+	{ErrKeyNotFound, keyMethods, []string{"не найден"}},
+	{ErrKeyNotFound, keyMethods, []string{"not found"}},
+	{ErrPersonNotFound, personMethods, []string{"не найден"}},
+	{ErrPersonNotFound, personMethods, []string{"not found"}},
+	{ErrInvalidParameters, nil, []string{"неверн"}},
+	{ErrInvalidParameters, nil, []string{"invalid"}},
+}
+
+// Error is the Orion service error classified by its message.
+type Error struct {
+	Kind  error
+	Cause *req.ServiceError
+}
+
+func (e *Error) Error() string {
+	return e.Cause.Error()
+}
+
+func (e *Error) Unwrap() []error {
+	return []error{e.Kind, e.Cause}
+}
+
+func classify(err error) error {
+	var serviceErr *req.ServiceError
+	if !errors.As(err, &serviceErr) {
+		return err
+	}
+
+	message := strings.ToLower(serviceErr.Description + " " + serviceErr.InnerExceptionMessage)
+	for _, known := range knownErrors {
+		if known.methods != nil && !slices.Contains(known.methods, serviceErr.Method) {
+			continue
+		}
+
+		matched := true
+		for _, fragment := range known.fragments {
+			if !strings.Contains(message, fragment) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return &Error{
+				Kind:  known.kind,
+				Cause: serviceErr,
+			}
+		}
+	}
+
+	return &Error{
+		Kind:  ErrOrionInternal,
+		Cause: serviceErr,
+	}
+}
@@ -0,0 +1,224 @@
+package orion_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Izumra/SKUD_OKEI/domain/dto/integrserv"
+	"github.com/Izumra/SKUD_OKEI/internal/lib/req"
+	"github.com/Izumra/SKUD_OKEI/internal/orion"
+	"github.com/Izumra/SKUD_OKEI/internal/orion/fake"
+)
+
+func newClient(t *testing.T, handler http.Handler) *orion.Client {
+	t.Helper()
+
+	httpServer := httptest.NewServer(handler)
+	t.Cleanup(httpServer.Close)
+
+	return orion.NewClient(httpServer.URL, req.NewTransport(req.TransportConfig{
+		Timeout:      time.Second,
+		RetryBackoff: time.Millisecond,
+	}))
+}
+
+func TestServiceErrors(t *testing.T) {
+	dataset := fake.Generate(fake.DatasetConfig{
+		Seed:    1,
+		Persons: 5,
+		Days:    1,
+	})
+	server := fake.NewServer(dataset, 1)
+	client := newClient(t, server)
+	ctx := context.Background()
+
+	calls := map[string]func() error{
+		"GetPersonById": func() error {
+			_, err := client.GetPersonById(ctx, 1)
+			return err
+		},
+		"GetKeyData": func() error {
+			_, err := client.GetKeyData(ctx, dataset.Keys[0].Code)
+			return err
+		},
+		"AddKey": func() error {
+			_, err := client.AddKey(ctx, &integrserv.KeyData{Code: "00000000000000AA", PersonId: 1})
+			return err
+		},
+		"GetPersons": func() error {
+			_, err := client.GetPersons(ctx, 0, 5, nil)
+			return err
+		},
+	}
+
+	tests := []struct {
+		name    string
+		method  string
+		err     integrserv.Error
+		wantErr error
+	}{
+		{
+			name:    "duplicate key",
+			method:  "AddKey",
+			err:     integrserv.Error{Description: fake.MsgDuplicateCard},
+			wantErr: orion.ErrDuplicateCard,
+		},
+		{
+			name:    "duplicate key in english",
+			method:  "AddKey",
+			err:     integrserv.Error{Description: "Key already exists"},
+			wantErr: orion.ErrDuplicateCard,
+		},
+		{
+			name:    "duplicate entry",
+			method:  "AddKey",
+			err:     integrserv.Error{Description: "Ошибка", InnerExceptionMessage: "Duplicate entry for the index"},
+			wantErr: orion.ErrDuplicateCard,
+		},
+		{
+			name:    "key not found",
+			method:  "GetPersons",
+			err:     integrserv.Error{Description: fake.MsgKeyNotFound},
+			wantErr: orion.ErrKeyNotFound,
+		},
+		{
+			name:    "key not found in english",
+			method:  "GetPersons",
+			err:     integrserv.Error{Description: "Key not found"},
+			wantErr: orion.ErrKeyNotFound,
+		},
+		{
+			name:    "person not found",
+			method:  "GetKeyData",
+			err:     integrserv.Error{Description: fake.MsgPersonNotFound},
+			wantErr: orion.ErrPersonNotFound,
+		},
+		{
+			name:    "subject not found",
+			method:  "GetKeyData",
+			err:     integrserv.Error{Description: "Субъект доступа не найден"},
+			wantErr: orion.ErrPersonNotFound,
+		},
+		{
+			name:    "person not found in english",
+			method:  "AddKey",
+			err:     integrserv.Error{Description: "Ошибка", InnerExceptionMessage: "Person not found"},
+			wantErr: orion.ErrPersonNotFound,
+		},
+		{
+			name:    "generic not found of the key method",
+			method:  "GetKeyData",
+			err:     integrserv.Error{Description: "Объект не найден"},
+			wantErr: orion.ErrKeyNotFound,
+		},
+		{
+			name:    "generic not found of the key method in english",
+			method:  "GetKeyData",
+			err:     integrserv.Error{Description: "Object not found"},
+			wantErr: orion.ErrKeyNotFound,
+		},
+		{
+			name:    "generic not found of the person method",
+			method:  "GetPersonById",
+			err:     integrserv.Error{Description: "Объект не найден"},
+			wantErr: orion.ErrPersonNotFound,
+		},
+		{
+			name:    "generic not found of the person method in english",
+			method:  "GetPersonById",
+			err:     integrserv.Error{Description: "Object not found"},
+			wantErr: orion.ErrPersonNotFound,
+		},
+		{
+			name:    "generic not found of the other method",
+			method:  "GetPersons",
+			err:     integrserv.Error{Description: "Объект не найден"},
+			wantErr: orion.ErrOrionInternal,
+		},
+		{
+			name:    "wrong parameters",
+			method:  "GetPersons",
+			err:     integrserv.Error{Description: fake.MsgWrongParameters},
+			wantErr: orion.ErrInvalidParameters,
+		},
+		{
+			name:    "invalid parameters in english",
+			method:  "GetPersons",
+			err:     integrserv.Error{Description: "Invalid parameter value"},
+			wantErr: orion.ErrInvalidParameters,
+		},
+		{
+			name:    "unknown message",
+			method:  "GetPersons",
+			err:     integrserv.Error{Description: "Ошибка базы данных"},
+			wantErr: orion.ErrOrionInternal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server.FailNext(tt.method, tt.err)
+			err := calls[tt.method]()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+
+			// the classified error keeps the message of Orion
+			var serviceErr *req.ServiceError
+			if !errors.As(err, &serviceErr) || serviceErr.Method != tt.method || serviceErr.Description != tt.err.Description {
+				t.Errorf("err = %#v, want the service error of %s", err, tt.method)
+			}
+		})
+	}
+}
+
+func TestTransportErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		wantErr error
+	}{
+		{
+			name: "fault",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+				io.WriteString(w, `<?xml version="1.0"?><SOAP-ENV:Envelope xmlns:SOAP-ENV="http://schemas.xmlsoap.org/soap/envelope/"><SOAP-ENV:Body>`+
+					`<SOAP-ENV:Fault><faultcode>SOAP-ENV:Server</faultcode><faultstring>Access violation</faultstring></SOAP-ENV:Fault>`+
+					`</SOAP-ENV:Body></SOAP-ENV:Envelope>`)
+			},
+		},
+		{
+			name: "malformed body",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				io.WriteString(w, "<html><body>Proxy error")
+			},
+			wantErr: req.ErrMalformedResponse,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newClient(t, tt.handler)
+
+			// the errors that are not answered by the service are not classified
+			_, err := client.GetPersonById(context.Background(), 1)
+			var orionErr *orion.Error
+			if err == nil || errors.As(err, &orionErr) {
+				t.Fatalf("err = %#v, want the unclassified error", err)
+			}
+
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+			var fault *req.SOAPFault
+			if tt.wantErr == nil && (!errors.As(err, &fault) || fault.HTTPStatus != http.StatusInternalServerError) {
+				t.Errorf("err = %#v, want the fault with the status %d", err, http.StatusInternalServerError)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package orion
+
+import (
+	"context"
+	"encoding/xml"
+
+	"github.com/Izumra/SKUD_OKEI/domain/dto/integrserv"
+)
+
+func (c *Client) GetEvents(ctx context.Context, filter *integrserv.EventFilter) ([]integrserv.Event, error) {
+	reqData := *filter
+	reqData.XMLName = xml.Name{
+		Local: "GetEvents",
+	}
+
+	var events []integrserv.Event
+	respBody := &integrserv.OperationResultEvents{
+		Result: &events,
+	}
+	err := c.call(ctx, "GetEvents", reqData, respBody)
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func (c *Client) GetEventsCount(ctx context.Context, filter *integrserv.EventCountFilter) (int64, error) {
+	reqData := *filter
+	reqData.XMLName = xml.Name{
+		Local: "GetEventsCount",
+	}
+
+	var count operationResultCount
+	respBody := &integrserv.OperationResultInt{
+		Result: &count,
+	}
+	err := c.call(ctx, "GetEventsCount", reqData, respBody)
+	if err != nil {
+		return -1, err
+	}
+
+	return count.OperationResult, nil
+}
+
+// StreamEvents passes the events to fn one by one while the response is
+// being read, the iteration stops on the first error returned by fn.
+func (c *Client) StreamEvents(ctx context.Context, filter *integrserv.EventFilter, fn func(event integrserv.Event) error) error {
+	reqData := *filter
+	reqData.XMLName = xml.Name{
+		Local: "GetEvents",
+	}
+
+	err := c.transport.PreparedStreamReqToXMLIntegerServ(ctx, "GetEvents", c.addr, reqData, "TEvent", func(dec *xml.Decoder, start *xml.StartElement) error {
+		var event integrserv.Event
+		if err := dec.DecodeElement(&event, start); err != nil {
+			return err
+		}
+		return fn(event)
+	})
+	if err != nil {
+		return classify(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,152 @@
+package fake
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"time"
+
+	"github.com/Izumra/SKUD_OKEI/domain/dto/integrserv"
+)
+
+var (
+	lastNames   = []string{"Иванов", "Смирнов", "Кузнецов", "Попов", "Васильев", "Петров", "Соколов", "Михайлов", "Новиков", "Федоров", "Морозов", "Волков"}
+	firstNames  = []string{"Александр", "Дмитрий", "Максим", "Сергей", "Андрей", "Алексей", "Артем", "Илья", "Кирилл", "Михаил"}
+	middleNames = []string{"Александрович", "Дмитриевич", "Сергеевич", "Андреевич", "Алексеевич", "Игоревич", "Олегович"}
+	groups      = []string{"ИС-21", "ИС-22", "ПР-21", "ПР-22", "БУ-21", "ЭК-22", "Сотрудники"}
+)
+
+// EventTypeAccessGranted is the type of the generated passes.
+const EventTypeAccessGranted = 28
+
+type Dataset struct {
+	Departments  []*integrserv.Department
+	Persons      []*integrserv.PersonData
+	Keys         []*integrserv.KeyData
+	Events       []integrserv.Event
+	AccessLevels []*integrserv.AccessLevel
+	AccessPoints []*integrserv.AccessPoint
+}
+
+type DatasetConfig struct {
+	Seed    int64
+	Persons int
+	Days    int
+	Now     time.Time
+}
+
+func Generate(cfg DatasetConfig) *Dataset {
+	rnd := rand.New(rand.NewSource(cfg.Seed))
+	if cfg.Now.IsZero() {
+		cfg.Now = time.Now()
+	}
+
+	ds := &Dataset{
+		AccessLevels: []*integrserv.AccessLevel{
+			{Id: 1, Name: "Полный доступ"},
+			{Id: 2, Name: "Сотрудники"},
+			{Id: 3, Name: "Студенты"},
+		},
+		AccessPoints: []*integrserv.AccessPoint{
+			{Id: 1, Name: "Турникет 1", EnterAccessZoneId: 1, ExitAccessZoneId: 0},
+			{Id: 2, Name: "Турникет 2", EnterAccessZoneId: 1, ExitAccessZoneId: 0},
+		},
+	}
+
+	for i, name := range groups {
+		ds.Departments = append(ds.Departments, &integrserv.Department{
+			Id:   int64(i + 1),
+			Name: name,
+		})
+	}
+
+	for i := 0; i < cfg.Persons; i++ {
+		person := &integrserv.PersonData{
+			Id:           int64(i + 1),
+			DepartmentId: ds.Departments[rnd.Intn(len(ds.Departments))].Id,
+			FirstName:    firstNames[rnd.Intn(len(firstNames))],
+			LastName:     lastNames[rnd.Intn(len(lastNames))],
+			MiddleName:   middleNames[rnd.Intn(len(middleNames))],
+			TabNum:       fmt.Sprintf("%05d", i+1),
+			Status:       5,
+		}
+		ds.Persons = append(ds.Persons, person)
+
+		startDate := cfg.Now.AddDate(-1, 0, 0)
+		accessLevel := 3
+		if person.DepartmentId == int64(len(groups)) {
+			accessLevel = 2
+		}
+		ds.Keys = append(ds.Keys, &integrserv.KeyData{
+			Id:              int64(i + 1),
+			CodeType:        integrserv.KeyCodeProxy,
+			Code:            fmt.Sprintf("%016X", rnd.Uint64()),
+			PersonId:        person.Id,
+			AccessLevelId:   accessLevel,
+			StartDate:       startDate,
+			EndDate:         startDate.AddDate(4, 0, 0),
+			IsStoreInDevice: true,
+		})
+	}
+
+	days := cfg.Days
+	if days < 1 {
+		days = 1
+	}
+	for d := days - 1; d >= 0; d-- {
+		day := cfg.Now.AddDate(0, 0, -d)
+		ds.Events = append(ds.Events, generateDay(rnd, ds, day, cfg.Now)...)
+	}
+	sortEvents(ds.Events)
+	for i := range ds.Events {
+		ds.Events[i].EventId = fmt.Sprintf("%d", i+1)
+	}
+
+	return ds
+}
+
+func generateDay(rnd *rand.Rand, ds *Dataset, day time.Time, now time.Time) []integrserv.Event {
+	var events []integrserv.Event
+
+	for i, person := range ds.Persons {
+		if rnd.Intn(10) == 0 {
+			continue
+		}
+
+		key := ds.Keys[i]
+		entry := time.Date(day.Year(), day.Month(), day.Day(), 7, 30, 0, 0, day.Location()).
+			Add(time.Duration(rnd.Intn(120)) * time.Minute)
+		exit := entry.Add(time.Duration(4*60+rnd.Intn(5*60)) * time.Minute)
+
+		if !entry.After(now) {
+			events = append(events, passEvent(rnd, person, key, entry, 1))
+		}
+		if rnd.Intn(20) != 0 && !exit.After(now) {
+			events = append(events, passEvent(rnd, person, key, exit, 2))
+		}
+	}
+
+	return events
+}
+
+func passEvent(rnd *rand.Rand, person *integrserv.PersonData, key *integrserv.KeyData, date time.Time, passMode int) integrserv.Event {
+	return integrserv.Event{
+		EventDate:     date,
+		PassMode:      passMode,
+		LastName:      person.LastName,
+		FirstName:     person.FirstName,
+		MiddleName:    person.MiddleName,
+		TabNum:        person.TabNum,
+		PersonId:      person.Id,
+		CardNo:        key.Code,
+		Description:   fmt.Sprintf("Доступ предоставлен,  %s Считыватель", key.Code),
+		AccessPointId: 1 + rnd.Intn(2),
+		EventTypeId:   EventTypeAccessGranted,
+	}
+}
+
+func sortEvents(events []integrserv.Event) {
+	slices.SortStableFunc(events, func(a, b integrserv.Event) int {
+		return a.EventDate.Compare(b.EventDate)
+	})
+}
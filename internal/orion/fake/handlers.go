@@ -0,0 +1,435 @@
+package fake
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Izumra/SKUD_OKEI/domain/dto/integrserv"
+)
+
+type pageReq struct {
+	Offset int64
+	Count  int64
+	Filter []struct {
+		Value string
+	}
+}
+
+type personReq struct {
+	Id         int64
+	PersonData integrserv.PersonData
+}
+
+type keyReq struct {
+	CardNo  string
+	KeyData integrserv.KeyData
+}
+
+type convertReq struct {
+	Code     uint64
+	CodeSize int
+	Pin      string
+}
+
+type itemsReq struct {
+	ItemType string
+}
+
+func page[T any](items []T, offset int64, count int64) []T {
+	if offset < 0 || offset >= int64(len(items)) {
+		return nil
+	}
+	items = items[offset:]
+	if count > 0 && count < int64(len(items)) {
+		items = items[:count]
+	}
+
+	return items
+}
+
+func encodeAll[T any](enc *xml.Encoder, name string, items []T) error {
+	for _, item := range items {
+		if err := enc.EncodeElement(item, ns2(name)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func encodeCount(enc *xml.Encoder, count int) error {
+	return enc.EncodeElement(struct {
+		OperationResult int
+	}{count}, ns2("TOperationResultInt"))
+}
+
+func encodeString(enc *xml.Encoder, value string) error {
+	return enc.EncodeElement(integrserv.Result{
+		OperationResult: value,
+	}, ns2("TOperationResultString"))
+}
+
+func (s *Server) getPersons(data []byte, enc *xml.Encoder) error {
+	var req pageReq
+	if err := xml.Unmarshal(data, &req); err != nil {
+		return err
+	}
+
+	var persons []*integrserv.PersonData
+	for _, person := range s.ds.Persons {
+		matched := true
+		for _, filter := range req.Filter {
+			if !matchPerson(person, filter.Value) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			persons = append(persons, person)
+		}
+	}
+
+	return encodeAll(enc, "TPersonData", page(persons, req.Offset, req.Count))
+}
+
+func matchPerson(person *integrserv.PersonData, filter string) bool {
+	key, value, found := strings.Cut(filter, "=")
+	if !found {
+		value = key
+		key = ""
+	}
+	value = strings.ToLower(strings.TrimSpace(value))
+
+	switch strings.TrimSpace(key) {
+	case "LastName":
+		return strings.HasPrefix(strings.ToLower(person.LastName), value)
+	case "FirstName":
+		return strings.HasPrefix(strings.ToLower(person.FirstName), value)
+	case "MiddleName":
+		return strings.HasPrefix(strings.ToLower(person.MiddleName), value)
+	case "TabNum":
+		return person.TabNum == value
+	case "DepartmentId":
+		return strconv.FormatInt(person.DepartmentId, 10) == value
+	default:
+		fullName := strings.ToLower(strings.Join([]string{person.LastName, person.FirstName, person.MiddleName}, " "))
+		return strings.Contains(fullName, value)
+	}
+}
+
+func (s *Server) getPersonsCount(data []byte, enc *xml.Encoder) error {
+	return encodeCount(enc, len(s.ds.Persons))
+}
+
+func (s *Server) findPerson(id int64) int {
+	for i, person := range s.ds.Persons {
+		if person.Id == id {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (s *Server) getPersonById(data []byte, enc *xml.Encoder) error {
+	var req personReq
+	if err := xml.Unmarshal(data, &req); err != nil {
+		return err
+	}
+
+	i := s.findPerson(req.Id)
+	if i == -1 {
+		return newServiceError(MsgPersonNotFound)
+	}
+
+	return enc.EncodeElement(s.ds.Persons[i], plain("OperationResult"))
+}
+
+func (s *Server) addPerson(data []byte, enc *xml.Encoder) error {
+	var req personReq
+	if err := xml.Unmarshal(data, &req); err != nil {
+		return err
+	}
+
+	person := req.PersonData
+	person.Id = 1
+	for _, p := range s.ds.Persons {
+		if p.Id >= person.Id {
+			person.Id = p.Id + 1
+		}
+	}
+	s.ds.Persons = append(s.ds.Persons, &person)
+
+	return enc.EncodeElement(person, plain("OperationResult"))
+}
+
+func (s *Server) updatePerson(data []byte, enc *xml.Encoder) error {
+	var req personReq
+	if err := xml.Unmarshal(data, &req); err != nil {
+		return err
+	}
+
+	i := s.findPerson(req.PersonData.Id)
+	if i == -1 {
+		return newServiceError(MsgPersonNotFound)
+	}
+	person := req.PersonData
+	s.ds.Persons[i] = &person
+
+	return enc.EncodeElement(person, plain("OperationResult"))
+}
+
+func (s *Server) deletePerson(data []byte, enc *xml.Encoder) error {
+	var req personReq
+	if err := xml.Unmarshal(data, &req); err != nil {
+		return err
+	}
+
+	i := s.findPerson(req.PersonData.Id)
+	if i == -1 {
+		return newServiceError(MsgPersonNotFound)
+	}
+	person := s.ds.Persons[i]
+	s.ds.Persons = append(s.ds.Persons[:i], s.ds.Persons[i+1:]...)
+
+	return enc.EncodeElement(person, plain("OperationResult"))
+}
+
+func (s *Server) getDepartments(data []byte, enc *xml.Encoder) error {
+	return encodeAll(enc, "TDepartment", s.ds.Departments)
+}
+
+func (s *Server) findKey(code string) int {
+	for i, key := range s.ds.Keys {
+		if strings.EqualFold(key.Code, code) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (s *Server) personKey(personId int64) *integrserv.KeyData {
+	for _, key := range s.ds.Keys {
+		if key.PersonId == personId {
+			return key
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) getKeys(data []byte, enc *xml.Encoder) error {
+	var req pageReq
+	if err := xml.Unmarshal(data, &req); err != nil {
+		return err
+	}
+
+	return encodeAll(enc, "TKeyData", page(s.ds.Keys, req.Offset, req.Count))
+}
+
+func (s *Server) getKeyData(data []byte, enc *xml.Encoder) error {
+	var req keyReq
+	if err := xml.Unmarshal(data, &req); err != nil {
+		return err
+	}
+
+	i := s.findKey(req.CardNo)
+	if i == -1 {
+		return newServiceError(MsgKeyNotFound)
+	}
+
+	return enc.EncodeElement(s.ds.Keys[i], plain("OperationResult"))
+}
+
+func (s *Server) addKey(data []byte, enc *xml.Encoder) error {
+	var req keyReq
+	if err := xml.Unmarshal(data, &req); err != nil {
+		return err
+	}
+
+	if s.findKey(req.KeyData.Code) != -1 {
+		return newServiceError(MsgDuplicateCard)
+	}
+	if s.findPerson(req.KeyData.PersonId) == -1 {
+		return newServiceError(MsgPersonNotFound)
+	}
+
+	key := req.KeyData
+	s.lastKeyId++
+	key.Id = s.lastKeyId
+	s.ds.Keys = append(s.ds.Keys, &key)
+
+	return enc.EncodeElement(key, plain("OperationResult"))
+}
+
+func (s *Server) updateKeyData(data []byte, enc *xml.Encoder) error {
+	var req keyReq
+	if err := xml.Unmarshal(data, &req); err != nil {
+		return err
+	}
+
+	i := s.findKey(req.KeyData.Code)
+	if i == -1 {
+		return newServiceError(MsgKeyNotFound)
+	}
+	key := req.KeyData
+	key.Id = s.ds.Keys[i].Id
+	s.ds.Keys[i] = &key
+
+	return enc.EncodeElement(key, plain("OperationResult"))
+}
+
+func (s *Server) convertWiegand(data []byte, enc *xml.Encoder) error {
+	var req convertReq
+	if err := xml.Unmarshal(data, &req); err != nil {
+		return err
+	}
+	if req.CodeSize != 0 && req.CodeSize != 26 && req.CodeSize != 34 {
+		return newServiceError(MsgWrongParameters)
+	}
+
+	return encodeString(enc, touchMemoryCode(req.Code))
+}
+
+func (s *Server) convertPin(data []byte, enc *xml.Encoder) error {
+	var req convertReq
+	if err := xml.Unmarshal(data, &req); err != nil {
+		return err
+	}
+
+	pin, err := strconv.ParseUint(req.Pin, 10, 48)
+	if err != nil {
+		return newServiceError(MsgWrongParameters)
+	}
+
+	return encodeString(enc, touchMemoryCode(pin))
+}
+
+// touchMemoryCode formats the code like Orion shows Dallas keys: CRC, six
+// bytes of the serial number and the family code.
+func touchMemoryCode(serial uint64) string {
+	bytes := []byte{0x01}
+	for i := 0; i < 6; i++ {
+		bytes = append(bytes, byte(serial>>(8*i)))
+	}
+
+	var crc byte
+	for _, b := range bytes {
+		for i := 0; i < 8; i++ {
+			mix := (crc ^ b) & 0x01
+			crc >>= 1
+			if mix != 0 {
+				crc ^= 0x8C
+			}
+			b >>= 1
+		}
+	}
+
+	return fmt.Sprintf("%02X%012X%02X", crc, serial&0xFFFFFFFFFFFF, 0x01)
+}
+
+func (s *Server) filterEvents(filter integrserv.EventCountFilter) []integrserv.Event {
+	persons := map[int64]bool{}
+	for _, person := range filter.Persons.PersonData {
+		persons[person.Id] = true
+	}
+	points := map[int64]bool{}
+	for _, point := range filter.EntryPoints.EntryPoint {
+		points[point.Id] = true
+	}
+	eventTypes := map[int64]bool{}
+	for _, eventType := range filter.EventTypes.EventType {
+		eventTypes[eventType.Id] = true
+	}
+
+	var events []integrserv.Event
+	for _, event := range s.ds.Events {
+		if event.EventDate.Before(filter.BeginTime) || !event.EventDate.Before(filter.EndTime) {
+			continue
+		}
+		if len(persons) != 0 && !persons[event.PersonId] {
+			continue
+		}
+		if len(points) != 0 && !points[int64(event.AccessPointId)] {
+			continue
+		}
+		if len(eventTypes) != 0 && !eventTypes[event.EventTypeId] {
+			continue
+		}
+		events = append(events, event)
+	}
+
+	return events
+}
+
+func (s *Server) getEvents(data []byte, enc *xml.Encoder) error {
+	var filter integrserv.EventFilter
+	if err := xml.Unmarshal(data, &filter); err != nil {
+		return err
+	}
+
+	events := s.filterEvents(integrserv.EventCountFilter{
+		BeginTime:   filter.BeginTime,
+		EndTime:     filter.EndTime,
+		EventTypes:  filter.EventTypes,
+		Persons:     filter.Persons,
+		EntryPoints: filter.EntryPoints,
+	})
+
+	return encodeAll(enc, "TEvent", page(events, filter.Offset, filter.Count))
+}
+
+func (s *Server) getEventsCount(data []byte, enc *xml.Encoder) error {
+	var filter integrserv.EventCountFilter
+	if err := xml.Unmarshal(data, &filter); err != nil {
+		return err
+	}
+
+	return encodeCount(enc, len(s.filterEvents(filter)))
+}
+
+func (s *Server) getAccessLevels(data []byte, enc *xml.Encoder) error {
+	return encodeAll(enc, "TAccessLevel", s.ds.AccessLevels)
+}
+
+func (s *Server) getAccessPoints(data []byte, enc *xml.Encoder) error {
+	return encodeAll(enc, "TAccessPoint", s.ds.AccessPoints)
+}
+
+func (s *Server) getItems(data []byte, enc *xml.Encoder) error {
+	var req itemsReq
+	if err := xml.Unmarshal(data, &req); err != nil {
+		return err
+	}
+
+	var items []*integrserv.Item
+	for _, point := range s.ds.AccessPoints {
+		items = append(items, &integrserv.Item{
+			Id:       point.Id,
+			ItemType: integrserv.ItemTypeAccessPoint,
+			Name:     point.Name,
+		})
+		for passMode := 1; passMode <= 2; passMode++ {
+			items = append(items, &integrserv.Item{
+				Id:       point.Id*10 + int64(passMode),
+				ItemType: integrserv.ItemTypeReader,
+				Name:     fmt.Sprintf("%s, считыватель %d", point.Name, passMode),
+			})
+		}
+	}
+
+	if req.ItemType != "" {
+		filtered := items[:0]
+		for _, item := range items {
+			if item.ItemType == req.ItemType {
+				filtered = append(filtered, item)
+			}
+		}
+		items = filtered
+	}
+
+	return encodeAll(enc, "TItem", items)
+}
@@ -0,0 +1,295 @@
+package fake
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Izumra/SKUD_OKEI/domain/dto/integrserv"
+)
+
+const (
+	MsgPersonNotFound  = "Сотрудник с указанным идентификатором не найден"
+	MsgKeyNotFound     = "Ключ с указанным кодом не найден"
+	MsgDuplicateCard   = "Ключ с таким кодом уже зарегистрирован в системе"
+	MsgWrongParameters = "Неверные параметры запроса"
+)
+
+type handler func(s *Server, data []byte, enc *xml.Encoder) error
+
+var handlers = map[string]handler{
+	"GetPersons":                  (*Server).getPersons,
+	"GetPersonsCount":             (*Server).getPersonsCount,
+	"GetPersonById":               (*Server).getPersonById,
+	"AddPerson":                   (*Server).addPerson,
+	"UpdatePerson":                (*Server).updatePerson,
+	"DeletePerson":                (*Server).deletePerson,
+	"GetDepartments":              (*Server).getDepartments,
+	"GetKeys":                     (*Server).getKeys,
+	"GetKeyData":                  (*Server).getKeyData,
+	"AddKey":                      (*Server).addKey,
+	"UpdateKeyData":               (*Server).updateKeyData,
+	"ConvertWiegandToTouchMemory": (*Server).convertWiegand,
+	"ConvertPinToTouchMemory":     (*Server).convertPin,
+	"GetEvents":                   (*Server).getEvents,
+	"GetEventsCount":              (*Server).getEventsCount,
+	"GetAccessLevels":             (*Server).getAccessLevels,
+	"GetAccessPoints":             (*Server).getAccessPoints,
+	"GetItems":                    (*Server).getItems,
+}
+
+type Server struct {
+	mu          sync.Mutex
+	ds          *Dataset
+	rnd         *rand.Rand
+	down        bool
+	failures    map[string]integrserv.Error
+	lastEventId int64
+	lastKeyId   int64
+}
+
+func NewServer(ds *Dataset, seed int64) *Server {
+	s := &Server{
+		ds:       ds,
+		rnd:      rand.New(rand.NewSource(seed)),
+		failures: make(map[string]integrserv.Error),
+	}
+	for _, e := range ds.Events {
+		id, err := strconv.ParseInt(e.EventId, 10, 64)
+		if err == nil && id > s.lastEventId {
+			s.lastEventId = id
+		}
+	}
+	for _, key := range ds.Keys {
+		if key.Id > s.lastKeyId {
+			s.lastKeyId = key.Id
+		}
+	}
+
+	return s
+}
+
+// SetDown makes the server drop every incoming connection, the way the real
+// IntegrServ behaves when the Windows service hangs.
+func (s *Server) SetDown(down bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.down = down
+}
+
+// FailNext makes the next call of the method return the passed Orion error.
+func (s *Server) FailNext(method string, err integrserv.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures[method] = err
+}
+
+func (s *Server) PushEvent(event integrserv.Event) integrserv.Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.pushEvent(event)
+}
+
+func (s *Server) pushEvent(event integrserv.Event) integrserv.Event {
+	s.lastEventId++
+	event.EventId = strconv.FormatInt(s.lastEventId, 10)
+	if event.EventDate.IsZero() {
+		event.EventDate = time.Now()
+	}
+	s.ds.Events = append(s.ds.Events, event)
+
+	return event
+}
+
+// Simulate generates the pass of a random person every interval until the
+// context is done, so the live monitor has something to show.
+func (s *Server) Simulate(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.mu.Lock()
+			// the persons added without the key do not pass
+			if len(s.ds.Persons) != 0 {
+				person := s.ds.Persons[s.rnd.Intn(len(s.ds.Persons))]
+				if key := s.personKey(person.Id); key != nil {
+					passMode := 1
+					for j := len(s.ds.Events) - 1; j >= 0; j-- {
+						if s.ds.Events[j].PersonId == person.Id {
+							passMode = 3 - s.ds.Events[j].PassMode
+							break
+						}
+					}
+					s.pushEvent(passEvent(s.rnd, person, key, now, passMode))
+				}
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	down := s.down
+	s.mu.Unlock()
+	if down {
+		if hj, ok := w.(http.Hijacker); ok {
+			conn, _, err := hj.Hijack()
+			if err == nil {
+				conn.Close()
+				return
+			}
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeFault(w, http.StatusMethodNotAllowed, "SOAP-ENV:Client", "Метод запроса должен быть POST")
+		return
+	}
+
+	action := strings.Trim(r.Header.Get("SOAPAction"), `"`)
+	_, method, found := strings.Cut(action, "#")
+	if !found {
+		writeFault(w, http.StatusBadRequest, "SOAP-ENV:Client", "Не указан заголовок SOAPAction")
+		return
+	}
+
+	handle, ok := handlers[method]
+	if !ok {
+		writeFault(w, http.StatusInternalServerError, "SOAP-ENV:Server", fmt.Sprintf("Метод %s не поддерживается", method))
+		return
+	}
+
+	var envelope struct {
+		Body struct {
+			Content []byte `xml:",innerxml"`
+		} `xml:"Body"`
+	}
+	if err := xml.NewDecoder(r.Body).Decode(&envelope); err != nil {
+		writeFault(w, http.StatusBadRequest, "SOAP-ENV:Client", "Ошибка разбора конверта: "+err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if failure, ok := s.failures[method]; ok {
+		delete(s.failures, method)
+		writeResponse(w, method, func(enc *xml.Encoder) error {
+			return enc.EncodeElement(failure, ns2("ServiceError"))
+		})
+		return
+	}
+
+	writeResponse(w, method, func(enc *xml.Encoder) error {
+		return handle(s, envelope.Body.Content, enc)
+	})
+}
+
+type serviceError struct {
+	description string
+}
+
+func (e *serviceError) Error() string {
+	return e.description
+}
+
+func newServiceError(description string) error {
+	return &serviceError{description}
+}
+
+func ns2(local string) xml.StartElement {
+	return xml.StartElement{Name: xml.Name{Local: "NS2:" + local}}
+}
+
+func plain(local string) xml.StartElement {
+	return xml.StartElement{Name: xml.Name{Local: local}}
+}
+
+func attr(name, value string) xml.Attr {
+	return xml.Attr{Name: xml.Name{Local: name}, Value: value}
+}
+
+func writeResponse(w http.ResponseWriter, method string, content func(enc *xml.Encoder) error) {
+	var buf strings.Builder
+	enc := xml.NewEncoder(&buf)
+
+	envelope := plain("SOAP-ENV:Envelope")
+	envelope.Attr = []xml.Attr{
+		attr("xmlns:SOAP-ENV", "http://schemas.xmlsoap.org/soap/envelope/"),
+		attr("xmlns:xsd", "http://www.w3.org/2001/XMLSchema"),
+		attr("xmlns:xsi", "http://www.w3.org/2001/XMLSchema-instance"),
+		attr("xmlns:SOAP-ENC", "http://schemas.xmlsoap.org/soap/encoding/"),
+	}
+	body := plain("SOAP-ENV:Body")
+	body.Attr = []xml.Attr{
+		attr("SOAP-ENV:encodingStyle", "http://schemas.xmlsoap.org/soap/encoding/"),
+		attr("xmlns:NS1", "urn:OrionProIntf-IOrionPro"),
+		attr("xmlns:NS2", "urn:OrionProIntf"),
+	}
+	methodResp := plain("NS1:" + method + "Response")
+
+	enc.EncodeToken(xml.ProcInst{Target: "xml", Inst: []byte(`version="1.0"`)})
+	enc.EncodeToken(envelope)
+	enc.EncodeToken(body)
+	enc.EncodeToken(methodResp)
+	enc.EncodeToken(methodResp.End())
+
+	err := content(enc)
+	if err != nil {
+		description := err.Error()
+		if _, ok := err.(*serviceError); !ok {
+			description = MsgWrongParameters
+		}
+		enc.EncodeElement(integrserv.Error{
+			Description:           description,
+			InnerExceptionMessage: err.Error(),
+		}, ns2("ServiceError"))
+	}
+
+	enc.EncodeToken(body.End())
+	enc.EncodeToken(envelope.End())
+	enc.Flush()
+
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	w.Write([]byte(buf.String()))
+}
+
+func writeFault(w http.ResponseWriter, status int, code string, description string) {
+	fault := struct {
+		XMLName xml.Name `xml:"SOAP-ENV:Envelope"`
+		XmlsEnv string   `xml:"xmlns:SOAP-ENV,attr"`
+		Body    struct {
+			Fault struct {
+				Code   string `xml:"faultcode"`
+				String string `xml:"faultstring"`
+			} `xml:"SOAP-ENV:Fault"`
+		} `xml:"SOAP-ENV:Body"`
+	}{
+		XmlsEnv: "http://schemas.xmlsoap.org/soap/envelope/",
+	}
+	fault.Body.Fault.Code = code
+	fault.Body.Fault.String = description
+
+	data, _ := xml.Marshal(fault)
+
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write([]byte(xml.Header))
+	w.Write(data)
+}
@@ -0,0 +1,186 @@
+package fake
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Izumra/SKUD_OKEI/domain/dto/integrserv"
+	"github.com/Izumra/SKUD_OKEI/internal/lib/req"
+	"github.com/Izumra/SKUD_OKEI/internal/orion"
+)
+
+// newClient serves the fake with the generated dataset to the client of Orion.
+func newClient(t *testing.T, persons int) (*Server, *Dataset, *orion.Client) {
+	t.Helper()
+
+	dataset := Generate(DatasetConfig{
+		Seed:    1,
+		Persons: persons,
+		Days:    1,
+	})
+	server := NewServer(dataset, 1)
+
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+
+	return server, dataset, orion.NewClient(httpServer.URL, req.NewTransport(req.TransportConfig{
+		Timeout:      time.Second,
+		RetryBackoff: time.Millisecond,
+	}))
+}
+
+func TestGetPersons(t *testing.T) {
+	_, _, client := newClient(t, 10)
+
+	tests := []struct {
+		name    string
+		offset  int64
+		count   int64
+		wantIds []int64
+	}{
+		{name: "first page", offset: 0, count: 3, wantIds: []int64{1, 2, 3}},
+		{name: "middle page", offset: 4, count: 2, wantIds: []int64{5, 6}},
+		{name: "last short page", offset: 8, count: 5, wantIds: []int64{9, 10}},
+		{name: "past the end", offset: 10, count: 5, wantIds: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			persons, err := client.GetPersons(context.Background(), tt.offset, tt.count, nil)
+			if err != nil {
+				t.Fatalf("GetPersons() err = %v", err)
+			}
+
+			var ids []int64
+			for _, person := range persons {
+				ids = append(ids, person.Id)
+			}
+			if len(ids) != len(tt.wantIds) {
+				t.Fatalf("GetPersons() = %v, want %v", ids, tt.wantIds)
+			}
+			for i := range ids {
+				if ids[i] != tt.wantIds[i] {
+					t.Fatalf("GetPersons() = %v, want %v", ids, tt.wantIds)
+				}
+			}
+		})
+	}
+}
+
+func TestErrors(t *testing.T) {
+	server, dataset, client := newClient(t, 5)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		call    func() error
+		wantErr error
+	}{
+		{
+			name: "unknown person",
+			call: func() error {
+				_, err := client.GetPersonById(ctx, 99)
+				return err
+			},
+			wantErr: orion.ErrPersonNotFound,
+		},
+		{
+			name: "unknown key",
+			call: func() error {
+				_, err := client.GetKeyData(ctx, "0000000000000000")
+				return err
+			},
+			wantErr: orion.ErrKeyNotFound,
+		},
+		{
+			name: "duplicate key",
+			call: func() error {
+				_, err := client.AddKey(ctx, &integrserv.KeyData{Code: dataset.Keys[0].Code, PersonId: 1})
+				return err
+			},
+			wantErr: orion.ErrDuplicateCard,
+		},
+		{
+			name: "key of the unknown person",
+			call: func() error {
+				_, err := client.AddKey(ctx, &integrserv.KeyData{Code: "00000000000000AA", PersonId: 99})
+				return err
+			},
+			wantErr: orion.ErrPersonNotFound,
+		},
+		{
+			name: "generic not found of the person method",
+			call: func() error {
+				server.FailNext("GetPersonById", integrserv.Error{Description: "Объект не найден"})
+				_, err := client.GetPersonById(ctx, 1)
+				return err
+			},
+			wantErr: orion.ErrPersonNotFound,
+		},
+		{
+			name: "generic not found of the key method",
+			call: func() error {
+				server.FailNext("GetKeyData", integrserv.Error{Description: "Объект не найден"})
+				_, err := client.GetKeyData(ctx, dataset.Keys[0].Code)
+				return err
+			},
+			wantErr: orion.ErrKeyNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAddKeyId(t *testing.T) {
+	_, dataset, client := newClient(t, 5)
+	ctx := context.Background()
+
+	// the ids of the added keys follow the greatest one, they are not reused
+	// after the keys are replaced
+	dataset.Keys = dataset.Keys[:3]
+	for i, code := range []string{"00000000000000A1", "00000000000000A2"} {
+		key, err := client.AddKey(ctx, &integrserv.KeyData{Code: code, PersonId: 1})
+		if err != nil {
+			t.Fatalf("AddKey() err = %v", err)
+		}
+		if want := int64(6 + i); key.Id != want {
+			t.Errorf("AddKey() id = %d, want %d", key.Id, want)
+		}
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	server, dataset, _ := newClient(t, 5)
+	generated := len(dataset.Events)
+
+	// the keys of the persons are not in the order of the persons
+	dataset.Keys[0], dataset.Keys[4] = dataset.Keys[4], dataset.Keys[0]
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	server.Simulate(ctx, 5*time.Millisecond)
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	simulated := server.ds.Events[generated:]
+	if len(simulated) == 0 {
+		t.Fatal("no passes were simulated")
+	}
+	for _, event := range simulated {
+		key := server.personKey(event.PersonId)
+		if key == nil || event.CardNo != key.Code {
+			t.Errorf("the pass of the person %d has the key %s of the other person", event.PersonId, event.CardNo)
+		}
+	}
+}
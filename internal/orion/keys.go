@@ -0,0 +1,115 @@
+package orion
+
+import (
+	"context"
+	"encoding/xml"
+
+	"github.com/Izumra/SKUD_OKEI/domain/dto/integrserv"
+)
+
+type getKeysReq struct {
+	XMLName xml.Name `xml:"GetKeys"`
+	Offset  int64
+	Count   int64
+}
+
+type getKeyDataReq struct {
+	XMLName xml.Name `xml:"GetKeyData"`
+	CardNo  string
+}
+
+type keyDataReq struct {
+	XMLName xml.Name
+	KeyData *integrserv.KeyData
+}
+
+type convertWiegandReq struct {
+	XMLName  xml.Name `xml:"ConvertWiegandToTouchMemory"`
+	Code     int
+	CodeSize int
+}
+
+type convertPinReq struct {
+	XMLName xml.Name `xml:"ConvertPinToTouchMemory"`
+	Pin     string
+}
+
+func (c *Client) GetKeys(ctx context.Context, offset int64, count int64) ([]*integrserv.KeyData, error) {
+	var keys []*integrserv.KeyData
+	respBody := &integrserv.OperationResultKeys{
+		Result: &keys,
+	}
+	err := c.call(ctx, "GetKeys", getKeysReq{Offset: offset, Count: count}, respBody)
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (c *Client) GetKeyData(ctx context.Context, cardNo string) (*integrserv.KeyData, error) {
+	var key integrserv.KeyData
+	respBody := &integrserv.OperationResult{
+		Result: &key,
+	}
+	err := c.call(ctx, "GetKeyData", getKeyDataReq{CardNo: cardNo}, respBody)
+	if err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+func (c *Client) AddKey(ctx context.Context, keyData *integrserv.KeyData) (*integrserv.KeyData, error) {
+	return c.keyDataCall(ctx, "AddKey", keyData)
+}
+
+func (c *Client) UpdateKeyData(ctx context.Context, keyData *integrserv.KeyData) (*integrserv.KeyData, error) {
+	return c.keyDataCall(ctx, "UpdateKeyData", keyData)
+}
+
+func (c *Client) keyDataCall(ctx context.Context, method string, keyData *integrserv.KeyData) (*integrserv.KeyData, error) {
+	reqData := keyDataReq{
+		XMLName: xml.Name{
+			Local: method,
+		},
+		KeyData: keyData,
+	}
+
+	var key integrserv.KeyData
+	respBody := &integrserv.OperationResult{
+		Result: &key,
+	}
+	err := c.call(ctx, method, reqData, respBody)
+	if err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+func (c *Client) ConvertWiegandToTouchMemory(ctx context.Context, code int, codeSize int) (string, error) {
+	var result integrserv.Result
+	respBody := &integrserv.OperationResultString{
+		Result: &result,
+	}
+	err := c.call(ctx, "ConvertWiegandToTouchMemory", convertWiegandReq{Code: code, CodeSize: codeSize}, respBody)
+	if err != nil {
+		return "", err
+	}
+
+	return result.OperationResult, nil
+}
+
+func (c *Client) ConvertPinToTouchMemory(ctx context.Context, pin string) (string, error) {
+	var result integrserv.Result
+	respBody := &integrserv.OperationResultString{
+		Result: &result,
+	}
+	err := c.call(ctx, "ConvertPinToTouchMemory", convertPinReq{Pin: pin}, respBody)
+	if err != nil {
+		return "", err
+	}
+
+	return result.OperationResult, nil
+}
@@ -0,0 +1,141 @@
+package orion
+
+import (
+	"context"
+	"encoding/xml"
+
+	"github.com/Izumra/SKUD_OKEI/domain/dto/integrserv"
+)
+
+type filterItem struct {
+	XMLName xml.Name `xml:"Filter"`
+	Value   string   `xml:"Value"`
+}
+
+type getPersonsReq struct {
+	XMLName      xml.Name `xml:"GetPersons"`
+	WithoutPhoto bool
+	Offset       int64
+	Count        int64
+	Filter       []filterItem
+}
+
+type getPersonsCountReq struct {
+	XMLName xml.Name `xml:"GetPersonsCount"`
+}
+
+type getPersonByIdReq struct {
+	XMLName xml.Name `xml:"GetPersonById"`
+	Id      int64
+}
+
+type personDataReq struct {
+	XMLName    xml.Name
+	PersonData integrserv.PersonData
+}
+
+func (c *Client) GetPersons(ctx context.Context, offset int64, count int64, filterParams []string) ([]*integrserv.PersonData, error) {
+	filter := make([]filterItem, len(filterParams))
+	for i, v := range filterParams {
+		filter[i] = filterItem{
+			Value: v,
+		}
+	}
+	reqData := getPersonsReq{
+		WithoutPhoto: true,
+		Offset:       offset,
+		Count:        count,
+		Filter:       filter,
+	}
+
+	var persons []*integrserv.PersonData
+	respBody := &integrserv.OperationResultPersons{
+		Result: &persons,
+	}
+	err := c.call(ctx, "GetPersons", reqData, respBody)
+	if err != nil {
+		return nil, err
+	}
+
+	return persons, nil
+}
+
+// PersonDepartments returns the departments of all the persons by their ids,
+// the persons are taken page by page, because the filters of the persons are
+// not supported by every version of Orion.
+func (c *Client) PersonDepartments(ctx context.Context) (map[int64]int64, error) {
+	const pageSize = 100
+
+	result := map[int64]int64{}
+	for offset := int64(0); ; offset += pageSize {
+		persons, err := c.GetPersons(ctx, offset, pageSize, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, person := range persons {
+			result[person.Id] = person.DepartmentId
+		}
+
+		if len(persons) < pageSize {
+			return result, nil
+		}
+	}
+}
+
+func (c *Client) GetPersonsCount(ctx context.Context) (int64, error) {
+	var count operationResultCount
+	respBody := &integrserv.OperationResultInt{
+		Result: &count,
+	}
+	err := c.call(ctx, "GetPersonsCount", getPersonsCountReq{}, respBody)
+	if err != nil {
+		return -1, err
+	}
+
+	return count.OperationResult, nil
+}
+
+func (c *Client) GetPersonById(ctx context.Context, id int64) (*integrserv.PersonData, error) {
+	var person integrserv.PersonData
+	respBody := &integrserv.OperationResult{
+		Result: &person,
+	}
+	err := c.call(ctx, "GetPersonById", getPersonByIdReq{Id: id}, respBody)
+	if err != nil {
+		return nil, err
+	}
+
+	return &person, nil
+}
+
+func (c *Client) AddPerson(ctx context.Context, data integrserv.PersonData) (*integrserv.PersonData, error) {
+	return c.personDataCall(ctx, "AddPerson", data)
+}
+
+func (c *Client) UpdatePerson(ctx context.Context, data integrserv.PersonData) (*integrserv.PersonData, error) {
+	return c.personDataCall(ctx, "UpdatePerson", data)
+}
+
+func (c *Client) DeletePerson(ctx context.Context, data integrserv.PersonData) (*integrserv.PersonData, error) {
+	return c.personDataCall(ctx, "DeletePerson", data)
+}
+
+func (c *Client) personDataCall(ctx context.Context, method string, data integrserv.PersonData) (*integrserv.PersonData, error) {
+	reqData := personDataReq{
+		XMLName: xml.Name{
+			Local: method,
+		},
+		PersonData: data,
+	}
+
+	respBody := &integrserv.OperationResult{
+		Result: &data,
+	}
+	err := c.call(ctx, method, reqData, respBody)
+	if err != nil {
+		return nil, err
+	}
+
+	return &data, nil
+}
@@ -3,7 +3,6 @@ package auth
 import (
 	"context"
 	"errors"
-	"fmt"
 	"log/slog"
 
 	"github.com/Izumra/SKUD_OKEI/domain/dto/resp"
@@ -30,6 +29,7 @@ type Service struct {
 	sessStorage SessionStorage
 	usrRep      repository.User
 	usrPrvdr    provider.User
+	policy      PasswordPolicy
 }
 
 func NewService(
@@ -37,12 +37,14 @@ func NewService(
 	sessStorage SessionStorage,
 	usrRep repository.User,
 	usrPrvdr provider.User,
+	policy PasswordPolicy,
 ) *Service {
 	return &Service{
 		logger,
 		sessStorage,
 		usrRep,
 		usrPrvdr,
+		policy.withDefaults(),
 	}
 }
 
@@ -52,16 +54,31 @@ func (s *Service) Login(ctx context.Context, username, password string) (*resp.S
 
 	user, err := s.usrPrvdr.UserByUsername(ctx, username)
 	if err != nil {
-		logger.Error("Occured the error while finding the user", err)
+		logger.Error("Occured the error while finding the user", slog.Any("err", err))
 		return nil, err
 	}
-	if user.Password != password {
-		return nil, fmt.Errorf("Пароли не совпадают")
+	ok, rehash := s.policy.verify(user.Password, password)
+	if !ok {
+		return nil, ErrPasswordMismatch
+	}
+
+	// the password saved in the clear text or by the previous cost is hashed
+	// again, the failure does not prevent the login
+	if rehash {
+		hash, err := s.policy.hash(password)
+		if err == nil {
+			err = s.usrRep.UpdatePassword(ctx, user.Id, hash)
+		}
+		if err != nil {
+			logger.Error("Occured the error while hashing the password again", slog.Any("err", err))
+		} else {
+			user.Password = hash
+		}
 	}
 
 	sessionId, err := s.sessStorage.Create(ctx, user)
 	if err != nil {
-		logger.Error("Occured the error while creating the session", err)
+		logger.Error("Occured the error while creating the session", slog.Any("err", err))
 		return nil, err
 	}
 
@@ -75,9 +92,20 @@ func (s *Service) Registrate(ctx context.Context, username, password string) (*r
 	op := "internal/services/auth.Service.Registrate"
 	logger := s.logger.With(slog.String("op", op))
 
+	err := s.policy.Check(password)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := s.policy.hash(password)
+	if err != nil {
+		logger.Error("Occured the error while hashing the password", slog.Any("err", err))
+		return nil, err
+	}
+
 	user := entity.User{
 		Username: username,
-		Password: password,
+		Password: hash,
 		Role:     valueobject.StudentRole,
 	}
 
@@ -86,14 +114,14 @@ func (s *Service) Registrate(ctx context.Context, username, password string) (*r
 		if errors.Is(err, storage.ErrUserExist) {
 			return nil, ErrUserAlreadyRegistered
 		}
-		logger.Error("Occured the error while finding the user", err)
+		logger.Error("Occured the error while finding the user", slog.Any("err", err))
 		return nil, err
 	}
 	user.Id = userId
 
 	sessionId, err := s.sessStorage.Create(ctx, &user)
 	if err != nil {
-		logger.Error("Occured the error while creating the session", err)
+		logger.Error("Occured the error while creating the session", slog.Any("err", err))
 		return nil, err
 	}
 
@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/Izumra/SKUD_OKEI/domain/entity"
+	"github.com/Izumra/SKUD_OKEI/internal/storage"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// users keeps the single user, its password is changed by UpdatePassword.
+type users struct {
+	user      entity.User
+	updateErr error
+	updates   int
+}
+
+func (u *users) AddUser(ctx context.Context, data entity.User) (int64, error) {
+	return 0, storage.ErrUserExist
+}
+
+func (u *users) UpdatePassword(ctx context.Context, id int64, password string) error {
+	u.updates++
+	if u.updateErr != nil {
+		return u.updateErr
+	}
+	u.user.Password = password
+	return nil
+}
+
+func (u *users) DeleteUserById(ctx context.Context, id int64) error {
+	return nil
+}
+
+func (u *users) UserByID(ctx context.Context, id int64) (*entity.User, error) {
+	user := u.user
+	return &user, nil
+}
+
+func (u *users) UserByUsername(ctx context.Context, username string) (*entity.User, error) {
+	if username != u.user.Username {
+		return nil, storage.ErrUserNotFound
+	}
+	user := u.user
+	return &user, nil
+}
+
+type sessions struct {
+	SessionStorage
+	created []*entity.User
+}
+
+func (s *sessions) Create(ctx context.Context, data *entity.User) (string, error) {
+	s.created = append(s.created, data)
+	return "session", nil
+}
+
+func TestLoginRehash(t *testing.T) {
+	hash := func(cost int) string {
+		hash, err := bcrypt.GenerateFromPassword([]byte("Asdf!234"), cost)
+		if err != nil {
+			t.Fatalf("GenerateFromPassword() err = %v", err)
+		}
+		return string(hash)
+	}
+	current := hash(bcrypt.MinCost)
+
+	tests := []struct {
+		name        string
+		saved       string
+		password    string
+		updateErr   error
+		wantErr     error
+		wantUpdates int
+		// wantRehashed reports whether the saved password is replaced by the
+		// hash of the policy cost
+		wantRehashed bool
+	}{
+		{
+			name:     "keeps the hash of the policy cost",
+			saved:    current,
+			password: "Asdf!234",
+		},
+		{
+			name:         "hashes the clear text",
+			saved:        "Asdf!234",
+			password:     "Asdf!234",
+			wantUpdates:  1,
+			wantRehashed: true,
+		},
+		{
+			name:         "hashes again by the policy cost",
+			saved:        hash(bcrypt.MinCost + 1),
+			password:     "Asdf!234",
+			wantUpdates:  1,
+			wantRehashed: true,
+		},
+		{
+			name:      "logs in when the hash is not saved",
+			saved:     "Asdf!234",
+			password:  "Asdf!234",
+			updateErr: errors.New("database is locked"),
+			// the update is tried, but the clear text is kept
+			wantUpdates: 1,
+		},
+		{
+			name:     "rejects the wrong password",
+			saved:    "Asdf!234",
+			password: "Asdf!235",
+			wantErr:  ErrPasswordMismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := &users{
+				user: entity.User{
+					Id:       2,
+					Username: "vova",
+					Password: tt.saved,
+				},
+				updateErr: tt.updateErr,
+			}
+			sessions := &sessions{}
+			s := NewService(slog.New(slog.NewTextHandler(io.Discard, nil)), sessions, users, users, PasswordPolicy{Cost: bcrypt.MinCost})
+
+			_, err := s.Login(context.Background(), "vova", tt.password)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("Login() err = %v, want %v", err, tt.wantErr)
+			}
+			if users.updates != tt.wantUpdates {
+				t.Errorf("updates = %d, want %d", users.updates, tt.wantUpdates)
+			}
+
+			saved := users.user.Password
+			if !tt.wantRehashed {
+				if saved != tt.saved {
+					t.Errorf("the saved password was changed to %q", saved)
+				}
+				return
+			}
+			cost, err := bcrypt.Cost([]byte(saved))
+			if err != nil || cost != bcrypt.MinCost {
+				t.Fatalf("the saved password %q is not the hash of the policy cost", saved)
+			}
+			if bcrypt.CompareHashAndPassword([]byte(saved), []byte(tt.password)) != nil {
+				t.Errorf("the saved hash does not match the password")
+			}
+			if len(sessions.created) != 1 || sessions.created[0].Password != saved {
+				t.Errorf("the session was not created with the new hash")
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// maxPasswordLength is the limit of bcrypt, the longer passwords are not
+// hashed by it.
+const maxPasswordLength = 72
+
+var (
+	ErrWeakPassword     = errors.New("пароль не соответствует требованиям")
+	ErrPasswordMismatch = errors.New("Пароли не совпадают")
+)
+
+// PasswordPolicy is the requirements to the passwords of the new users, the
+// zero values are replaced by the defaults.
+type PasswordPolicy struct {
+	MinLength      int
+	RequireUpper   bool
+	RequireLower   bool
+	RequireDigit   bool
+	RequireSpecial bool
+	// Cost is the cost of bcrypt, the hashes of the other cost are replaced
+	// on the login of the user.
+	Cost int
+}
+
+func (p PasswordPolicy) withDefaults() PasswordPolicy {
+	if p.MinLength <= 0 {
+		p.MinLength = 8
+	}
+	if p.Cost < bcrypt.MinCost || p.Cost > bcrypt.MaxCost {
+		p.Cost = bcrypt.DefaultCost
+	}
+	return p
+}
+
+// Check returns the first requirement the password does not meet.
+func (p PasswordPolicy) Check(password string) error {
+	if utf8.RuneCountInString(password) < p.MinLength {
+		return fmt.Errorf("%w: длина пароля должна быть не менее %d символов", ErrWeakPassword, p.MinLength)
+	}
+	if len(password) > maxPasswordLength {
+		return fmt.Errorf("%w: длина пароля должна быть не более %d байт", ErrWeakPassword, maxPasswordLength)
+	}
+
+	var upper, lower, digit, special bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			special = true
+		}
+	}
+
+	switch {
+	case p.RequireUpper && !upper:
+		return fmt.Errorf("%w: пароль должен содержать заглавную букву", ErrWeakPassword)
+	case p.RequireLower && !lower:
+		return fmt.Errorf("%w: пароль должен содержать строчную букву", ErrWeakPassword)
+	case p.RequireDigit && !digit:
+		return fmt.Errorf("%w: пароль должен содержать цифру", ErrWeakPassword)
+	case p.RequireSpecial && !special:
+		return fmt.Errorf("%w: пароль должен содержать специальный символ", ErrWeakPassword)
+	}
+
+	return nil
+}
+
+func (p PasswordPolicy) hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), p.Cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// verify compares the password with the saved one, the passwords saved before
+// the hashing are kept in the clear text and compared in the constant time.
+// It reports whether the saved password has to be hashed again.
+func (p PasswordPolicy) verify(saved, password string) (ok bool, rehash bool) {
+	cost, err := bcrypt.Cost([]byte(saved))
+	if err != nil {
+		return subtle.ConstantTimeCompare([]byte(saved), []byte(password)) == 1, true
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(saved), []byte(password))
+	return err == nil, cost != p.Cost
+}
@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestPasswordPolicyCheck(t *testing.T) {
+	policy := PasswordPolicy{
+		RequireUpper:   true,
+		RequireLower:   true,
+		RequireDigit:   true,
+		RequireSpecial: true,
+	}.withDefaults()
+
+	tests := []struct {
+		name     string
+		password string
+		wantErr  error
+	}{
+		{name: "strong", password: "Asdf!234"},
+		{name: "strong cyrillic", password: "Пароль!234"},
+		{name: "short", password: "As!1", wantErr: ErrWeakPassword},
+		{name: "longer than bcrypt hashes", password: "Aa!1" + strings.Repeat("a", 70), wantErr: ErrWeakPassword},
+		{name: "without upper", password: "asdf!234", wantErr: ErrWeakPassword},
+		{name: "without lower", password: "ASDF!234", wantErr: ErrWeakPassword},
+		{name: "without digit", password: "Asdf!asd", wantErr: ErrWeakPassword},
+		{name: "without special", password: "Asdf1234", wantErr: ErrWeakPassword},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Check(tt.password)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Errorf("Check() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPasswordPolicyVerify(t *testing.T) {
+	policy := PasswordPolicy{Cost: bcrypt.MinCost}.withDefaults()
+
+	hash := func(cost int) string {
+		hash, err := bcrypt.GenerateFromPassword([]byte("Asdf!234"), cost)
+		if err != nil {
+			t.Fatalf("GenerateFromPassword() err = %v", err)
+		}
+		return string(hash)
+	}
+
+	tests := []struct {
+		name       string
+		saved      string
+		password   string
+		wantOk     bool
+		wantRehash bool
+	}{
+		{
+			name:     "hash of the policy cost",
+			saved:    hash(bcrypt.MinCost),
+			password: "Asdf!234",
+			wantOk:   true,
+		},
+		{
+			name:       "hash of the other cost",
+			saved:      hash(bcrypt.MinCost + 1),
+			password:   "Asdf!234",
+			wantOk:     true,
+			wantRehash: true,
+		},
+		{
+			name:     "wrong password for the hash",
+			saved:    hash(bcrypt.MinCost),
+			password: "Asdf!235",
+		},
+		{
+			name:       "clear text",
+			saved:      "Asdf!234",
+			password:   "Asdf!234",
+			wantOk:     true,
+			wantRehash: true,
+		},
+		{
+			name:       "wrong password for the clear text",
+			saved:      "Asdf!234",
+			password:   "Asdf!23",
+			wantRehash: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, rehash := policy.verify(tt.saved, tt.password)
+			if ok != tt.wantOk || rehash != tt.wantRehash {
+				t.Errorf("verify() = %t, %t, want %t, %t", ok, rehash, tt.wantOk, tt.wantRehash)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+type ExpiringSessionStorage interface {
+	SessionStorage
+	Sweep(ctx context.Context, now time.Time) (int64, error)
+}
+
+// Sweeper removes the expired sessions periodically, the expired session is
+// also removed when it is requested.
+type Sweeper struct {
+	logger   *slog.Logger
+	sessions ExpiringSessionStorage
+	interval time.Duration
+}
+
+func NewSweeper(
+	logger *slog.Logger,
+	sessions ExpiringSessionStorage,
+	interval time.Duration,
+) *Sweeper {
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+
+	return &Sweeper{
+		logger:   logger,
+		sessions: sessions,
+		interval: interval,
+	}
+}
+
+func (s *Sweeper) Run(ctx context.Context) {
+	op := "internal/services/auth.Sweeper.Run"
+	logger := s.logger.With(slog.String("op", op))
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			removed, err := s.sessions.Sweep(ctx, now)
+			if err != nil {
+				if ctx.Err() == nil {
+					logger.Info("Occured the error while removing the expired sessions", slog.Any("err", err))
+				}
+				continue
+			}
+			if removed > 0 {
+				logger.Info("The expired sessions are removed", slog.Int64("count", removed))
+			}
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package caching
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/Izumra/SKUD_OKEI/domain/dto/resp"
+	"github.com/Izumra/SKUD_OKEI/internal/storage/cache"
+)
+
+type Cache interface {
+	Flush()
+	CacheStats() []cache.Stats
+}
+
+type Service struct {
+	logger *slog.Logger
+	cache  Cache
+}
+
+func NewService(
+	logger *slog.Logger,
+	cache Cache,
+) *Service {
+	return &Service{
+		logger,
+		cache,
+	}
+}
+
+func (s *Service) CacheStats(ctx context.Context) ([]resp.CacheStats, error) {
+
+	var result []resp.CacheStats
+	for _, stats := range s.cache.CacheStats() {
+		cacheStats := resp.CacheStats{
+			Name:    stats.Name,
+			Entries: stats.Entries,
+			Hits:    stats.Hits,
+			Misses:  stats.Misses,
+			Stale:   stats.Stale,
+		}
+		if requests := stats.Hits + stats.Misses; requests != 0 {
+			cacheStats.HitRatio = float64(stats.Hits) / float64(requests)
+		}
+		result = append(result, cacheStats)
+	}
+
+	return result, nil
+}
+
+func (s *Service) FlushCache(ctx context.Context) error {
+	op := "internal/services/caching.Service.FlushCache"
+	logger := s.logger.With(slog.String("op", op))
+
+	s.cache.Flush()
+	logger.Info("The cache of the reference data is flushed by the administrator")
+
+	return nil
+}
@@ -0,0 +1,311 @@
+package evacuation
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Izumra/SKUD_OKEI/domain/dto/integrserv"
+	"github.com/Izumra/SKUD_OKEI/domain/dto/resp"
+	"github.com/Izumra/SKUD_OKEI/domain/entity"
+	"github.com/Izumra/SKUD_OKEI/internal/storage/cache"
+)
+
+var (
+	ErrNoSecret = errors.New("не задан ключ подписи отчета эвакуации")
+)
+
+type Storage interface {
+	AddEvacuation(ctx context.Context, evacuation entity.Evacuation) (int64, error)
+	Evacuation(ctx context.Context, id int64) (*entity.Evacuation, error)
+	ActiveEvacuation(ctx context.Context) (*entity.Evacuation, error)
+	AccountPerson(ctx context.Context, evacuationId int64, personId int64, accountedAt *time.Time, accountedBy string) error
+	FinishEvacuation(ctx context.Context, id int64, finishedAt time.Time, finishedBy string, makeReport func(evacuation *entity.Evacuation) ([]byte, string, error)) ([]byte, string, error)
+	EvacuationReport(ctx context.Context, id int64) ([]byte, string, error)
+}
+
+type Register interface {
+	Snapshot() ([]resp.PresentPerson, error)
+}
+
+type OrionClient interface {
+	GetDepartments(ctx context.Context) ([]*integrserv.Department, error)
+}
+
+type Service struct {
+	logger   *slog.Logger
+	storage  Storage
+	register Register
+	orion    OrionClient
+	secret   []byte
+
+	mu       sync.Mutex
+	watchers map[chan struct{}]int64
+}
+
+func NewService(
+	logger *slog.Logger,
+	storage Storage,
+	register Register,
+	orion OrionClient,
+	secret string,
+) *Service {
+	return &Service{
+		logger:   logger,
+		storage:  storage,
+		register: register,
+		orion:    orion,
+		secret:   []byte(secret),
+		watchers: map[chan struct{}]int64{},
+	}
+}
+
+// Start activates the evacuation with the persons who are inside the building
+// at the moment.
+func (s *Service) Start(ctx context.Context, user *entity.User) (*resp.Evacuation, error) {
+	op := "internal/services/evacuation.Service.Start"
+	logger := s.logger.With(slog.String("op", op))
+
+	inside, err := s.register.Snapshot()
+	if err != nil {
+		logger.Info("Occured the error while taking the persons inside the building", slog.Any("err", err))
+		return nil, err
+	}
+
+	evacuation := entity.Evacuation{
+		StartedAt: time.Now(),
+		StartedBy: user.Username,
+		Persons:   make([]entity.EvacuationPerson, 0, len(inside)),
+	}
+	for _, person := range inside {
+		evacuation.Persons = append(evacuation.Persons, entity.EvacuationPerson{
+			PersonId:      person.PersonId,
+			LastName:      person.LastName,
+			FirstName:     person.FirstName,
+			MiddleName:    person.MiddleName,
+			TabNum:        person.TabNum,
+			DepartmentId:  person.DepartmentId,
+			EnteredAt:     person.EnteredAt,
+			AccessPointId: person.AccessPointId,
+		})
+	}
+
+	id, err := s.storage.AddEvacuation(ctx, evacuation)
+	if err != nil {
+		logger.Info("Occured the error while saving the evacuation", slog.Any("err", err))
+		return nil, err
+	}
+	logger.Info("The evacuation is started", slog.Int64("id", id), slog.String("by", user.Username), slog.Int("persons", len(inside)))
+
+	return s.evacuation(ctx, id)
+}
+
+func (s *Service) Active(ctx context.Context) (*resp.Evacuation, error) {
+	evacuation, err := s.storage.ActiveEvacuation(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.view(evacuation, s.departmentNames(ctx)), nil
+}
+
+func (s *Service) Evacuation(ctx context.Context, id int64) (*resp.Evacuation, error) {
+	return s.evacuation(ctx, id)
+}
+
+// Account marks the person as accounted for at the assembly point or removes
+// the mark, the watchers of the evacuation are notified.
+func (s *Service) Account(ctx context.Context, user *entity.User, id int64, personId int64, accounted bool) (*resp.Evacuation, error) {
+	op := "internal/services/evacuation.Service.Account"
+	logger := s.logger.With(slog.String("op", op))
+
+	var accountedAt *time.Time
+	accountedBy := ""
+	if accounted {
+		now := time.Now()
+		accountedAt, accountedBy = &now, user.Username
+	}
+
+	err := s.storage.AccountPerson(ctx, id, personId, accountedAt, accountedBy)
+	if err != nil {
+		logger.Info("Occured the error while marking the person of the evacuation", slog.Any("err", err))
+		return nil, err
+	}
+	s.notify(id)
+
+	return s.evacuation(ctx, id)
+}
+
+// Finish finishes the evacuation, the report is signed by the secret and
+// saved with the evacuation.
+func (s *Service) Finish(ctx context.Context, user *entity.User, id int64) (*resp.EvacuationReport, error) {
+	op := "internal/services/evacuation.Service.Finish"
+	logger := s.logger.With(slog.String("op", op))
+
+	if len(s.secret) == 0 {
+		logger.Info("The report of the evacuation can not be signed", slog.Any("err", ErrNoSecret))
+		return nil, ErrNoSecret
+	}
+
+	// the names are taken before the transaction, so it does not wait for
+	// 'Орион Про'
+	names := s.departmentNames(ctx)
+
+	report, signature, err := s.storage.FinishEvacuation(ctx, id, time.Now(), user.Username, func(evacuation *entity.Evacuation) ([]byte, string, error) {
+		report, err := json.Marshal(s.view(evacuation, names))
+		if err != nil {
+			return nil, "", err
+		}
+		return report, s.sign(report), nil
+	})
+	if err != nil {
+		logger.Info("Occured the error while finishing the evacuation", slog.Any("err", err))
+		return nil, err
+	}
+	logger.Info("The evacuation is finished", slog.Int64("id", id), slog.String("by", user.Username))
+	s.notify(id)
+
+	return &resp.EvacuationReport{
+		Report:    report,
+		Signature: signature,
+	}, nil
+}
+
+func (s *Service) Report(ctx context.Context, id int64) (*resp.EvacuationReport, error) {
+	report, signature, err := s.storage.EvacuationReport(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &resp.EvacuationReport{
+		Report:    report,
+		Signature: signature,
+	}, nil
+}
+
+// Watch returns the channel receiving the signal on every change of the
+// evacuation, the signals are coalesced, so the watcher loads the evacuation
+// again. The watch has to be stopped by the returned func.
+func (s *Service) Watch(id int64) (<-chan struct{}, func()) {
+	changes := make(chan struct{}, 1)
+
+	s.mu.Lock()
+	s.watchers[changes] = id
+	s.mu.Unlock()
+
+	return changes, func() {
+		s.mu.Lock()
+		delete(s.watchers, changes)
+		s.mu.Unlock()
+	}
+}
+
+func (s *Service) notify(id int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for changes, watched := range s.watchers {
+		if watched != id {
+			continue
+		}
+		select {
+		case changes <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (s *Service) sign(report []byte) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write(report)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *Service) evacuation(ctx context.Context, id int64) (*resp.Evacuation, error) {
+	evacuation, err := s.storage.Evacuation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.view(evacuation, s.departmentNames(ctx)), nil
+}
+
+// departmentNames returns the names of the departments by their ids, the
+// evacuation is shown without the names when they are not available.
+func (s *Service) departmentNames(ctx context.Context) map[int64]string {
+	op := "internal/services/evacuation.Service.departmentNames"
+	logger := s.logger.With(slog.String("op", op))
+
+	names := map[int64]string{}
+	departments, err := s.orion.GetDepartments(ctx)
+	if err != nil && !errors.Is(err, cache.ErrStaleData) {
+		logger.Info("Occured the error while getting the names of the departments", slog.Any("err", err))
+	}
+	for _, department := range departments {
+		names[department.Id] = department.Name
+	}
+
+	return names
+}
+
+// view groups the persons not accounted for yet by the departments.
+func (s *Service) view(evacuation *entity.Evacuation, names map[int64]string) *resp.Evacuation {
+	result := resp.Evacuation{
+		Id:               evacuation.Id,
+		StartedAt:        evacuation.StartedAt,
+		StartedBy:        evacuation.StartedBy,
+		FinishedAt:       evacuation.FinishedAt,
+		FinishedBy:       evacuation.FinishedBy,
+		Total:            len(evacuation.Persons),
+		Departments:      []resp.EvacuationDepartment{},
+		AccountedPersons: []resp.EvacuationPerson{},
+	}
+
+	remaining := map[int64]int{}
+	for _, person := range evacuation.Persons {
+		view := resp.EvacuationPerson{
+			PersonId:      person.PersonId,
+			LastName:      person.LastName,
+			FirstName:     person.FirstName,
+			MiddleName:    person.MiddleName,
+			TabNum:        person.TabNum,
+			DepartmentId:  person.DepartmentId,
+			EnteredAt:     person.EnteredAt,
+			AccessPointId: person.AccessPointId,
+			AccountedAt:   person.AccountedAt,
+			AccountedBy:   person.AccountedBy,
+		}
+
+		if person.AccountedAt != nil {
+			result.AccountedPersons = append(result.AccountedPersons, view)
+			continue
+		}
+
+		i, ok := remaining[person.DepartmentId]
+		if !ok {
+			i = len(result.Departments)
+			remaining[person.DepartmentId] = i
+			result.Departments = append(result.Departments, resp.EvacuationDepartment{
+				DepartmentId: person.DepartmentId,
+				Name:         names[person.DepartmentId],
+			})
+		}
+		result.Departments[i].Persons = append(result.Departments[i].Persons, view)
+	}
+
+	slices.SortFunc(result.Departments, func(a, b resp.EvacuationDepartment) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	result.Accounted = len(result.AccountedPersons)
+	result.Remaining = result.Total - result.Accounted
+
+	return &result
+}
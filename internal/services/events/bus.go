@@ -0,0 +1,259 @@
+package events
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+
+	"github.com/Izumra/SKUD_OKEI/domain/dto/integrserv"
+)
+
+type BusEvent struct {
+	Seq   uint64
+	Event integrserv.Event
+}
+
+// Subscription receives the published events matching its filter, the events
+// which do not fit the buffer are dropped and counted.
+type Subscription struct {
+	id      uint64
+	name    string
+	filter  func(event integrserv.Event) bool
+	events  chan BusEvent
+	bus     *Bus
+	dropped uint64
+	last    uint64
+	start   uint64
+	startId string
+}
+
+func (sub *Subscription) Events() <-chan BusEvent {
+	return sub.events
+}
+
+// StartSeq returns the sequence number of the last event published before the
+// subscription, the subscriber receives the events after it.
+func (sub *Subscription) StartSeq() uint64 {
+	return sub.start
+}
+
+// StartEventId returns the id of the last event published before the
+// subscription, it is empty when the bus has not published any event yet.
+func (sub *Subscription) StartEventId() string {
+	return sub.startId
+}
+
+// Dropped returns the count of the events dropped because the subscriber did
+// not read them in time.
+func (sub *Subscription) Dropped() uint64 {
+	sub.bus.mu.RLock()
+	defer sub.bus.mu.RUnlock()
+
+	return sub.dropped
+}
+
+func (sub *Subscription) Close() {
+	sub.bus.mu.Lock()
+	defer sub.bus.mu.Unlock()
+
+	if _, ok := sub.bus.subs[sub.id]; ok {
+		delete(sub.bus.subs, sub.id)
+		close(sub.events)
+	}
+}
+
+type SubscriptionStats struct {
+	Id      uint64
+	Name    string
+	Buffer  int
+	Pending int
+	Dropped uint64
+	LastSeq uint64
+}
+
+type BusStats struct {
+	Seq           uint64
+	Subscriptions []SubscriptionStats
+}
+
+// Bus delivers the events to the subscribers without blocking the publisher,
+// every published event gets the next sequence number. The last published
+// events are kept in the ring, so the reconnected subscriber receives the
+// events it missed.
+type Bus struct {
+	mu      sync.RWMutex
+	seq     uint64
+	nextId  uint64
+	subs    map[uint64]*Subscription
+	history []BusEvent
+	next    int
+}
+
+func NewBus(history int) *Bus {
+	if history <= 0 {
+		history = 1000
+	}
+
+	return &Bus{
+		subs:    make(map[uint64]*Subscription),
+		history: make([]BusEvent, 0, history),
+	}
+}
+
+// Subscribe registers the subscriber, the nil filter passes all the events.
+func (b *Bus) Subscribe(name string, buffer int, filter func(event integrserv.Event) bool) *Subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.subscribe(name, buffer, filter)
+}
+
+// SubscribeSince registers the subscriber and returns the events published
+// after the seq. It reports false when the events are not kept in the ring
+// anymore, so the subscriber has to load the whole state again.
+func (b *Bus) SubscribeSince(seq uint64, name string, buffer int, filter func(event integrserv.Event) bool) (*Subscription, []BusEvent, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub := b.subscribe(name, buffer, filter)
+
+	missed, ok := b.since(seq)
+	if !ok {
+		return sub, nil, false
+	}
+
+	if filter != nil {
+		missed = slices.DeleteFunc(missed, func(busEvent BusEvent) bool {
+			return !filter(busEvent.Event)
+		})
+	}
+
+	return sub, missed, true
+}
+
+// Lookup returns the event with the id kept in the ring, the reconnected
+// subscriber finds the seq of the last event it saw, because the seqs are
+// started again with the restart of the server and the ids are not.
+func (b *Bus) Lookup(eventId string) (BusEvent, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for i := len(b.history) - 1; i >= 0; i-- {
+		busEvent := b.history[(b.next+i)%len(b.history)]
+		if busEvent.Event.EventId == eventId {
+			return busEvent, true
+		}
+	}
+
+	return BusEvent{}, false
+}
+
+// Seq returns the sequence number of the last published event.
+func (b *Bus) Seq() uint64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.seq
+}
+
+func (b *Bus) since(seq uint64) ([]BusEvent, bool) {
+	if seq > b.seq {
+		return nil, false
+	}
+	if seq == b.seq {
+		return nil, true
+	}
+
+	count := int(b.seq - seq)
+	if count > len(b.history) {
+		return nil, false
+	}
+
+	missed := make([]BusEvent, 0, count)
+	for i := len(b.history) - count; i < len(b.history); i++ {
+		missed = append(missed, b.history[(b.next+i)%len(b.history)])
+	}
+
+	return missed, true
+}
+
+func (b *Bus) subscribe(name string, buffer int, filter func(event integrserv.Event) bool) *Subscription {
+	if buffer <= 0 {
+		buffer = 64
+	}
+
+	b.nextId++
+	sub := &Subscription{
+		id:     b.nextId,
+		name:   name,
+		filter: filter,
+		events: make(chan BusEvent, buffer),
+		bus:    b,
+		start:  b.seq,
+	}
+	if len(b.history) != 0 {
+		last := (b.next + len(b.history) - 1) % len(b.history)
+		sub.startId = b.history[last].Event.EventId
+	}
+	b.subs[sub.id] = sub
+
+	return sub
+}
+
+func (b *Bus) Publish(events ...integrserv.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, event := range events {
+		b.seq++
+		busEvent := BusEvent{
+			Seq:   b.seq,
+			Event: event,
+		}
+
+		if len(b.history) < cap(b.history) {
+			b.history = append(b.history, busEvent)
+		} else {
+			b.history[b.next] = busEvent
+			b.next = (b.next + 1) % len(b.history)
+		}
+
+		for _, sub := range b.subs {
+			if sub.filter != nil && !sub.filter(event) {
+				continue
+			}
+
+			select {
+			case sub.events <- busEvent:
+				sub.last = busEvent.Seq
+			default:
+				sub.dropped++
+			}
+		}
+	}
+}
+
+func (b *Bus) Stats() BusStats {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	stats := BusStats{
+		Seq:           b.seq,
+		Subscriptions: make([]SubscriptionStats, 0, len(b.subs)),
+	}
+	for _, sub := range b.subs {
+		stats.Subscriptions = append(stats.Subscriptions, SubscriptionStats{
+			Id:      sub.id,
+			Name:    sub.name,
+			Buffer:  cap(sub.events),
+			Pending: len(sub.events),
+			Dropped: sub.dropped,
+			LastSeq: sub.last,
+		})
+	}
+	slices.SortFunc(stats.Subscriptions, func(a, b SubscriptionStats) int {
+		return cmp.Compare(a.Id, b.Id)
+	})
+
+	return stats
+}
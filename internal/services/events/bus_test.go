@@ -0,0 +1,169 @@
+package events
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/Izumra/SKUD_OKEI/domain/dto/integrserv"
+)
+
+// publishN publishes the events with the ids from 1 to n.
+func publishN(b *Bus, n int) {
+	for i := 1; i <= n; i++ {
+		b.Publish(integrserv.Event{
+			EventId:  strconv.Itoa(i),
+			PersonId: int64(i % 2),
+		})
+	}
+}
+
+func busEventIds(busEvents []BusEvent) []string {
+	ids := make([]string, 0, len(busEvents))
+	for _, busEvent := range busEvents {
+		ids = append(ids, busEvent.Event.EventId)
+	}
+
+	return ids
+}
+
+func TestBusSubscribeSince(t *testing.T) {
+	tests := []struct {
+		name      string
+		history   int
+		published int
+		since     uint64
+		filter    func(event integrserv.Event) bool
+		want      []string
+		wantOk    bool
+	}{
+		{
+			name:      "returns the missed events",
+			history:   5,
+			published: 3,
+			since:     0,
+			want:      []string{"1", "2", "3"},
+			wantOk:    true,
+		},
+		{
+			name:      "returns the missed events of the wrapped ring",
+			history:   5,
+			published: 8,
+			since:     3,
+			want:      []string{"4", "5", "6", "7", "8"},
+			wantOk:    true,
+		},
+		{
+			name:      "returns nothing for the last event",
+			history:   5,
+			published: 8,
+			since:     8,
+			want:      []string{},
+			wantOk:    true,
+		},
+		{
+			name:      "reports the events dropped from the ring",
+			history:   5,
+			published: 8,
+			since:     2,
+			wantOk:    false,
+		},
+		{
+			name:      "reports the seq of the other run of the server",
+			history:   5,
+			published: 3,
+			since:     10,
+			wantOk:    false,
+		},
+		{
+			name:      "filters the missed events",
+			history:   5,
+			published: 8,
+			since:     4,
+			filter: func(event integrserv.Event) bool {
+				return event.PersonId == 0
+			},
+			want:   []string{"6", "8"},
+			wantOk: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBus(tt.history)
+			publishN(b, tt.published)
+
+			sub, missed, ok := b.SubscribeSince(tt.since, "test", 0, tt.filter)
+			defer sub.Close()
+
+			if ok != tt.wantOk {
+				t.Fatalf("ok = %t, want %t", ok, tt.wantOk)
+			}
+			if got := busEventIds(missed); ok && !slices.Equal(got, tt.want) {
+				t.Errorf("missed = %v, want %v", got, tt.want)
+			}
+			if sub.StartSeq() != uint64(tt.published) {
+				t.Errorf("StartSeq() = %d, want %d", sub.StartSeq(), tt.published)
+			}
+			if sub.StartEventId() != strconv.Itoa(tt.published) {
+				t.Errorf("StartEventId() = %s, want %d", sub.StartEventId(), tt.published)
+			}
+		})
+	}
+}
+
+func TestBusLookup(t *testing.T) {
+	b := NewBus(5)
+	publishN(b, 8)
+
+	tests := []struct {
+		eventId string
+		wantSeq uint64
+		wantOk  bool
+	}{
+		{eventId: "8", wantSeq: 8, wantOk: true},
+		{eventId: "4", wantSeq: 4, wantOk: true},
+		{eventId: "3", wantOk: false},
+		{eventId: "9", wantOk: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.eventId, func(t *testing.T) {
+			busEvent, ok := b.Lookup(tt.eventId)
+			if ok != tt.wantOk || busEvent.Seq != tt.wantSeq {
+				t.Errorf("Lookup() = %d, %t, want %d, %t", busEvent.Seq, ok, tt.wantSeq, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestBusDrop(t *testing.T) {
+	b := NewBus(10)
+	slow := b.Subscribe("slow", 2, nil)
+	defer slow.Close()
+	filtered := b.Subscribe("filtered", 2, func(event integrserv.Event) bool {
+		return event.EventId == "5"
+	})
+	defer filtered.Close()
+
+	publishN(b, 5)
+
+	// the publisher is not blocked by the full buffer of the subscriber
+	if got := slow.Dropped(); got != 3 {
+		t.Errorf("slow dropped = %d, want 3", got)
+	}
+	if got := filtered.Dropped(); got != 0 {
+		t.Errorf("filtered dropped = %d, want 0", got)
+	}
+
+	var got []string
+	for len(slow.Events()) != 0 {
+		got = append(got, (<-slow.Events()).Event.EventId)
+	}
+	if !slices.Equal(got, []string{"1", "2"}) {
+		t.Errorf("slow received %v, want [1 2]", got)
+	}
+	if busEvent := <-filtered.Events(); busEvent.Seq != 5 {
+		t.Errorf("filtered received seq %d, want 5", busEvent.Seq)
+	}
+}
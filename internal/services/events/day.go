@@ -0,0 +1,133 @@
+package events
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Izumra/SKUD_OKEI/domain/dto/integrserv"
+)
+
+// dayLoad is the loading of the operational day shared by all the callers.
+type dayLoad struct {
+	done    chan struct{}
+	err     error
+	pending []integrserv.Event
+}
+
+// dayLog keeps the events of the current operational day, so the monitors
+// take the state of the day from it instead of requesting Orion on their
+// own. The day is loaded once and is kept up to date by the published
+// events.
+type dayLog struct {
+	mu      sync.Mutex
+	begin   time.Time
+	end     time.Time
+	loaded  bool
+	seen    map[string]bool
+	events  []integrserv.Event
+	loading *dayLoad
+}
+
+func newDayLog() *dayLog {
+	return &dayLog{}
+}
+
+// DayEvents returns the bounds of the operational day containing the moment
+// and its events known at the moment. The event published before the call is
+// either in the result or is delivered by the subscription made before it.
+func (s *Service) DayEvents(ctx context.Context, moment time.Time) (time.Time, time.Time, []integrserv.Event, error) {
+	begin, end := s.OperationalDay(moment)
+
+	for {
+		d := s.day
+		d.mu.Lock()
+		if d.loaded && d.begin.Equal(begin) {
+			events := slices.Clone(d.events)
+			d.mu.Unlock()
+			return begin, end, events, nil
+		}
+
+		load := d.loading
+		if load == nil || !d.begin.Equal(begin) {
+			load = &dayLoad{
+				done: make(chan struct{}),
+			}
+			d.begin, d.end = begin, end
+			d.loaded = false
+			d.seen, d.events = nil, nil
+			d.loading = load
+
+			// the loading outlives the caller, the other callers wait for it
+			go s.loadDay(context.WithoutCancel(ctx), load, begin, end)
+		}
+		d.mu.Unlock()
+
+		select {
+		case <-load.done:
+			if load.err != nil {
+				return begin, end, nil, load.err
+			}
+		case <-ctx.Done():
+			return begin, end, nil, ctx.Err()
+		}
+	}
+}
+
+func (s *Service) loadDay(ctx context.Context, load *dayLoad, begin, end time.Time) {
+	defer close(load.done)
+
+	var events []integrserv.Event
+	err := iterate(ctx, s.streamEvents, begin, time.Now(), IterateOptions{}, func(event integrserv.Event) error {
+		events = append(events, event)
+		return nil
+	})
+
+	d := s.day
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.loading != load {
+		return
+	}
+	d.loading = nil
+
+	if err != nil {
+		load.err = err
+		return
+	}
+
+	d.seen = make(map[string]bool, len(events)+len(load.pending))
+	for _, event := range append(events, load.pending...) {
+		d.add(event)
+	}
+	d.loaded = true
+}
+
+// publish adds the new events to the operational day and then passes them to
+// the bus, so the subscriber made after the publishing finds them in the day.
+func (s *Service) publish(events ...integrserv.Event) {
+	d := s.day
+	d.mu.Lock()
+	for _, event := range events {
+		switch {
+		case d.loaded:
+			d.add(event)
+		case d.loading != nil:
+			d.loading.pending = append(d.loading.pending, event)
+		}
+	}
+	d.mu.Unlock()
+
+	s.bus.Publish(events...)
+}
+
+func (d *dayLog) add(event integrserv.Event) {
+	if event.EventDate.Before(d.begin) || !event.EventDate.Before(d.end) || d.seen[event.EventId] {
+		return
+	}
+
+	d.seen[event.EventId] = true
+	d.events = append(d.events, event)
+}
@@ -0,0 +1,73 @@
+package events
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/Izumra/SKUD_OKEI/domain/dto/integrserv"
+	"github.com/Izumra/SKUD_OKEI/domain/entity"
+	"github.com/Izumra/SKUD_OKEI/internal/storage"
+)
+
+// emptyArchive is the archive which is not synced yet, so the events are
+// taken from IntegrServ.
+type emptyArchive struct {
+	Archive
+}
+
+func (emptyArchive) ArchiveRange(ctx context.Context) (*entity.ArchiveRange, error) {
+	return nil, storage.ErrArchiveEmpty
+}
+
+func TestDayEvents(t *testing.T) {
+	dataset, client := newFakeOrion(t)
+
+	s := NewService(slog.New(slog.NewTextHandler(io.Discard, nil)), client, nil, emptyArchive{}, NewBus(100), 6*time.Hour)
+	moment := fakeNow.Add(-2 * time.Hour)
+	wantBegin, wantEnd := s.OperationalDay(moment)
+
+	var want []string
+	for _, event := range dataset.Events {
+		if !event.EventDate.Before(wantBegin) && event.EventDate.Before(wantEnd) {
+			want = append(want, event.EventId)
+		}
+	}
+	if len(want) == 0 {
+		t.Fatal("the dataset has no events in the operational day")
+	}
+
+	begin, end, events, err := s.DayEvents(context.Background(), moment)
+	if err != nil {
+		t.Fatalf("DayEvents() err = %v", err)
+	}
+	if !begin.Equal(wantBegin) || !end.Equal(wantEnd) {
+		t.Errorf("DayEvents() day = [%s, %s), want [%s, %s)", begin, end, wantBegin, wantEnd)
+	}
+	if len(events) != len(want) {
+		t.Fatalf("events = %d, want %d", len(events), len(want))
+	}
+
+	// the published events of the day are added once, the events of the
+	// other days are not
+	sub := s.Subscribe("test", 10, nil)
+	defer sub.Close()
+	s.publish(
+		integrserv.Event{EventId: "new", EventDate: fakeNow},
+		integrserv.Event{EventId: "new", EventDate: fakeNow},
+		integrserv.Event{EventId: "tomorrow", EventDate: wantEnd},
+	)
+
+	_, _, events, err = s.DayEvents(context.Background(), moment)
+	if err != nil {
+		t.Fatalf("DayEvents() err = %v", err)
+	}
+	if len(events) != len(want)+1 || events[len(events)-1].EventId != "new" {
+		t.Errorf("events = %d, want %d ending with the published one", len(events), len(want)+1)
+	}
+	if pending := len(sub.Events()); pending != 3 {
+		t.Errorf("the bus received %d events, want 3", pending)
+	}
+}
@@ -1,72 +1,272 @@
 package events
 
 import (
+	"cmp"
 	"context"
-	"fmt"
+	"errors"
 	"log/slog"
+	"slices"
+	"time"
 
 	"github.com/Izumra/SKUD_OKEI/domain/dto/integrserv"
-	"github.com/Izumra/SKUD_OKEI/internal/lib/req"
-	"github.com/Izumra/SKUD_OKEI/internal/services/auth"
+	"github.com/Izumra/SKUD_OKEI/domain/dto/resp"
+	"github.com/Izumra/SKUD_OKEI/domain/entity"
+	valueobject "github.com/Izumra/SKUD_OKEI/domain/value-object"
+	"github.com/Izumra/SKUD_OKEI/internal/storage"
 )
 
+type OrionClient interface {
+	GetEvents(ctx context.Context, filter *integrserv.EventFilter) ([]integrserv.Event, error)
+	GetEventsCount(ctx context.Context, filter *integrserv.EventCountFilter) (int64, error)
+	StreamEvents(ctx context.Context, filter *integrserv.EventFilter, fn func(event integrserv.Event) error) error
+}
+
+// Directory resolves the departments of the scope to the persons, the events
+// of the scoped users are filtered by them.
+type Directory interface {
+	DepartmentPersons(ctx context.Context, departments []int64) (map[int64]bool, error)
+}
+
+type Archive interface {
+	ArchiveRange(ctx context.Context) (*entity.ArchiveRange, error)
+	ArchiveEvents(ctx context.Context, events []integrserv.Event, synced entity.ArchiveRange) error
+	ArchivedEvents(ctx context.Context, filter *integrserv.EventFilter) ([]integrserv.Event, error)
+	ArchivedEventsCount(ctx context.Context, filter *integrserv.EventCountFilter) (int64, error)
+	ArchivedUntyped(ctx context.Context, begin, end time.Time) (bool, error)
+}
+
 type Service struct {
-	logger         *slog.Logger
-	sessStore      auth.SessionStorage
-	integrServAddr string
+	logger    *slog.Logger
+	orion     OrionClient
+	directory Directory
+	archive   Archive
+	bus       *Bus
+	dayStart  time.Duration
+	day       *dayLog
 }
 
 func NewService(
 	logger *slog.Logger,
-	sessStore auth.SessionStorage,
-	integrServAddr string,
+	orion OrionClient,
+	directory Directory,
+	archive Archive,
+	bus *Bus,
+	dayStart time.Duration,
 ) *Service {
 	return &Service{
 		logger,
-		sessStore,
-		fmt.Sprintf("%s/soap/IOrionPro", integrServAddr),
+		orion,
+		directory,
+		archive,
+		bus,
+		dayStart,
+		newDayLog(),
 	}
 }
 
-func (s *Service) GetEvents(ctx context.Context, eventsFilter *integrserv.EventFilter) ([]integrserv.Event, error) {
+func (s *Service) GetEvents(ctx context.Context, scope *valueobject.Scope, eventsFilter *integrserv.EventFilter) ([]integrserv.Event, error) {
 	op := "internal/services/events.Service.GetEvents"
 	logger := s.logger.With(slog.String("op", op))
 
-	var expBody []integrserv.Event
-	respBody := integrserv.OperationResultEvents{
-		SoapEnvEncodingStyle: "http://schemas.xmlsoap.org/soap/encoding/",
-		XmlnsNS1:             "urn:OrionProIntf-IOrionPro",
-		XmlnsNS2:             "urn:OrionProIntf",
-
-		Result: &expBody,
+	persons, visible, err := s.restrict(ctx, scope, eventsFilter.Persons.PersonData)
+	if err != nil {
+		logger.Info("Occured the error while taking the persons of the scope", slog.Any("err", err))
+		return nil, err
+	}
+	if !visible {
+		return []integrserv.Event{}, nil
 	}
-	err := req.PreparedReqToXMLIntegerServ(ctx, "GetEvents", s.integrServAddr, eventsFilter, &respBody)
+	eventsFilter.Persons.PersonData = persons
+
+	events, err := s.getEvents(ctx, eventsFilter)
 	if err != nil {
-		logger.Info("Occured the error while taking events by filter", err)
+		logger.Info("Occured the error while taking events by filter", slog.Any("err", err))
 		return nil, err
 	}
-	return expBody, nil
+	return events, nil
 }
 
-func (s *Service) GetEventsCount(ctx context.Context, eventsFilter *integrserv.EventCountFilter) (int64, error) {
+func (s *Service) GetEventsCount(ctx context.Context, scope *valueobject.Scope, eventsFilter *integrserv.EventCountFilter) (int64, error) {
 	op := "internal/services/events.Service.GetEventsCount"
 	logger := s.logger.With(slog.String("op", op))
 
-	type Count struct {
-		OperationResult int64
+	persons, visible, err := s.restrict(ctx, scope, eventsFilter.Persons.PersonData)
+	if err != nil {
+		logger.Info("Occured the error while taking the persons of the scope", slog.Any("err", err))
+		return -1, err
+	}
+	if !visible {
+		return 0, nil
 	}
-	resp := Count{}
-	respBody := integrserv.OperationResultInt{
-		SoapEnvEncodingStyle: "http://schemas.xmlsoap.org/soap/encoding/",
-		XmlnsNS1:             "urn:OrionProIntf-IOrionPro",
-		XmlnsNS2:             "urn:OrionProIntf",
+	eventsFilter.Persons.PersonData = persons
 
-		Result: &resp,
+	if s.archived(ctx, eventsFilter) {
+		count, err := s.archive.ArchivedEventsCount(ctx, eventsFilter)
+		if err == nil {
+			return count, nil
+		}
+		logger.Info("Occured the error while counting the archived events", slog.Any("err", err))
 	}
-	err := req.PreparedReqToXMLIntegerServ(ctx, "GetEventsCount", s.integrServAddr, eventsFilter, &respBody)
+
+	count, err := s.orion.GetEventsCount(ctx, eventsFilter)
 	if err != nil {
-		logger.Info("Occured the error while taking the count of events by filter", err)
+		logger.Info("Occured the error while taking the count of events by filter", slog.Any("err", err))
 		return -1, err
 	}
-	return resp.OperationResult, nil
+	return count, nil
+}
+
+// restrict limits the persons of the filter to the persons of the departments
+// of the scope, the filter without the persons gets all the persons of the
+// scope. It reports false when no person of the filter is in the scope.
+func (s *Service) restrict(ctx context.Context, scope *valueobject.Scope, persons []*integrserv.PersonData) ([]*integrserv.PersonData, bool, error) {
+	if scope == nil {
+		return persons, true, nil
+	}
+
+	allowed, err := s.directory.DepartmentPersons(ctx, scope.Departments())
+	if err != nil {
+		return nil, false, err
+	}
+
+	var result []*integrserv.PersonData
+	if len(persons) == 0 {
+		for id := range allowed {
+			result = append(result, &integrserv.PersonData{Id: id})
+		}
+		slices.SortFunc(result, func(a, b *integrserv.PersonData) int {
+			return cmp.Compare(a.Id, b.Id)
+		})
+	} else {
+		for _, person := range persons {
+			if person != nil && allowed[person.Id] {
+				result = append(result, person)
+			}
+		}
+	}
+
+	return result, len(result) != 0, nil
+}
+
+// getEvents answers from the local archive when the range is already synced
+// and requests Orion otherwise.
+func (s *Service) getEvents(ctx context.Context, eventsFilter *integrserv.EventFilter) ([]integrserv.Event, error) {
+	countFilter := integrserv.EventCountFilter{
+		BeginTime:   eventsFilter.BeginTime,
+		EndTime:     eventsFilter.EndTime,
+		EventTypes:  eventsFilter.EventTypes,
+		Persons:     eventsFilter.Persons,
+		EntryPoints: eventsFilter.EntryPoints,
+	}
+	if s.archived(ctx, &countFilter) {
+		events, err := s.archive.ArchivedEvents(ctx, eventsFilter)
+		if err == nil {
+			return events, nil
+		}
+		s.logger.Info("Occured the error while taking the archived events", slog.Any("err", err))
+	}
+
+	return s.orion.GetEvents(ctx, eventsFilter)
+}
+
+// archived reports whether the archive is able to answer the filter, the
+// filter by the types is answered only when all the events of the range are
+// archived with their types.
+func (s *Service) archived(ctx context.Context, eventsFilter *integrserv.EventCountFilter) bool {
+	synced, err := s.archive.ArchiveRange(ctx)
+	if err != nil {
+		if !errors.Is(err, storage.ErrArchiveEmpty) {
+			s.logger.Info("Occured the error while taking the synced range of the archive", slog.Any("err", err))
+		}
+		return false
+	}
+	if !synced.Covers(eventsFilter.BeginTime, eventsFilter.EndTime) {
+		return false
+	}
+
+	if len(eventsFilter.EventTypes.EventType) != 0 {
+		untyped, err := s.archive.ArchivedUntyped(ctx, eventsFilter.BeginTime, eventsFilter.EndTime)
+		if err != nil {
+			s.logger.Info("Occured the error while checking the types of the archived events", slog.Any("err", err))
+			return false
+		}
+		return !untyped
+	}
+
+	return true
+}
+
+// streamEvents passes the page of the events to fn while it is being read
+// from IntegrServ, the page covered by the archive is taken from it.
+func (s *Service) streamEvents(ctx context.Context, eventsFilter *integrserv.EventFilter, fn func(event integrserv.Event) error) error {
+	countFilter := integrserv.EventCountFilter{
+		BeginTime:   eventsFilter.BeginTime,
+		EndTime:     eventsFilter.EndTime,
+		EventTypes:  eventsFilter.EventTypes,
+		Persons:     eventsFilter.Persons,
+		EntryPoints: eventsFilter.EntryPoints,
+	}
+	if s.archived(ctx, &countFilter) {
+		events, err := s.archive.ArchivedEvents(ctx, eventsFilter)
+		if err == nil {
+			for _, event := range events {
+				if err := fn(event); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		s.logger.Info("Occured the error while taking the archived events", slog.Any("err", err))
+	}
+
+	return s.orion.StreamEvents(ctx, eventsFilter, fn)
+}
+
+// Subscribe subscribes to the new events published by the poller.
+func (s *Service) Subscribe(name string, buffer int, filter func(event integrserv.Event) bool) *Subscription {
+	return s.bus.Subscribe(name, buffer, filter)
+}
+
+// SubscribeSince subscribes to the new events and returns the events published
+// after the seq, it reports false when they are not kept by the bus anymore.
+func (s *Service) SubscribeSince(seq uint64, name string, buffer int, filter func(event integrserv.Event) bool) (*Subscription, []BusEvent, bool) {
+	return s.bus.SubscribeSince(seq, name, buffer, filter)
+}
+
+// OperationalDay returns the bounds of the operational day containing the
+// moment, the day begins at the configured offset from the midnight.
+func (s *Service) OperationalDay(moment time.Time) (time.Time, time.Time) {
+	day := startOfDay(moment)
+	if moment.Before(day.Add(s.dayStart)) {
+		day = day.AddDate(0, 0, -1)
+	}
+
+	return day.Add(s.dayStart), day.AddDate(0, 0, 1).Add(s.dayStart)
+}
+
+// LookupEvent returns the event with the id if it is still kept by the bus.
+func (s *Service) LookupEvent(eventId string) (BusEvent, bool) {
+	return s.bus.Lookup(eventId)
+}
+
+func (s *Service) BusStats(ctx context.Context) (*resp.EventBus, error) {
+
+	stats := s.bus.Stats()
+	result := resp.EventBus{
+		Seq:           stats.Seq,
+		Subscribers:   len(stats.Subscriptions),
+		Subscriptions: make([]resp.EventBusSubscription, 0, len(stats.Subscriptions)),
+	}
+	for _, sub := range stats.Subscriptions {
+		result.Subscriptions = append(result.Subscriptions, resp.EventBusSubscription{
+			Id:      sub.Id,
+			Name:    sub.Name,
+			Buffer:  sub.Buffer,
+			Lag:     sub.Pending,
+			Dropped: sub.Dropped,
+			LastSeq: sub.LastSeq,
+		})
+	}
+
+	return &result, nil
 }
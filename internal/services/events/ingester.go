@@ -0,0 +1,160 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/Izumra/SKUD_OKEI/domain/dto/integrserv"
+	"github.com/Izumra/SKUD_OKEI/domain/entity"
+	"github.com/Izumra/SKUD_OKEI/internal/storage"
+)
+
+// Ingester copies the events from Orion to the local archive, the synced
+// range is saved with the events, so the ingester resumes after restarts.
+type Ingester struct {
+	logger   *slog.Logger
+	orion    OrionClient
+	archive  Archive
+	interval time.Duration
+	lag      time.Duration
+	chunk    time.Duration
+}
+
+func NewIngester(
+	logger *slog.Logger,
+	orion OrionClient,
+	archive Archive,
+	interval time.Duration,
+	lag time.Duration,
+) *Ingester {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	if lag < 0 {
+		lag = 0
+	}
+
+	return &Ingester{
+		logger:   logger,
+		orion:    orion,
+		archive:  archive,
+		interval: interval,
+		lag:      lag,
+		chunk:    defaultWindow,
+	}
+}
+
+func (i *Ingester) Run(ctx context.Context) {
+	op := "internal/services/events.Ingester.Run"
+	logger := i.logger.With(slog.String("op", op))
+
+	ticker := time.NewTicker(i.interval)
+	defer ticker.Stop()
+
+	for {
+		err := i.Sync(ctx)
+		if err != nil && ctx.Err() == nil {
+			logger.Info("Occured the error while syncing the archive of the events", slog.Any("err", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync copies the events from the end of the synced range up to the moment
+// shifted by the lag, Orion could save the events of the controllers late.
+func (i *Ingester) Sync(ctx context.Context) error {
+	synced, err := i.syncedRange(ctx, startOfDay(time.Now()))
+	if err != nil {
+		return err
+	}
+
+	end := time.Now().Add(-i.lag).Truncate(time.Second)
+	for synced.To.Before(end) {
+		chunkEnd := synced.To.Add(i.chunk)
+		if chunkEnd.After(end) {
+			chunkEnd = end
+		}
+
+		err := i.ingest(ctx, synced.To, chunkEnd)
+		if err != nil {
+			return err
+		}
+		synced.To = chunkEnd
+	}
+
+	return nil
+}
+
+// Backfill imports the events older than the synced range down to from, the
+// range is extended chunk by chunk, so the interrupted backfill resumes from
+// the last imported chunk.
+func (i *Ingester) Backfill(ctx context.Context, from time.Time, chunk time.Duration, onChunk func(synced entity.ArchiveRange)) error {
+	if chunk <= 0 {
+		chunk = i.chunk
+	}
+
+	synced, err := i.syncedRange(ctx, time.Now().Add(-i.lag).Truncate(time.Second))
+	if err != nil {
+		return err
+	}
+
+	for synced.From.After(from) {
+		chunkBegin := synced.From.Add(-chunk)
+		if chunkBegin.Before(from) {
+			chunkBegin = from
+		}
+
+		err := i.ingest(ctx, chunkBegin, synced.From)
+		if err != nil {
+			return err
+		}
+		synced.From = chunkBegin
+
+		if onChunk != nil {
+			onChunk(*synced)
+		}
+	}
+
+	return nil
+}
+
+func (i *Ingester) ingest(ctx context.Context, begin, end time.Time) error {
+	var events []integrserv.Event
+	err := iterate(ctx, i.orion.StreamEvents, begin, end, IterateOptions{}, func(event integrserv.Event) error {
+		events = append(events, event)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return i.archive.ArchiveEvents(ctx, events, entity.ArchiveRange{
+		From: begin,
+		To:   end,
+	})
+}
+
+// syncedRange returns the synced range of the archive, the empty archive
+// starts at the passed moment.
+func (i *Ingester) syncedRange(ctx context.Context, start time.Time) (*entity.ArchiveRange, error) {
+	synced, err := i.archive.ArchiveRange(ctx)
+	if errors.Is(err, storage.ErrArchiveEmpty) {
+		return &entity.ArchiveRange{
+			From: start,
+			To:   start,
+		}, nil
+	}
+
+	return synced, err
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,145 @@
+package events
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/Izumra/SKUD_OKEI/domain/dto/integrserv"
+	valueobject "github.com/Izumra/SKUD_OKEI/domain/value-object"
+)
+
+const (
+	defaultPageSize = 100
+	defaultWindow   = 24 * time.Hour
+)
+
+type IterateOptions struct {
+	EventTypes  []*integrserv.EventType
+	Persons     []*integrserv.PersonData
+	EntryPoints []*integrserv.EntryPoint
+	// Scope limits the events to the persons of its departments, the nil
+	// scope passes the events of all the persons.
+	Scope *valueobject.Scope
+	// PageSize is the count of the events requested from IntegrServ at once.
+	PageSize int64
+	// Window is the length of the time range requested at once, the offset
+	// is reset for every window.
+	Window     time.Duration
+	OnProgress func(progress Progress)
+}
+
+type Progress struct {
+	Begin   time.Time
+	End     time.Time
+	Current time.Time
+	Pages   int
+	Fetched int
+}
+
+// streamPage passes the events of the page to fn as they are read.
+type streamPage func(ctx context.Context, filter *integrserv.EventFilter, fn func(event integrserv.Event) error) error
+
+// IterateEvents passes to fn every event of the range [begin, end) exactly
+// once, requesting the events page by page, every page is streamed from
+// IntegrServ without being kept in the memory. The iteration stops on the first
+// error returned by fn or when the context is done.
+func (s *Service) IterateEvents(ctx context.Context, begin, end time.Time, opts IterateOptions, fn func(event integrserv.Event) error) error {
+	op := "internal/services/events.Service.IterateEvents"
+	logger := s.logger.With(slog.String("op", op))
+
+	persons, visible, err := s.restrict(ctx, opts.Scope, opts.Persons)
+	if err != nil {
+		logger.Info("Occured the error while taking the persons of the scope", slog.Any("err", err))
+		return err
+	}
+	if !visible {
+		return nil
+	}
+	opts.Persons = persons
+
+	err = iterate(ctx, s.streamEvents, begin, end, opts, fn)
+	if err != nil {
+		logger.Info("Occured the error while iterating over the events", slog.Any("err", err))
+		return err
+	}
+
+	return nil
+}
+
+func iterate(ctx context.Context, stream streamPage, begin, end time.Time, opts IterateOptions, fn func(event integrserv.Event) error) error {
+	if opts.PageSize <= 0 {
+		opts.PageSize = defaultPageSize
+	}
+	if opts.Window <= 0 {
+		opts.Window = defaultWindow
+	}
+
+	progress := Progress{
+		Begin: begin,
+		End:   end,
+	}
+
+	var prevSeen map[string]bool
+	for windowBegin := begin; windowBegin.Before(end); windowBegin = windowBegin.Add(opts.Window) {
+		windowEnd := windowBegin.Add(opts.Window)
+		if windowEnd.After(end) {
+			windowEnd = end
+		}
+		progress.Current = windowBegin
+
+		seen := map[string]bool{}
+		for offset := int64(0); ; {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			filter := integrserv.EventFilter{
+				BeginTime: windowBegin,
+				EndTime:   windowEnd,
+				EventTypes: integrserv.EventTypes{
+					EventType: opts.EventTypes,
+				},
+				Persons: integrserv.Persons{
+					PersonData: opts.Persons,
+				},
+				EntryPoints: integrserv.EntryPoints{
+					EntryPoint: opts.EntryPoints,
+				},
+				Offset: offset,
+				Count:  opts.PageSize,
+			}
+
+			var read int64
+			err := stream(ctx, &filter, func(event integrserv.Event) error {
+				read++
+				if seen[event.EventId] || prevSeen[event.EventId] {
+					return nil
+				}
+				seen[event.EventId] = true
+				progress.Fetched++
+				if event.EventDate.After(progress.Current) {
+					progress.Current = event.EventDate
+				}
+
+				return fn(event)
+			})
+			if err != nil {
+				return err
+			}
+
+			progress.Pages++
+			if opts.OnProgress != nil {
+				opts.OnProgress(progress)
+			}
+
+			if read < opts.PageSize {
+				break
+			}
+			offset += read
+		}
+		prevSeen = seen
+	}
+
+	return nil
+}
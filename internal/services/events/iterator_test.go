@@ -0,0 +1,163 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Izumra/SKUD_OKEI/domain/dto/integrserv"
+	"github.com/Izumra/SKUD_OKEI/internal/lib/req"
+	"github.com/Izumra/SKUD_OKEI/internal/orion"
+	"github.com/Izumra/SKUD_OKEI/internal/orion/fake"
+)
+
+var fakeNow = time.Date(2026, 10, 14, 18, 0, 0, 0, time.Local)
+
+// newFakeOrion serves the fake IntegrServ to the client of Orion.
+func newFakeOrion(t *testing.T) (*fake.Dataset, *orion.Client) {
+	t.Helper()
+
+	dataset := fake.Generate(fake.DatasetConfig{
+		Seed:    1,
+		Persons: 20,
+		Days:    3,
+		Now:     fakeNow,
+	})
+	httpServer := httptest.NewServer(fake.NewServer(dataset, 1))
+	t.Cleanup(httpServer.Close)
+
+	transport := req.NewTransport(req.TransportConfig{
+		Timeout:      time.Second,
+		RetryBackoff: time.Millisecond,
+	})
+
+	return dataset, orion.NewClient(httpServer.URL, transport)
+}
+
+func TestIterate(t *testing.T) {
+	dataset, client := newFakeOrion(t)
+	begin, end := fakeNow.AddDate(0, 0, -3), fakeNow
+
+	tests := []struct {
+		name     string
+		pageSize int64
+		window   time.Duration
+		persons  []*integrserv.PersonData
+	}{
+		{
+			name:     "single page",
+			pageSize: 10000,
+		},
+		{
+			name:     "pages of one event",
+			pageSize: 1,
+		},
+		{
+			name:     "pages of the odd size",
+			pageSize: 7,
+		},
+		{
+			name:     "hourly windows",
+			pageSize: 5,
+			window:   time.Hour,
+		},
+		{
+			name:     "events of the persons",
+			pageSize: 3,
+			persons:  []*integrserv.PersonData{{Id: 1}, {Id: 2}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			window := tt.window
+			if window == 0 {
+				window = defaultWindow
+			}
+			persons := map[int64]bool{}
+			for _, person := range tt.persons {
+				persons[person.Id] = true
+			}
+
+			// every window takes the pages with its events and the last
+			// short page
+			var want []string
+			wantPages := 0
+			for windowBegin := begin; windowBegin.Before(end); windowBegin = windowBegin.Add(window) {
+				windowEnd := windowBegin.Add(window)
+				if windowEnd.After(end) {
+					windowEnd = end
+				}
+
+				count := 0
+				for _, event := range dataset.Events {
+					if event.EventDate.Before(windowBegin) || !event.EventDate.Before(windowEnd) {
+						continue
+					}
+					if len(persons) != 0 && !persons[event.PersonId] {
+						continue
+					}
+					want = append(want, event.EventId)
+					count++
+				}
+				wantPages += count/int(tt.pageSize) + 1
+			}
+
+			var got []string
+			var progress Progress
+			err := iterate(context.Background(), client.StreamEvents, begin, end, IterateOptions{
+				Persons:  tt.persons,
+				PageSize: tt.pageSize,
+				Window:   tt.window,
+				OnProgress: func(p Progress) {
+					progress = p
+				},
+			}, func(event integrserv.Event) error {
+				got = append(got, event.EventId)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("iterate() err = %v", err)
+			}
+
+			if len(want) == 0 {
+				t.Fatal("the dataset has no events in the range")
+			}
+			if len(got) != len(want) {
+				t.Fatalf("events = %d, want %d", len(got), len(want))
+			}
+			for i := range want {
+				if got[i] != want[i] {
+					t.Fatalf("event %d = %s, want %s", i, got[i], want[i])
+				}
+			}
+			if progress.Pages != wantPages || progress.Fetched != len(want) {
+				t.Errorf("progress = %d pages, %d fetched, want %d pages, %d fetched", progress.Pages, progress.Fetched, wantPages, len(want))
+			}
+		})
+	}
+}
+
+func TestIterateStop(t *testing.T) {
+	_, client := newFakeOrion(t)
+	errStop := errors.New("stop")
+
+	read := 0
+	err := iterate(context.Background(), client.StreamEvents, fakeNow.AddDate(0, 0, -3), fakeNow, IterateOptions{
+		PageSize: 5,
+	}, func(event integrserv.Event) error {
+		read++
+		if read == 8 {
+			return errStop
+		}
+		return nil
+	})
+	if !errors.Is(err, errStop) {
+		t.Errorf("err = %v, want %v", err, errStop)
+	}
+	if read != 8 {
+		t.Errorf("read = %d, want 8", read)
+	}
+}
@@ -0,0 +1,90 @@
+package events
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/Izumra/SKUD_OKEI/domain/dto/integrserv"
+)
+
+// Poller is the single source of the new events for the bus, so the
+// consumers do not request Orion on their own.
+type Poller struct {
+	logger   *slog.Logger
+	service  *Service
+	interval time.Duration
+	overlap  time.Duration
+}
+
+func NewPoller(
+	logger *slog.Logger,
+	service *Service,
+	interval time.Duration,
+	overlap time.Duration,
+) *Poller {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	if overlap < 0 {
+		overlap = 0
+	}
+
+	return &Poller{
+		logger:   logger,
+		service:  service,
+		interval: interval,
+		overlap:  overlap,
+	}
+}
+
+// Run polls the events from the moment of the start. Every poll requests the
+// range overlapping the previous one, because Orion could save the events of
+// the controllers late, the repeated events are skipped.
+func (p *Poller) Run(ctx context.Context) {
+	op := "internal/services/events.Poller.Run"
+	logger := p.logger.With(slog.String("op", op))
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	// cursor is the end of the previous poll
+	cursor := time.Now()
+	seen := map[string]time.Time{}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		end := time.Now()
+
+		var fresh []integrserv.Event
+		err := iterate(ctx, p.service.streamEvents, cursor.Add(-p.overlap), end, IterateOptions{}, func(event integrserv.Event) error {
+			if _, ok := seen[event.EventId]; ok {
+				return nil
+			}
+			seen[event.EventId] = event.EventDate
+			fresh = append(fresh, event)
+			return nil
+		})
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.Info("Occured the error while polling the new events", slog.Any("err", err))
+			}
+			continue
+		}
+
+		if len(fresh) != 0 {
+			p.service.publish(fresh...)
+		}
+
+		cursor = end
+		for id, date := range seen {
+			if date.Before(cursor.Add(-p.overlap)) {
+				delete(seen, id)
+			}
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package health
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Izumra/SKUD_OKEI/domain/dto/resp"
+	"github.com/Izumra/SKUD_OKEI/internal/lib/req"
+	integrServUtil "github.com/Izumra/SKUD_OKEI/utils/integerserv"
+)
+
+const (
+	transitionsLimit = 50
+	latencyWindow    = 20
+)
+
+type OrionProbe interface {
+	GetPersonsCount(ctx context.Context) (int64, error)
+}
+
+type Recoverer interface {
+	Recover(ctx context.Context) error
+	History() []integrServUtil.Attempt
+	Strategy() string
+}
+
+type Breaker interface {
+	State() req.BreakerState
+}
+
+type Limiter interface {
+	Stats() req.LimiterStats
+}
+
+type Service struct {
+	logger    *slog.Logger
+	probe     OrionProbe
+	recoverer Recoverer
+	breaker   Breaker
+	limiter   Limiter
+	interval  time.Duration
+
+	mu          sync.RWMutex
+	checked     bool
+	up          bool
+	checkedAt   time.Time
+	since       time.Time
+	lastError   string
+	latencies   []time.Duration
+	transitions []resp.OrionTransition
+}
+
+func NewService(
+	logger *slog.Logger,
+	probe OrionProbe,
+	recoverer Recoverer,
+	breaker Breaker,
+	limiter Limiter,
+	interval time.Duration,
+) *Service {
+	if interval <= 0 {
+		interval = 15 * time.Second
+	}
+
+	return &Service{
+		logger:    logger,
+		probe:     probe,
+		recoverer: recoverer,
+		breaker:   breaker,
+		limiter:   limiter,
+		interval:  interval,
+	}
+}
+
+// Run probes IntegrServ every interval until the context is done.
+func (s *Service) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.check(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Service) check(ctx context.Context) {
+	op := "internal/services/health.Service.check"
+	logger := s.logger.With(slog.String("op", op))
+
+	ctx, cancel := context.WithTimeout(ctx, s.interval)
+	defer cancel()
+
+	startedAt := time.Now()
+	_, err := s.probe.GetPersonsCount(ctx)
+	latency := time.Since(startedAt)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	up := err == nil
+	s.checkedAt = startedAt
+	s.latencies = append(s.latencies, latency)
+	if len(s.latencies) > latencyWindow {
+		s.latencies = s.latencies[1:]
+	}
+	if err != nil {
+		s.lastError = err.Error()
+	}
+
+	if !s.checked || s.up != up {
+		transition := resp.OrionTransition{
+			At: startedAt,
+			Up: up,
+		}
+		if err != nil {
+			transition.Error = err.Error()
+			logger.Info("Служба IntegrServ перестала отвечать", slog.Any("причина", err))
+		} else if s.checked {
+			logger.Info("Служба IntegrServ снова доступна")
+		}
+
+		s.transitions = append(s.transitions, transition)
+		if len(s.transitions) > transitionsLimit {
+			s.transitions = s.transitions[1:]
+		}
+		s.since = startedAt
+	}
+	s.checked = true
+	s.up = up
+}
+
+func (s *Service) orionHealth() resp.OrionHealth {
+	health := resp.OrionHealth{
+		Up:        s.up,
+		CheckedAt: s.checkedAt,
+	}
+	if len(s.latencies) != 0 {
+		health.LatencyMs = s.latencies[len(s.latencies)-1].Milliseconds()
+	}
+
+	return health
+}
+
+func (s *Service) Health(ctx context.Context) *resp.Health {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// the state of IntegrServ is not known until the first probe completes
+	status := "ok"
+	if !s.checked {
+		status = "unknown"
+	} else if !s.up {
+		status = "degraded"
+	}
+
+	return &resp.Health{
+		Status: status,
+		Orion:  s.orionHealth(),
+	}
+}
+
+func (s *Service) OrionStatus(ctx context.Context) (*resp.OrionStatus, error) {
+
+	s.mu.RLock()
+	status := resp.OrionStatus{
+		OrionHealth:      s.orionHealth(),
+		Since:            s.since,
+		LastError:        s.lastError,
+		Breaker:          s.breaker.State().String(),
+		RecoveryStrategy: s.recoverer.Strategy(),
+		Transitions:      make([]resp.OrionTransition, len(s.transitions)),
+	}
+	copy(status.Transitions, s.transitions)
+	if len(s.latencies) != 0 {
+		var total time.Duration
+		for _, latency := range s.latencies {
+			total += latency
+		}
+		status.AvgLatencyMs = (total / time.Duration(len(s.latencies))).Milliseconds()
+	}
+	s.mu.RUnlock()
+
+	stats := s.limiter.Stats()
+	status.Limiter = resp.OrionLimiter{
+		MaxConcurrent: stats.MaxConcurrent,
+		InFlight:      stats.InFlight,
+		Queued:        stats.Queued,
+		Calls:         stats.Calls,
+		Coalesced:     stats.Coalesced,
+		AvgWaitMs:     stats.AvgWait.Milliseconds(),
+		MaxWaitMs:     stats.MaxWait.Milliseconds(),
+		AvgLatencyMs:  stats.AvgLatency.Milliseconds(),
+		MaxLatencyMs:  stats.MaxLatency.Milliseconds(),
+	}
+
+	for _, attempt := range s.recoverer.History() {
+		status.Reboots = append(status.Reboots, resp.OrionReboot{
+			StartedAt:  attempt.StartedAt,
+			DurationMs: attempt.Duration.Milliseconds(),
+			Strategy:   attempt.Strategy,
+			Success:    attempt.Success,
+			Error:      attempt.Error,
+		})
+	}
+
+	return &status, nil
+}
+
+func (s *Service) RestartOrion(ctx context.Context) error {
+	op := "internal/services/health.Service.RestartOrion"
+	logger := s.logger.With(slog.String("op", op))
+
+	err := s.recoverer.Recover(ctx)
+	if err != nil {
+		logger.Info("Служба IntegrServ не перезагружена", slog.Any("причина", err))
+		return err
+	}
+	logger.Info("Служба IntegrServ перезагружена по запросу администратора")
+
+	go s.check(context.Background())
+
+	return nil
+}
@@ -2,7 +2,6 @@ package key
 
 import (
 	"context"
-	"encoding/xml"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -12,153 +11,137 @@ import (
 	"github.com/Izumra/SKUD_OKEI/domain/dto/integrserv"
 	valueobject "github.com/Izumra/SKUD_OKEI/domain/value-object"
 	"github.com/Izumra/SKUD_OKEI/internal/http/controllers"
-	"github.com/Izumra/SKUD_OKEI/internal/lib/req"
+	"github.com/Izumra/SKUD_OKEI/internal/orion"
 	"github.com/Izumra/SKUD_OKEI/internal/services/auth"
+	"github.com/Izumra/SKUD_OKEI/internal/services/events"
 	"github.com/Izumra/SKUD_OKEI/internal/storage/cache"
 )
 
 var (
-	ErrSessionTokenInvalid = errors.New("сессия пользователя не действительна")
-	ErrGettingStats        = errors.New("неожиданная ошибка при загрузке статистики пользователя")
-	ErrAccessDenied        = errors.New("вам отказано в доступе")
+	ErrGettingStats = errors.New("неожиданная ошибка при загрузке статистики пользователя")
 )
 
+type OrionClient interface {
+	GetKeys(ctx context.Context, offset int64, count int64) ([]*integrserv.KeyData, error)
+	GetKeyData(ctx context.Context, cardNo string) (*integrserv.KeyData, error)
+	AddKey(ctx context.Context, keyData *integrserv.KeyData) (*integrserv.KeyData, error)
+	UpdateKeyData(ctx context.Context, keyData *integrserv.KeyData) (*integrserv.KeyData, error)
+	ConvertWiegandToTouchMemory(ctx context.Context, code int, codeSize int) (string, error)
+	ConvertPinToTouchMemory(ctx context.Context, pin string) (string, error)
+	DepartmentPersons(ctx context.Context, departments []int64) (map[int64]bool, error)
+}
+
 type Service struct {
-	logger         *slog.Logger
-	sessStore      auth.SessionStorage
-	eventService   controllers.EventsService
-	integrServAddr string
+	logger       *slog.Logger
+	sessStore    auth.SessionStorage
+	eventService controllers.EventsService
+	orion        OrionClient
 }
 
 func NewService(
 	logger *slog.Logger,
 	sessStore auth.SessionStorage,
 	eventService controllers.EventsService,
-	integrServAddr string,
+	orion OrionClient,
 ) *Service {
 	return &Service{
 		logger,
 		sessStore,
 		eventService,
-		fmt.Sprintf("%s/soap/IOrionPro", integrServAddr),
+		orion,
 	}
 }
 
-func (s *Service) GetKeys(ctx context.Context, sessionId string, offset int64, count int64) ([]*integrserv.KeyData, error) {
+func (s *Service) GetKeys(ctx context.Context, scope *valueobject.Scope, offset int64, count int64) ([]*integrserv.KeyData, error) {
 	op := "internal/services/key/Service.GetKeys"
 	logger := s.logger.With(slog.String("op", op))
 
-	type Data struct {
-		XMLName xml.Name
-		Offset  int64
-		Count   int64
-	}
-	reqData := Data{
-		XMLName: xml.Name{
-			Local: "GetKeys",
-		},
-		Offset: offset,
-		Count:  count,
-	}
-
-	var expBody []*integrserv.KeyData
-	respBody := integrserv.OperationResultEvents{
-		SoapEnvEncodingStyle: "http://schemas.xmlsoap.org/soap/encoding/",
-		XmlnsNS1:             "urn:OrionProIntf-IOrionPro",
-		XmlnsNS2:             "urn:OrionProIntf",
+	if scope != nil {
+		keys, err := s.scopedKeys(ctx, scope)
+		if err != nil {
+			logger.Info("Occured the error while taking the list of the keys", slog.Any("err", err))
+			return nil, err
+		}
 
-		Result: &expBody,
+		if offset >= int64(len(keys)) {
+			return []*integrserv.KeyData{}, nil
+		}
+		keys = keys[offset:]
+		if count > 0 && count < int64(len(keys)) {
+			keys = keys[:count]
+		}
+		return keys, nil
 	}
 
-	err := req.PreparedReqToXMLIntegerServ(ctx, "GetKeys", s.integrServAddr, reqData, &respBody)
+	keys, err := s.orion.GetKeys(ctx, offset, count)
 	if err != nil {
-		logger.Info("Occured the error while taking events by filter", err)
+		logger.Info("Occured the error while taking the list of the keys", slog.Any("err", err))
 		return nil, err
 	}
 
-	return expBody, nil
+	return keys, nil
 }
 
-func (s *Service) GetKeyData(ctx context.Context, sessionId string, card string) (*integrserv.KeyData, error) {
+func (s *Service) GetKeyData(ctx context.Context, scope *valueobject.Scope, card string) (*integrserv.KeyData, error) {
 	op := "internal/services/key/Service.GetKeyData"
 	logger := s.logger.With(slog.String("op", op))
 
-	err := s.accessGuardian(ctx, sessionId)
-	if err != nil {
-		return nil, err
-	}
-
-	type Data struct {
-		XMLName xml.Name
-		CardNo  string
-	}
-	reqData := Data{
-		XMLName: xml.Name{
-			Local: "GetKeyData",
-		},
-		CardNo: card,
-	}
-
-	var resp integrserv.KeyData
-	respBody := &integrserv.OperationResult{
-		SoapEnvEncodingStyle: "http://schemas.xmlsoap.org/soap/encoding/",
-		XmlnsNS1:             "urn:OrionProIntf-IOrionPro",
-		XmlnsNS2:             "urn:OrionProIntf",
-
-		Result: &resp,
+	key, err := s.orion.GetKeyData(ctx, card)
+	if key != nil {
+		scopeErr := s.checkScope(ctx, scope, key.PersonId)
+		if scopeErr != nil {
+			return nil, scopeErr
+		}
 	}
-
-	err = req.PreparedReqToXMLIntegerServ(ctx, "GetKeyData", s.integrServAddr, reqData, respBody)
 	if err != nil {
-		logger.Info("Occured the error while finding the user by id", err)
+		if errors.Is(err, cache.ErrStaleData) {
+			logger.Info("The key data is served from the cache", slog.Any("err", err))
+			return key, err
+		}
+		logger.Info("Occured the error while finding the key by card number", slog.Any("err", err))
 		return nil, err
 	}
 
-	return &resp, nil
+	return key, nil
 }
 
-func (s *Service) UpdateKeyData(ctx context.Context, sessionId string, keyData *integrserv.KeyData) (*integrserv.KeyData, error) {
+// UpdateKeyData updates the key, the scoped user is not able to pass the key
+// to the person out of the departments of the scope.
+func (s *Service) UpdateKeyData(ctx context.Context, scope *valueobject.Scope, keyData *integrserv.KeyData) (*integrserv.KeyData, error) {
 	op := "internal/services/key/Service.UpdateKeyData"
 	logger := s.logger.With(slog.String("op", op))
 
-	err := s.accessGuardian(ctx, sessionId)
+	err := s.checkScope(ctx, scope, keyData.PersonId)
 	if err != nil {
 		return nil, err
 	}
-
-	type ReqData struct {
-		XMLName xml.Name
-		KeyData *integrserv.KeyData
-	}
-	reqData := ReqData{
-		XMLName: xml.Name{
-			Local: "UpdateKeyData",
-		},
-		KeyData: keyData,
+	if scope != nil {
+		current, err := s.orion.GetKeyData(ctx, keyData.Code)
+		if err != nil && !errors.Is(err, cache.ErrStaleData) && !errors.Is(err, orion.ErrKeyNotFound) {
+			return nil, err
+		}
+		if current != nil {
+			err = s.checkScope(ctx, scope, current.PersonId)
+			if err != nil {
+				return nil, err
+			}
+		}
 	}
 
-	var respData integrserv.KeyData
-	respBody := &integrserv.OperationResult{
-		SoapEnvEncodingStyle: "http://schemas.xmlsoap.org/soap/encoding/",
-		XmlnsNS1:             "urn:OrionProIntf-IOrionPro",
-		XmlnsNS2:             "urn:OrionProIntf",
-
-		Result: &respData,
-	}
-	err = req.PreparedReqToXMLIntegerServ(ctx, "UpdateKeyData", s.integrServAddr, reqData, respBody)
+	key, err := s.orion.UpdateKeyData(ctx, keyData)
 	if err != nil {
-		logger.Info("Occured the error while updating person data", err)
+		logger.Info("Occured the error while updating key data", slog.Any("err", err))
 		return nil, err
 	}
 
-	return &respData, nil
+	return key, nil
 }
 
-func (s *Service) AddKey(ctx context.Context, sessionId string, keyData *integrserv.KeyData) (*integrserv.KeyData, error) {
+func (s *Service) AddKey(ctx context.Context, scope *valueobject.Scope, keyData *integrserv.KeyData) (*integrserv.KeyData, error) {
 	op := "internal/services/key/Service.AddKey"
 	logger := s.logger.With(slog.String("op", op))
 
-	err := s.accessGuardian(ctx, sessionId)
+	err := s.checkScope(ctx, scope, keyData.PersonId)
 	if err != nil {
 		return nil, err
 	}
@@ -182,43 +165,65 @@ func (s *Service) AddKey(ctx context.Context, sessionId string, keyData *integrs
 		keyData.StartDate.Location(),
 	)
 
-	type ReqData struct {
-		XMLName xml.Name
-		KeyData *integrserv.KeyData
-	}
-	reqData := ReqData{
-		XMLName: xml.Name{
-			Local: "AddKey",
-		},
-		KeyData: keyData,
+	key, err := s.orion.AddKey(ctx, keyData)
+	if err != nil {
+		logger.Info("Occured the error while additing the new key", slog.Any("err", err))
+		return nil, err
 	}
 
-	var respData integrserv.KeyData
-	respBody := &integrserv.OperationResult{
-		SoapEnvEncodingStyle: "http://schemas.xmlsoap.org/soap/encoding/",
-		XmlnsNS1:             "urn:OrionProIntf-IOrionPro",
-		XmlnsNS2:             "urn:OrionProIntf",
+	return key, nil
+}
 
-		Result: &respData,
-	}
+// scopedKeys returns the keys of the persons of the departments of the scope,
+// Orion does not filter the keys by the persons, so all the pages are taken.
+func (s *Service) scopedKeys(ctx context.Context, scope *valueobject.Scope) ([]*integrserv.KeyData, error) {
+	const pageSize = 100
 
-	err = req.PreparedReqToXMLIntegerServ(ctx, "AddKey", s.integrServAddr, reqData, respBody)
+	persons, err := s.orion.DepartmentPersons(ctx, scope.Departments())
 	if err != nil {
-		logger.Info("Occured the error while updating person data", err)
 		return nil, err
 	}
 
-	return &respData, nil
+	var result []*integrserv.KeyData
+	for offset := int64(0); ; offset += pageSize {
+		keys, err := s.orion.GetKeys(ctx, offset, pageSize)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, key := range keys {
+			if persons[key.PersonId] {
+				result = append(result, key)
+			}
+		}
+
+		if len(keys) < pageSize {
+			return result, nil
+		}
+	}
 }
 
-func (s *Service) ReadKeyCode(ctx context.Context, sessionId string, idReader int) (string, error) {
-	op := "internal/services/key/Service.ReadKeyCode"
-	logger := s.logger.With(slog.String("op", op))
+// checkScope checks that the owner of the key belongs to the departments of
+// the scope.
+func (s *Service) checkScope(ctx context.Context, scope *valueobject.Scope, personId int64) error {
+	if scope == nil {
+		return nil
+	}
 
-	err := s.accessGuardian(ctx, sessionId)
+	persons, err := s.orion.DepartmentPersons(ctx, scope.Departments())
 	if err != nil {
-		return "", err
+		return err
 	}
+	if !persons[personId] {
+		return valueobject.ErrOutOfScope
+	}
+
+	return nil
+}
+
+func (s *Service) ReadKeyCode(ctx context.Context, idReader int) (string, error) {
+	op := "internal/services/key/Service.ReadKeyCode"
+	logger := s.logger.With(slog.String("op", op))
 
 	type Reader struct {
 		AccessPointID int
@@ -251,29 +256,22 @@ func (s *Service) ReadKeyCode(ctx context.Context, sessionId string, idReader in
 
 	timeSurvey := time.Now()
 
-	filter := integrserv.EventFilter{
-		XMLName: xml.Name{
-			Local: "GetEvents",
-		},
-		BeginTime: time.Date(timeSurvey.Year(), timeSurvey.Month(), timeSurvey.Day(), timeSurvey.Hour(), timeSurvey.Minute()-5, 0, 0, timeSurvey.Location()),
-		EndTime:   timeSurvey,
-	}
-
-	events, err := s.eventService.GetEvents(ctx, &filter)
-	if err != nil {
-		logger.Info("Occured the error while reading the card", err)
-		return "", err
-	}
+	beginTime := time.Date(timeSurvey.Year(), timeSurvey.Month(), timeSurvey.Day(), timeSurvey.Hour(), timeSurvey.Minute()-5, 0, 0, timeSurvey.Location())
 
 	var keys []string
 	regExp := regexp.MustCompile(`.,  (.*) Считыватель$`)
-	for i := range events {
-		if regExp.MatchString(events[i].Description) {
-			if events[i].PassMode == selectedReader.Passmode && events[i].AccessPointId == selectedReader.AccessPointID {
-				submatches := regExp.FindStringSubmatch(events[i].Description)
+	err := s.eventService.IterateEvents(ctx, beginTime, timeSurvey, events.IterateOptions{}, func(event integrserv.Event) error {
+		if regExp.MatchString(event.Description) {
+			if event.PassMode == selectedReader.Passmode && event.AccessPointId == selectedReader.AccessPointID {
+				submatches := regExp.FindStringSubmatch(event.Description)
 				keys = append(keys, submatches[1])
 			}
 		}
+		return nil
+	})
+	if err != nil {
+		logger.Info("Occured the error while reading the card", slog.Any("err", err))
+		return "", err
 	}
 
 	if len(keys) != 0 {
@@ -283,93 +281,28 @@ func (s *Service) ReadKeyCode(ctx context.Context, sessionId string, idReader in
 	return "", fmt.Errorf("Считать ключ не удалось, попробуйте еще раз")
 }
 
-func (s *Service) ConvertWiegandToTouchMemory(ctx context.Context, sessionId string, code int, codeSize int) (string, error) {
+func (s *Service) ConvertWiegandToTouchMemory(ctx context.Context, code int, codeSize int) (string, error) {
 	op := "internal/services/key/Service.ConvertWiegandToTouchMemory"
 	logger := s.logger.With(slog.String("op", op))
 
-	err := s.accessGuardian(ctx, sessionId)
-	if err != nil {
-		return "", err
-	}
-
-	type ReqData struct {
-		XMLName  xml.Name
-		Code     int
-		CodeSize int
-	}
-	reqData := ReqData{
-		XMLName: xml.Name{
-			Local: "ConvertWiegandToTouchMemory",
-		},
-		Code:     code,
-		CodeSize: codeSize,
-	}
-
-	var respData integrserv.Result
-	respBody := &integrserv.OperationResultString{
-		SoapEnvEncodingStyle: "http://schemas.xmlsoap.org/soap/encoding/",
-		XmlnsNS1:             "urn:OrionProIntf-IOrionPro",
-		XmlnsNS2:             "urn:OrionProIntf",
-
-		Result: &respData,
-	}
-	err = req.PreparedReqToXMLIntegerServ(ctx, "ConvertWiegandToTouchMemory", s.integrServAddr, reqData, respBody)
+	result, err := s.orion.ConvertWiegandToTouchMemory(ctx, code, codeSize)
 	if err != nil {
-		logger.Info("Occured the error while updating person data", err)
+		logger.Info("Occured the error while converting the wiegand code", slog.Any("err", err))
 		return "", err
 	}
 
-	return respData.OperationResult, nil
+	return result, nil
 }
-func (s *Service) ConvertPinToTouchMemory(ctx context.Context, sessionId string, pin string) (string, error) {
+
+func (s *Service) ConvertPinToTouchMemory(ctx context.Context, pin string) (string, error) {
 	op := "internal/services/key/Service.ConvertPinToTouchMemory"
 	logger := s.logger.With(slog.String("op", op))
 
-	err := s.accessGuardian(ctx, sessionId)
+	result, err := s.orion.ConvertPinToTouchMemory(ctx, pin)
 	if err != nil {
+		logger.Info("Occured the error while converting the pin code", slog.Any("err", err))
 		return "", err
 	}
 
-	type ReqData struct {
-		XMLName xml.Name
-		Pin     string
-	}
-	reqData := ReqData{
-		XMLName: xml.Name{
-			Local: "ConvertPinToTouchMemory",
-		},
-		Pin: pin,
-	}
-
-	var respData integrserv.Result
-	respBody := &integrserv.OperationResultString{
-		SoapEnvEncodingStyle: "http://schemas.xmlsoap.org/soap/encoding/",
-		XmlnsNS1:             "urn:OrionProIntf-IOrionPro",
-		XmlnsNS2:             "urn:OrionProIntf",
-
-		Result: &respData,
-	}
-	err = req.PreparedReqToXMLIntegerServ(ctx, "ConvertPinToTouchMemory", s.integrServAddr, reqData, respBody)
-	if err != nil {
-		logger.Info("Occured the error while updating person data", err)
-		return "", err
-	}
-
-	return respData.OperationResult, nil
-}
-
-func (s *Service) accessGuardian(ctx context.Context, sessionId string) error {
-	user, err := s.sessStore.GetByID(ctx, sessionId)
-	if err != nil {
-		if errors.Is(err, cache.ErrSessionNotFound) {
-			return ErrSessionTokenInvalid
-		}
-		return err
-	}
-
-	if user.Role == valueobject.StudentRole {
-		return ErrAccessDenied
-	}
-
-	return nil
+	return result, nil
 }
@@ -2,9 +2,7 @@ package persons
 
 import (
 	"context"
-	"encoding/xml"
 	"errors"
-	"fmt"
 	"log/slog"
 	"slices"
 	"sync"
@@ -14,42 +12,51 @@ import (
 	"github.com/Izumra/SKUD_OKEI/domain/dto/resp"
 	valueobject "github.com/Izumra/SKUD_OKEI/domain/value-object"
 	"github.com/Izumra/SKUD_OKEI/internal/http/controllers"
-	"github.com/Izumra/SKUD_OKEI/internal/lib/req"
 	"github.com/Izumra/SKUD_OKEI/internal/services/auth"
+	"github.com/Izumra/SKUD_OKEI/internal/services/events"
 	"github.com/Izumra/SKUD_OKEI/internal/storage/cache"
 )
 
 var (
-	ErrSessionTokenInvalid = errors.New("сессия пользователя не действительна")
-	ErrGettingStats        = errors.New("неожиданная ошибка при загрузке статистики пользователя")
-	ErrAccessDenied        = errors.New("вам отказано в доступе")
+	ErrGettingStats = errors.New("неожиданная ошибка при загрузке статистики пользователя")
 )
 
+type OrionClient interface {
+	GetPersons(ctx context.Context, offset int64, count int64, filterParams []string) ([]*integrserv.PersonData, error)
+	GetPersonsCount(ctx context.Context) (int64, error)
+	GetPersonById(ctx context.Context, id int64) (*integrserv.PersonData, error)
+	AddPerson(ctx context.Context, data integrserv.PersonData) (*integrserv.PersonData, error)
+	UpdatePerson(ctx context.Context, data integrserv.PersonData) (*integrserv.PersonData, error)
+	DeletePerson(ctx context.Context, data integrserv.PersonData) (*integrserv.PersonData, error)
+	GetDepartments(ctx context.Context) ([]*integrserv.Department, error)
+	DepartmentPersons(ctx context.Context, departments []int64) (map[int64]bool, error)
+}
+
 type Service struct {
-	logger         *slog.Logger
-	eventsService  controllers.EventsService
-	sessStore      auth.SessionStorage
-	integrServAddr string
+	logger        *slog.Logger
+	eventsService controllers.EventsService
+	sessStore     auth.SessionStorage
+	orion         OrionClient
 }
 
 func NewService(
 	logger *slog.Logger,
 	eventsService controllers.EventsService,
 	sessStore auth.SessionStorage,
-	integrServAddr string,
+	orion OrionClient,
 ) *Service {
 	return &Service{
 		logger,
 		eventsService,
 		sessStore,
-		fmt.Sprintf("%s/soap/IOrionPro", integrServAddr),
+		orion,
 	}
 }
 
 // TODO: Figute out how to pass filter array of the params
 func (s *Service) GetPersons(
 	ctx context.Context,
-	sessionId string,
+	scope *valueobject.Scope,
 	offset int64,
 	count int64,
 	filterParams []string,
@@ -57,303 +64,238 @@ func (s *Service) GetPersons(
 	op := "internal/services/persons.Service.GetPersons"
 	logger := s.logger.With(slog.String("op", op))
 
-	err := s.accessGuardian(ctx, sessionId)
-	if err != nil {
-		return nil, err
-	}
-
-	type FilterItem struct {
-		XMLName xml.Name `xml:"Filter"`
-		Value   string   `xml:"Value"`
-	}
-	type Data struct {
-		XMLName      xml.Name
-		WithoutPhoto bool
-		Offset       int64
-		Count        int64
-		Filter       []FilterItem
-	}
+	if scope != nil {
+		persons, err := s.scopedPersons(ctx, scope, filterParams)
+		if err != nil {
+			logger.Info("Occured the error while getting the list of the users", slog.Any("err", err))
+			return nil, err
+		}
 
-	filter := make([]FilterItem, len(filterParams))
-	for i, v := range filterParams {
-		filter[i] = FilterItem{
-			Value: v,
+		if offset >= int64(len(persons)) {
+			return []*integrserv.PersonData{}, nil
 		}
+		persons = persons[offset:]
+		if count > 0 && count < int64(len(persons)) {
+			persons = persons[:count]
+		}
+		return persons, nil
 	}
-	reqData := Data{
-		WithoutPhoto: true,
-		XMLName: xml.Name{
-			Local: "GetPersons",
-		},
-		Offset: offset,
-		Count:  count,
-		Filter: filter,
-	}
-
-	var expBody []*integrserv.PersonData
-	respBody := &integrserv.OperationResultPersons{
-		SoapEnvEncodingStyle: "http://schemas.xmlsoap.org/soap/encoding/",
-		XmlnsNS1:             "urn:OrionProIntf-IOrionPro",
-		XmlnsNS2:             "urn:OrionProIntf",
 
-		Result: &expBody,
-	}
-	err = req.PreparedReqToXMLIntegerServ(ctx, "GetPersons", s.integrServAddr, reqData, respBody)
+	persons, err := s.orion.GetPersons(ctx, offset, count, filterParams)
 	if err != nil {
-		logger.Info("Occured the error while getting the list of the users", err)
+		logger.Info("Occured the error while getting the list of the users", slog.Any("err", err))
 		return nil, err
 	}
 
-	return expBody, nil
+	return persons, nil
 }
 
 func (s *Service) GetPersonsCount(
 	ctx context.Context,
-	sessionId string,
+	scope *valueobject.Scope,
 ) (int64, error) {
 	op := "internal/services/persons.Service.GetPersonsCount"
 	logger := s.logger.With(slog.String("op", op))
 
-	err := s.accessGuardian(ctx, sessionId)
-	if err != nil {
-		return -1, err
-	}
-
-	type Data struct {
-		XMLName xml.Name
-	}
-	reqData := Data{
-		XMLName: xml.Name{
-			Local: "GetPersonsCount",
-		},
-	}
-
-	type Count struct {
-		OperationResult int64
-	}
-	var resp Count
-	respBody := &integrserv.OperationResultInt{
-		SoapEnvEncodingStyle: "http://schemas.xmlsoap.org/soap/encoding/",
-		XmlnsNS1:             "urn:OrionProIntf-IOrionPro",
-		XmlnsNS2:             "urn:OrionProIntf",
-
-		Result: &resp,
+	if scope != nil {
+		persons, err := s.orion.DepartmentPersons(ctx, scope.Departments())
+		if err != nil {
+			logger.Info("Occured the error while counts the quantity of the users", slog.Any("err", err))
+			return -1, err
+		}
+		return int64(len(persons)), nil
 	}
 
-	err = req.PreparedReqToXMLIntegerServ(ctx, "GetPersonsCount", s.integrServAddr, reqData, respBody)
+	count, err := s.orion.GetPersonsCount(ctx)
 	if err != nil {
-		logger.Info("Occured the error while counts the quantity of the users", err)
+		logger.Info("Occured the error while counts the quantity of the users", slog.Any("err", err))
 		return -1, err
 	}
 
-	return resp.OperationResult, nil
+	return count, nil
 }
 
 func (s *Service) GetPersonById(
 	ctx context.Context,
-	sessionId string,
+	scope *valueobject.Scope,
 	id int64,
 ) (*integrserv.PersonData, error) {
 	op := "internal/services/persons.Service.GetPersonById"
 	logger := s.logger.With(slog.String("op", op))
 
-	err := s.accessGuardian(ctx, sessionId)
-	if err != nil {
-		return nil, err
-	}
-
-	type Data struct {
-		XMLName xml.Name
-		Id      int64
-	}
-	reqData := Data{
-		XMLName: xml.Name{
-			Local: "GetPersonById",
-		},
-		Id: id,
+	person, err := s.orion.GetPersonById(ctx, id)
+	if person != nil && !scope.Allows(person.DepartmentId) {
+		return nil, valueobject.ErrOutOfScope
 	}
-
-	var resp integrserv.PersonData
-	respBody := &integrserv.OperationResult{
-		SoapEnvEncodingStyle: "http://schemas.xmlsoap.org/soap/encoding/",
-		XmlnsNS1:             "urn:OrionProIntf-IOrionPro",
-		XmlnsNS2:             "urn:OrionProIntf",
-
-		Result: &resp,
-	}
-	err = req.PreparedReqToXMLIntegerServ(ctx, "GetPersonById", s.integrServAddr, reqData, respBody)
 	if err != nil {
-		logger.Info("Occured the error while finding the user by id", err)
+		if errors.Is(err, cache.ErrStaleData) {
+			logger.Info("The person data is served from the cache", slog.Any("err", err))
+			return person, err
+		}
+		logger.Info("Occured the error while finding the user by id", slog.Any("err", err))
 		return nil, err
 	}
 
-	return &resp, nil
+	return person, nil
 }
 
 func (s *Service) AddPerson(
 	ctx context.Context,
-	sessionId string,
+	scope *valueobject.Scope,
 	data integrserv.PersonData,
 ) (*integrserv.PersonData, error) {
 	op := "internal/services/persons.Service.AddPerson"
 	logger := s.logger.With(slog.String("op", op))
 
-	err := s.accessGuardian(ctx, sessionId)
-	if err != nil {
-		return nil, err
+	if !scope.Allows(data.DepartmentId) {
+		return nil, valueobject.ErrOutOfScope
 	}
 
 	data.Status = 5
-	type ReqData struct {
-		XMLName    xml.Name
-		PersonData integrserv.PersonData
-	}
-	reqData := ReqData{
-		XMLName: xml.Name{
-			Local: "AddPerson",
-		},
-		PersonData: data,
-	}
-
-	respBody := &integrserv.OperationResult{
-		SoapEnvEncodingStyle: "http://schemas.xmlsoap.org/soap/encoding/",
-		XmlnsNS1:             "urn:OrionProIntf-IOrionPro",
-		XmlnsNS2:             "urn:OrionProIntf",
-
-		Result: &data,
-	}
-	err = req.PreparedReqToXMLIntegerServ(ctx, "AddPerson", s.integrServAddr, reqData, respBody)
+	person, err := s.orion.AddPerson(ctx, data)
 	if err != nil {
-		logger.Info("Occured the error while additing the new person", err)
+		logger.Info("Occured the error while additing the new person", slog.Any("err", err))
 		return nil, err
 	}
 
-	return &data, nil
+	return person, nil
 }
 
+// UpdatePerson updates the person, the scoped user is not able to move the
+// person out of the departments of the scope.
 func (s *Service) UpdatePerson(
 	ctx context.Context,
-	sessionId string,
+	scope *valueobject.Scope,
 	data integrserv.PersonData,
 ) (*integrserv.PersonData, error) {
 	op := "internal/services/persons.Service.UpdatePerson"
 	logger := s.logger.With(slog.String("op", op))
 
-	err := s.accessGuardian(ctx, sessionId)
+	if !scope.Allows(data.DepartmentId) {
+		return nil, valueobject.ErrOutOfScope
+	}
+	err := s.checkScope(ctx, scope, data.Id)
 	if err != nil {
 		return nil, err
 	}
 
-	type Data struct {
-		XMLName    xml.Name
-		PersonData integrserv.PersonData
-	}
-	reqData := Data{
-		XMLName: xml.Name{
-			Local: "UpdatePerson",
-		},
-		PersonData: data,
-	}
-
-	respBody := &integrserv.OperationResult{
-		SoapEnvEncodingStyle: "http://schemas.xmlsoap.org/soap/encoding/",
-		XmlnsNS1:             "urn:OrionProIntf-IOrionPro",
-		XmlnsNS2:             "urn:OrionProIntf",
-
-		Result: &data,
-	}
-	err = req.PreparedReqToXMLIntegerServ(ctx, "UpdatePerson", s.integrServAddr, reqData, respBody)
+	person, err := s.orion.UpdatePerson(ctx, data)
 	if err != nil {
-		logger.Info("Occured the error while updating person data", err)
+		logger.Info("Occured the error while updating person data", slog.Any("err", err))
 		return nil, err
 	}
 
-	return &data, nil
+	return person, nil
 }
 
 func (s *Service) DeletePerson(
 	ctx context.Context,
-	sessionId string,
+	scope *valueobject.Scope,
 	data integrserv.PersonData,
 ) (*integrserv.PersonData, error) {
 	op := "internal/services/persons.Service.DeletePerson"
 	logger := s.logger.With(slog.String("op", op))
 
-	err := s.accessGuardian(ctx, sessionId)
+	err := s.checkScope(ctx, scope, data.Id)
 	if err != nil {
 		return nil, err
 	}
 
-	type Data struct {
-		XMLName    xml.Name
-		PersonData integrserv.PersonData
-	}
-	reqData := Data{
-		XMLName: xml.Name{
-			Local: "DeletePerson",
-		},
-		PersonData: data,
-	}
-
-	respBody := &integrserv.OperationResult{
-		SoapEnvEncodingStyle: "http://schemas.xmlsoap.org/soap/encoding/",
-		XmlnsNS1:             "urn:OrionProIntf-IOrionPro",
-		XmlnsNS2:             "urn:OrionProIntf",
-
-		Result: &data,
-	}
-	err = req.PreparedReqToXMLIntegerServ(ctx, "DeletePerson", s.integrServAddr, reqData, respBody)
+	person, err := s.orion.DeletePerson(ctx, data)
 	if err != nil {
-		logger.Info("Occured the error while deleting the person", err)
+		logger.Info("Occured the error while deleting the person", slog.Any("err", err))
 		return nil, err
 	}
 
-	return &data, nil
+	return person, nil
 }
 
 func (s *Service) GetDepartments(
 	ctx context.Context,
-	sessionId string,
+	scope *valueobject.Scope,
 ) ([]*integrserv.Department, error) {
 	op := "internal/services/persons.Service.GetDepartments"
 	logger := s.logger.With(slog.String("op", op))
 
-	err := s.accessGuardian(ctx, sessionId)
+	departments, err := s.orion.GetDepartments(ctx)
+	if scope != nil && departments != nil {
+		departments = slices.DeleteFunc(slices.Clone(departments), func(department *integrserv.Department) bool {
+			return !scope.Allows(department.Id)
+		})
+	}
 	if err != nil {
+		if errors.Is(err, cache.ErrStaleData) {
+			logger.Info("The list of the departments is served from the cache", slog.Any("err", err))
+			return departments, err
+		}
+		logger.Info("Occured the error while getting the list of the departments", slog.Any("err", err))
 		return nil, err
 	}
 
-	type Data struct {
-		XMLName xml.Name
+	return departments, nil
+}
+
+// DepartmentPersons returns the ids of the persons of the departments.
+func (s *Service) DepartmentPersons(ctx context.Context, departments []int64) (map[int64]bool, error) {
+	op := "internal/services/persons.Service.DepartmentPersons"
+	logger := s.logger.With(slog.String("op", op))
+
+	persons, err := s.orion.DepartmentPersons(ctx, departments)
+	if err != nil {
+		logger.Info("Occured the error while getting the persons of the departments", slog.Any("err", err))
+		return nil, err
 	}
-	reqData := Data{
-		XMLName: xml.Name{
-			Local: "GetDepartments",
-		},
+
+	return persons, nil
+}
+
+// scopedPersons returns the persons of the departments of the scope matching
+// the filters, Orion does not filter the persons by the departments, so all
+// the pages are taken.
+func (s *Service) scopedPersons(ctx context.Context, scope *valueobject.Scope, filterParams []string) ([]*integrserv.PersonData, error) {
+	const pageSize = 100
+
+	var result []*integrserv.PersonData
+	for offset := int64(0); ; offset += pageSize {
+		persons, err := s.orion.GetPersons(ctx, offset, pageSize, filterParams)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, person := range persons {
+			if scope.Allows(person.DepartmentId) {
+				result = append(result, person)
+			}
+		}
+
+		if len(persons) < pageSize {
+			return result, nil
+		}
 	}
+}
 
-	var departments []*integrserv.Department
-	respBody := &integrserv.OperationResultDepartments{
-		SoapEnvEncodingStyle: "http://schemas.xmlsoap.org/soap/encoding/",
-		XmlnsNS1:             "urn:OrionProIntf-IOrionPro",
-		XmlnsNS2:             "urn:OrionProIntf",
+// checkScope checks that the person belongs to the departments of the scope.
+func (s *Service) checkScope(ctx context.Context, scope *valueobject.Scope, personId int64) error {
+	if scope == nil {
+		return nil
+	}
 
-		Result: &departments,
+	person, err := s.orion.GetPersonById(ctx, personId)
+	if err != nil && !errors.Is(err, cache.ErrStaleData) {
+		return err
 	}
-	err = req.PreparedReqToXMLIntegerServ(ctx, "GetDepartments", s.integrServAddr, reqData, respBody)
-	if err != nil {
-		logger.Info("Occured the error while deleting the person", err)
-		return nil, err
+	if !scope.Allows(person.DepartmentId) {
+		return valueobject.ErrOutOfScope
 	}
 
-	return departments, nil
+	return nil
 }
 
-func (s *Service) GetDaylyUserStats(ctx context.Context, sessionId string, id int64, date time.Time) ([]*resp.Action, error) {
+func (s *Service) GetDaylyUserStats(ctx context.Context, scope *valueobject.Scope, id int64, date time.Time) ([]*resp.Action, error) {
 	op := "internal/services/persons.Service.GetDaylyUserStats"
 	logger := s.logger.With(slog.String("op", op))
 
-	err := s.accessGuardian(ctx, sessionId)
+	err := s.checkScope(ctx, scope, id)
 	if err != nil {
 		return nil, err
 	}
@@ -361,58 +303,42 @@ func (s *Service) GetDaylyUserStats(ctx context.Context, sessionId string, id in
 	beginTime := time.Date(date.Year(), date.Month(), date.Day(), 6, 0, 0, 0, date.Location())
 	endTime := time.Date(date.Year(), date.Month(), date.Day(), 23, 0, 0, 0, date.Location())
 
-	filter := integrserv.EventFilter{
-		XMLName: xml.Name{
-			Local: "GetEvents",
-		},
-		BeginTime: beginTime.Local(),
-		EndTime:   endTime.Local(),
-		Persons: integrserv.Persons{
-			PersonData: []*integrserv.PersonData{
-				{
-					Id: id,
-				},
+	opts := events.IterateOptions{
+		Persons: []*integrserv.PersonData{
+			{
+				Id: id,
 			},
 		},
 	}
-	eventsComing, err := s.eventsService.GetEvents(ctx, &filter)
-	if err != nil {
-		logger.Error("occured the error while getting the dayly stats", err)
-		return nil, err
-	}
-
-	events := map[string]bool{}
 
 	response := []*resp.Action{}
-	for i := 0; i < len(eventsComing); i++ {
-		for _, e := range eventsComing {
-			if _, ok := events[e.EventId]; ok {
-				break
-			}
-
-			if e.PassMode == 1 {
-				response = append(response, &resp.Action{
-					Time:   e.EventDate,
-					Action: "coming",
-				})
-			} else if e.PassMode == 2 {
-				response = append(response, &resp.Action{
-					Time:   e.EventDate,
-					Action: "leaving",
-				})
-			}
-			events[e.EventId] = true
+	err = s.eventsService.IterateEvents(ctx, beginTime.Local(), endTime.Local(), opts, func(e integrserv.Event) error {
+		if e.PassMode == 1 {
+			response = append(response, &resp.Action{
+				Time:   e.EventDate,
+				Action: "coming",
+			})
+		} else if e.PassMode == 2 {
+			response = append(response, &resp.Action{
+				Time:   e.EventDate,
+				Action: "leaving",
+			})
 		}
+		return nil
+	})
+	if err != nil {
+		logger.Error("occured the error while getting the dayly stats", slog.Any("err", err))
+		return nil, err
 	}
 
 	return response, nil
 }
 
-func (s *Service) GetMonthlyUserStats(ctx context.Context, sessionId string, id int64, month time.Time) ([]*resp.Activity, error) {
+func (s *Service) GetMonthlyUserStats(ctx context.Context, scope *valueobject.Scope, id int64, month time.Time) ([]*resp.Activity, error) {
 	op := "internal/services/persons.Service.GetMonthlyUserStats"
 	logger := s.logger.With(slog.String("op", op))
 
-	err := s.accessGuardian(ctx, sessionId)
+	err := s.checkScope(ctx, scope, id)
 	if err != nil {
 		return nil, err
 	}
@@ -431,35 +357,27 @@ func (s *Service) GetMonthlyUserStats(ctx context.Context, sessionId string, id
 			beginTime := time.Date(month.Year(), month.Month()+1, -day, 0, 0, 0, 0, month.Location())
 			endTime := time.Date(month.Year(), month.Month()+1, -day, 24, 0, 0, 0, month.Location())
 
-			filter := integrserv.EventFilter{
-				XMLName: xml.Name{
-					Local: "GetEvents",
-				},
-				BeginTime: beginTime,
-				EndTime:   endTime,
-				Persons: integrserv.Persons{
-					PersonData: []*integrserv.PersonData{
-						{
-							Id: id,
-						},
+			opts := events.IterateOptions{
+				Persons: []*integrserv.PersonData{
+					{
+						Id: id,
 					},
 				},
 			}
 
-			eventsComing, err := s.eventsService.GetEvents(ctx, &filter)
-			if err != nil {
-				chanErr <- err
-				return
-			}
-
 			var countComing int
 			var countLeaving int
-			for _, e := range eventsComing {
+			err := s.eventsService.IterateEvents(ctx, beginTime, endTime, opts, func(e integrserv.Event) error {
 				if e.PassMode == 1 {
 					countComing++
 				} else if e.PassMode == 2 {
 					countLeaving++
 				}
+				return nil
+			})
+			if err != nil {
+				chanErr <- err
+				return
 			}
 
 			response[day] = &resp.Activity{
@@ -477,25 +395,9 @@ func (s *Service) GetMonthlyUserStats(ctx context.Context, sessionId string, id
 	}()
 
 	if err := <-chanErr; err != nil {
-		logger.Error("Occured the error while requesting for the day stats", err)
+		logger.Error("Occured the error while requesting for the day stats", slog.Any("err", err))
 		return nil, err
 	}
 
 	return response, nil
 }
-
-func (s *Service) accessGuardian(ctx context.Context, sessionId string) error {
-	user, err := s.sessStore.GetByID(ctx, sessionId)
-	if err != nil {
-		if errors.Is(err, cache.ErrSessionNotFound) {
-			return ErrSessionTokenInvalid
-		}
-		return err
-	}
-
-	if user.Role == valueobject.StudentRole {
-		return ErrAccessDenied
-	}
-
-	return nil
-}
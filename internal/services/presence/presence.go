@@ -0,0 +1,355 @@
+package presence
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Izumra/SKUD_OKEI/domain/dto/integrserv"
+	"github.com/Izumra/SKUD_OKEI/domain/dto/resp"
+	valueobject "github.com/Izumra/SKUD_OKEI/domain/value-object"
+	"github.com/Izumra/SKUD_OKEI/internal/services/events"
+	"github.com/Izumra/SKUD_OKEI/internal/storage/cache"
+)
+
+const sweepInterval = time.Minute
+
+var (
+	ErrNotSeeded = errors.New("список присутствующих еще не загружен из 'Орион Про'")
+)
+
+type EventsSource interface {
+	IterateEvents(ctx context.Context, begin, end time.Time, opts events.IterateOptions, fn func(event integrserv.Event) error) error
+	Subscribe(name string, buffer int, filter func(event integrserv.Event) bool) *events.Subscription
+	OperationalDay(moment time.Time) (time.Time, time.Time)
+}
+
+type OrionClient interface {
+	GetPersonById(ctx context.Context, id int64) (*integrserv.PersonData, error)
+	PersonDepartments(ctx context.Context) (map[int64]int64, error)
+	GetDepartments(ctx context.Context) ([]*integrserv.Department, error)
+}
+
+// pass is the last pass of the person, the person is inside when the pass is
+// the entry.
+type pass struct {
+	event        integrserv.Event
+	departmentId int64
+}
+
+type Service struct {
+	logger      *slog.Logger
+	events      EventsSource
+	orion       OrionClient
+	expireAfter time.Duration
+
+	mu     sync.RWMutex
+	seeded bool
+	passes map[int64]pass
+	// departments is the index of the departments of the persons taken by
+	// the last seed, the persons missing from it are requested one by one.
+	departments map[int64]int64
+}
+
+func NewService(
+	logger *slog.Logger,
+	events EventsSource,
+	orion OrionClient,
+	expireAfter time.Duration,
+) *Service {
+	if expireAfter <= 0 {
+		expireAfter = 16 * time.Hour
+	}
+
+	return &Service{
+		logger:      logger,
+		events:      events,
+		orion:       orion,
+		expireAfter: expireAfter,
+		passes:      map[int64]pass{},
+	}
+}
+
+// Run seeds the register by the events of the operational day and keeps it
+// up to date by the live events until the context is done. The register is
+// seeded again when the subscription misses the events.
+func (s *Service) Run(ctx context.Context) {
+	op := "internal/services/presence.Service.Run"
+	logger := s.logger.With(slog.String("op", op))
+
+	// the subscription is made before the seed, so the events published
+	// while the register is seeding are not lost
+	sub := s.events.Subscribe("presence", 1024, nil)
+	defer sub.Close()
+
+	sweep := time.NewTicker(sweepInterval)
+	defer sweep.Stop()
+
+	s.seed(ctx)
+	dropped := sub.Dropped()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case busEvent, ok := <-sub.Events():
+			if !ok {
+				return
+			}
+
+			if current := sub.Dropped(); current != dropped {
+				dropped = current
+				logger.Info("The presence register missed the events, it is seeded again")
+				s.seed(ctx)
+				continue
+			}
+
+			s.apply(ctx, busEvent.Event)
+		case <-sweep.C:
+			s.mu.RLock()
+			seeded := s.seeded
+			s.mu.RUnlock()
+
+			if !seeded {
+				s.seed(ctx)
+			}
+			s.expire(time.Now())
+		}
+	}
+}
+
+func (s *Service) seed(ctx context.Context) error {
+	op := "internal/services/presence.Service.seed"
+	logger := s.logger.With(slog.String("op", op))
+
+	now := time.Now()
+	begin, _ := s.events.OperationalDay(now)
+
+	passes := map[int64]pass{}
+	err := s.events.IterateEvents(ctx, begin, now, events.IterateOptions{}, func(event integrserv.Event) error {
+		if isPass(event) && event.EventDate.After(passes[event.PersonId].event.EventDate) {
+			passes[event.PersonId] = pass{event: event}
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Info("Occured the error while seeding the presence register", slog.Any("err", err))
+		return err
+	}
+
+	departments, err := s.orion.PersonDepartments(ctx)
+	if err != nil && !errors.Is(err, cache.ErrStaleData) {
+		logger.Info("Occured the error while taking the departments of the persons", slog.Any("err", err))
+
+		s.mu.RLock()
+		departments = s.departments
+		s.mu.RUnlock()
+	}
+
+	for id, last := range passes {
+		if last.event.PassMode == 1 {
+			last.departmentId = departments[id]
+			passes[id] = last
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// the live events applied while seeding are newer than the seed
+	for id, last := range s.passes {
+		if last.event.EventDate.After(passes[id].event.EventDate) {
+			passes[id] = last
+		}
+	}
+	s.passes = passes
+	s.departments = departments
+	s.seeded = true
+
+	return nil
+}
+
+func (s *Service) apply(ctx context.Context, event integrserv.Event) {
+	if !isPass(event) {
+		return
+	}
+
+	s.mu.RLock()
+	last, ok := s.passes[event.PersonId]
+	departmentId, indexed := s.departments[event.PersonId]
+	s.mu.RUnlock()
+	if ok && !event.EventDate.After(last.event.EventDate) {
+		return
+	}
+
+	// the department of the person missing from the index is taken out of
+	// the lock, it is requested from Orion
+	current := pass{event: event}
+	if event.PassMode == 1 {
+		if !indexed {
+			departmentId = s.department(ctx, event.PersonId)
+		}
+		current.departmentId = departmentId
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if last, ok := s.passes[event.PersonId]; !ok || event.EventDate.After(last.event.EventDate) {
+		s.passes[event.PersonId] = current
+	}
+}
+
+// expire removes the persons entered before the cutoff, they are supposed to
+// leave the building without the badge.
+func (s *Service) expire(now time.Time) {
+	cutoff := now.Add(-s.expireAfter)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, last := range s.passes {
+		if last.event.EventDate.Before(cutoff) {
+			delete(s.passes, id)
+		}
+	}
+}
+
+func (s *Service) department(ctx context.Context, personId int64) int64 {
+	op := "internal/services/presence.Service.department"
+	logger := s.logger.With(slog.String("op", op))
+
+	person, err := s.orion.GetPersonById(ctx, personId)
+	if err != nil && !errors.Is(err, cache.ErrStaleData) {
+		logger.Info("Occured the error while taking the department of the person", slog.Int64("person", personId), slog.Any("err", err))
+		return 0
+	}
+	if person == nil {
+		return 0
+	}
+
+	return person.DepartmentId
+}
+
+// Inside returns the persons inside the building, the persons of the other
+// departments are skipped when the department is not zero. It fails while the
+// register is not seeded.
+func (s *Service) Inside(ctx context.Context, scope *valueobject.Scope, department int64) (*resp.Presence, error) {
+	persons, err := s.inside(scope, department)
+	if err != nil {
+		return nil, err
+	}
+
+	return &resp.Presence{
+		Count:   len(persons),
+		Persons: persons,
+	}, nil
+}
+
+// Snapshot returns all the persons inside the building for the other
+// services, it fails while the register is not seeded, because the empty list
+// would be wrong.
+func (s *Service) Snapshot() ([]resp.PresentPerson, error) {
+	return s.inside(nil, 0)
+}
+
+// inside fails while the register is not seeded, because the empty list would
+// be wrong.
+func (s *Service) inside(scope *valueobject.Scope, department int64) ([]resp.PresentPerson, error) {
+	cutoff := time.Now().Add(-s.expireAfter)
+	persons := []resp.PresentPerson{}
+
+	s.mu.RLock()
+	if !s.seeded {
+		s.mu.RUnlock()
+		return nil, ErrNotSeeded
+	}
+	for id, last := range s.passes {
+		if last.event.PassMode != 1 || last.event.EventDate.Before(cutoff) {
+			continue
+		}
+		if department != 0 && last.departmentId != department {
+			continue
+		}
+		if !scope.Allows(last.departmentId) {
+			continue
+		}
+
+		persons = append(persons, resp.PresentPerson{
+			PersonId:      id,
+			LastName:      last.event.LastName,
+			FirstName:     last.event.FirstName,
+			MiddleName:    last.event.MiddleName,
+			TabNum:        last.event.TabNum,
+			DepartmentId:  last.departmentId,
+			EnteredAt:     last.event.EventDate,
+			AccessPointId: last.event.AccessPointId,
+		})
+	}
+	s.mu.RUnlock()
+
+	slices.SortFunc(persons, func(a, b resp.PresentPerson) int {
+		if c := strings.Compare(a.LastName, b.LastName); c != 0 {
+			return c
+		}
+		if c := strings.Compare(a.FirstName, b.FirstName); c != 0 {
+			return c
+		}
+		return a.EnteredAt.Compare(b.EnteredAt)
+	})
+
+	return persons, nil
+}
+
+// Departments returns the count of the persons inside the building by the
+// departments, the persons of the unknown department are counted with zero id.
+// It fails while the register is not seeded.
+func (s *Service) Departments(ctx context.Context, scope *valueobject.Scope) ([]resp.PresenceDepartment, error) {
+	op := "internal/services/presence.Service.Departments"
+	logger := s.logger.With(slog.String("op", op))
+
+	cutoff := time.Now().Add(-s.expireAfter)
+	counts := map[int64]int{}
+
+	s.mu.RLock()
+	if !s.seeded {
+		s.mu.RUnlock()
+		return nil, ErrNotSeeded
+	}
+	for _, last := range s.passes {
+		if last.event.PassMode == 1 && !last.event.EventDate.Before(cutoff) && scope.Allows(last.departmentId) {
+			counts[last.departmentId]++
+		}
+	}
+	s.mu.RUnlock()
+
+	names := map[int64]string{}
+	departments, err := s.orion.GetDepartments(ctx)
+	if err != nil && !errors.Is(err, cache.ErrStaleData) {
+		logger.Info("Occured the error while getting the names of the departments", slog.Any("err", err))
+	}
+	for _, department := range departments {
+		names[department.Id] = department.Name
+	}
+
+	result := make([]resp.PresenceDepartment, 0, len(counts))
+	for id, count := range counts {
+		result = append(result, resp.PresenceDepartment{
+			DepartmentId: id,
+			Name:         names[id],
+			Count:        count,
+		})
+	}
+	slices.SortFunc(result, func(a, b resp.PresenceDepartment) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return result, nil
+}
+
+func isPass(event integrserv.Event) bool {
+	return event.PersonId != 0 && (event.PassMode == 1 || event.PassMode == 2)
+}
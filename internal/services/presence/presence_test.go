@@ -0,0 +1,288 @@
+package presence
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Izumra/SKUD_OKEI/domain/dto/integrserv"
+	"github.com/Izumra/SKUD_OKEI/domain/dto/resp"
+	"github.com/Izumra/SKUD_OKEI/domain/entity"
+	valueobject "github.com/Izumra/SKUD_OKEI/domain/value-object"
+	"github.com/Izumra/SKUD_OKEI/internal/lib/req"
+	"github.com/Izumra/SKUD_OKEI/internal/orion"
+	"github.com/Izumra/SKUD_OKEI/internal/orion/fake"
+	"github.com/Izumra/SKUD_OKEI/internal/services/events"
+	"github.com/Izumra/SKUD_OKEI/internal/storage"
+)
+
+// emptyArchive is the archive which is not synced yet, so the events are
+// taken from IntegrServ.
+type emptyArchive struct {
+	events.Archive
+}
+
+func (emptyArchive) ArchiveRange(ctx context.Context) (*entity.ArchiveRange, error) {
+	return nil, storage.ErrArchiveEmpty
+}
+
+// newService serves the fake IntegrServ with the passes to the register, the
+// operational day begins an hour ago and the entries expire in 25 minutes.
+func newService(t *testing.T, passes []integrserv.Event) (*Service, *fake.Dataset) {
+	t.Helper()
+
+	// the generated events are in the past, the passes are the only events
+	// of the operational day
+	dataset := fake.Generate(fake.DatasetConfig{
+		Seed:    1,
+		Persons: 5,
+		Days:    1,
+		Now:     time.Date(2020, 1, 1, 18, 0, 0, 0, time.Local),
+	})
+	server := fake.NewServer(dataset, 1)
+	for _, pass := range passes {
+		server.PushEvent(pass)
+	}
+
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+
+	client := orion.NewClient(httpServer.URL, req.NewTransport(req.TransportConfig{
+		Timeout:      time.Second,
+		RetryBackoff: time.Millisecond,
+	}))
+
+	now := time.Now()
+	year, month, day := now.Date()
+	dayStart := now.Sub(time.Date(year, month, day, 0, 0, 0, 0, now.Location())) - time.Hour
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	eventsService := events.NewService(logger, client, nil, emptyArchive{}, events.NewBus(100), dayStart)
+
+	return NewService(logger, eventsService, client, 25*time.Minute), dataset
+}
+
+func passOf(personId int64, passMode int, ago time.Duration) integrserv.Event {
+	return integrserv.Event{
+		PersonId:  personId,
+		PassMode:  passMode,
+		EventDate: time.Now().Add(-ago),
+	}
+}
+
+func personIds(persons []resp.PresentPerson) []int64 {
+	ids := make([]int64, 0, len(persons))
+	for _, person := range persons {
+		ids = append(ids, person.PersonId)
+	}
+	slices.Sort(ids)
+
+	return ids
+}
+
+func TestInside(t *testing.T) {
+	s, dataset := newService(t, []integrserv.Event{
+		// inside
+		passOf(1, 1, 10*time.Minute),
+		// left
+		passOf(2, 1, 20*time.Minute),
+		passOf(2, 2, 10*time.Minute),
+		// entered before the operational day
+		passOf(3, 1, 2*time.Hour),
+		// expired
+		passOf(4, 1, 40*time.Minute),
+		// entered after leaving
+		passOf(5, 2, 20*time.Minute),
+		passOf(5, 1, 5*time.Minute),
+	})
+	ctx := context.Background()
+
+	_, err := s.Inside(ctx, nil, 0)
+	if !errors.Is(err, ErrNotSeeded) {
+		t.Fatalf("Inside() before the seed err = %v, want %v", err, ErrNotSeeded)
+	}
+	_, err = s.Departments(ctx, nil)
+	if !errors.Is(err, ErrNotSeeded) {
+		t.Fatalf("Departments() before the seed err = %v, want %v", err, ErrNotSeeded)
+	}
+
+	err = s.seed(ctx)
+	if err != nil {
+		t.Fatalf("seed() err = %v", err)
+	}
+
+	department := dataset.Persons[0].DepartmentId
+	tests := []struct {
+		name       string
+		scope      *valueobject.Scope
+		department int64
+		want       []int64
+	}{
+		{
+			name: "all the persons",
+			want: []int64{1, 5},
+		},
+		{
+			name:       "persons of the department",
+			department: department,
+			want:       personsOf(dataset, department, 1, 5),
+		},
+		{
+			name:       "persons of the unknown department",
+			department: 99,
+			want:       []int64{},
+		},
+		{
+			name:  "persons of the scope",
+			scope: valueobject.NewScope([]int64{department}),
+			want:  personsOf(dataset, department, 1, 5),
+		},
+		{
+			name:  "persons of the empty scope",
+			scope: valueobject.NewScope(nil),
+			want:  []int64{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			presence, err := s.Inside(ctx, tt.scope, tt.department)
+			if err != nil {
+				t.Fatalf("Inside() err = %v", err)
+			}
+			if got := personIds(presence.Persons); !slices.Equal(got, tt.want) || presence.Count != len(tt.want) {
+				t.Errorf("Inside() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// personsOf returns the persons of the department among the ids.
+func personsOf(dataset *fake.Dataset, department int64, ids ...int64) []int64 {
+	result := []int64{}
+	for _, id := range ids {
+		if dataset.Persons[id-1].DepartmentId == department {
+			result = append(result, id)
+		}
+	}
+
+	return result
+}
+
+func TestApply(t *testing.T) {
+	s, dataset := newService(t, []integrserv.Event{
+		passOf(1, 1, 10*time.Minute),
+	})
+	ctx := context.Background()
+
+	err := s.seed(ctx)
+	if err != nil {
+		t.Fatalf("seed() err = %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		event integrserv.Event
+		want  []int64
+	}{
+		{
+			name:  "the entry adds the person",
+			event: passOf(2, 1, time.Minute),
+			want:  []int64{1, 2},
+		},
+		{
+			name:  "the older exit is skipped",
+			event: passOf(1, 2, 20*time.Minute),
+			want:  []int64{1, 2},
+		},
+		{
+			name:  "the exit removes the person",
+			event: passOf(1, 2, 0),
+			want:  []int64{2},
+		},
+		{
+			name:  "the event without the person is skipped",
+			event: passOf(0, 1, 0),
+			want:  []int64{2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s.apply(ctx, tt.event)
+
+			presence, err := s.Inside(ctx, nil, 0)
+			if err != nil {
+				t.Fatalf("Inside() err = %v", err)
+			}
+			if got := personIds(presence.Persons); !slices.Equal(got, tt.want) {
+				t.Errorf("Inside() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	departments, err := s.Departments(ctx, nil)
+	if err != nil {
+		t.Fatalf("Departments() err = %v", err)
+	}
+	want := []resp.PresenceDepartment{{
+		DepartmentId: dataset.Persons[1].DepartmentId,
+		Name:         dataset.Departments[dataset.Persons[1].DepartmentId-1].Name,
+		Count:        1,
+	}}
+	if !slices.Equal(departments, want) {
+		t.Errorf("Departments() = %+v, want %+v", departments, want)
+	}
+}
+
+// countingClient counts the persons requested one by one.
+type countingClient struct {
+	OrionClient
+	requested []int64
+}
+
+func (c *countingClient) GetPersonById(ctx context.Context, id int64) (*integrserv.PersonData, error) {
+	c.requested = append(c.requested, id)
+	return c.OrionClient.GetPersonById(ctx, id)
+}
+
+func TestDepartmentsIndex(t *testing.T) {
+	s, dataset := newService(t, []integrserv.Event{
+		passOf(1, 1, 10*time.Minute),
+		passOf(2, 1, 10*time.Minute),
+		passOf(3, 1, 10*time.Minute),
+	})
+	client := &countingClient{OrionClient: s.orion}
+	s.orion = client
+	ctx := context.Background()
+
+	err := s.seed(ctx)
+	if err != nil {
+		t.Fatalf("seed() err = %v", err)
+	}
+
+	// the persons known to the index are not requested, the person added to
+	// Orion after the seed is
+	s.apply(ctx, passOf(4, 1, time.Minute))
+	dataset.Persons = append(dataset.Persons, &integrserv.PersonData{Id: 6, DepartmentId: dataset.Persons[0].DepartmentId})
+	s.apply(ctx, passOf(6, 1, time.Minute))
+
+	if !slices.Equal(client.requested, []int64{6}) {
+		t.Errorf("requested persons = %v, want %v", client.requested, []int64{6})
+	}
+
+	presence, err := s.Inside(ctx, nil, 0)
+	if err != nil {
+		t.Fatalf("Inside() err = %v", err)
+	}
+	for _, person := range presence.Persons {
+		if want := dataset.Persons[person.PersonId-1].DepartmentId; person.DepartmentId != want {
+			t.Errorf("department of the person %d = %d, want %d", person.PersonId, person.DepartmentId, want)
+		}
+	}
+}
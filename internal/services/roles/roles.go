@@ -0,0 +1,247 @@
+package roles
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/Izumra/SKUD_OKEI/domain/dto/resp"
+	"github.com/Izumra/SKUD_OKEI/domain/entity"
+	valueobject "github.com/Izumra/SKUD_OKEI/domain/value-object"
+)
+
+var (
+	ErrRoleName          = errors.New("название роли не может быть пустым")
+	ErrUnknownPermission = errors.New("неизвестное разрешение")
+	ErrRoleProtected     = errors.New("роль администратора не может быть изменена или удалена")
+)
+
+type Storage interface {
+	Roles(ctx context.Context) ([]entity.Role, error)
+	AddRole(ctx context.Context, role entity.Role) (valueobject.Role, error)
+	UpdateRole(ctx context.Context, role entity.Role) error
+	DeleteRole(ctx context.Context, id valueobject.Role) error
+	SetUserRole(ctx context.Context, userId int64, roleId valueobject.Role) error
+	SetUserDepartments(ctx context.Context, userId int64, departments []int64) error
+}
+
+// Service keeps the permissions of the roles in the memory, so the check of
+// the request does not query the database. The permissions are loaded again
+// after every change of the roles.
+type Service struct {
+	logger  *slog.Logger
+	storage Storage
+
+	mu          sync.RWMutex
+	permissions map[valueobject.Role]map[valueobject.Permission]bool
+}
+
+func NewService(
+	logger *slog.Logger,
+	storage Storage,
+) *Service {
+	return &Service{
+		logger:      logger,
+		storage:     storage,
+		permissions: map[valueobject.Role]map[valueobject.Permission]bool{},
+	}
+}
+
+// Load loads the permissions of the roles from the storage.
+func (s *Service) Load(ctx context.Context) error {
+	op := "internal/services/roles.Service.Load"
+	logger := s.logger.With(slog.String("op", op))
+
+	roles, err := s.storage.Roles(ctx)
+	if err != nil {
+		logger.Info("Occured the error while loading the roles", slog.Any("err", err))
+		return err
+	}
+
+	permissions := make(map[valueobject.Role]map[valueobject.Permission]bool, len(roles))
+	for _, role := range roles {
+		set := make(map[valueobject.Permission]bool, len(role.Permissions))
+		for _, permission := range role.Permissions {
+			set[permission] = true
+		}
+		permissions[role.Id] = set
+	}
+
+	s.mu.Lock()
+	s.permissions = permissions
+	s.mu.Unlock()
+
+	return nil
+}
+
+// Allowed reports whether the role has the permission.
+func (s *Service) Allowed(role valueobject.Role, permission valueobject.Permission) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.permissions[role][permission]
+}
+
+func (s *Service) Permissions() []string {
+	result := make([]string, 0, len(valueobject.Permissions))
+	for _, permission := range valueobject.Permissions {
+		result = append(result, string(permission))
+	}
+	return result
+}
+
+func (s *Service) Roles(ctx context.Context) ([]resp.Role, error) {
+	op := "internal/services/roles.Service.Roles"
+	logger := s.logger.With(slog.String("op", op))
+
+	roles, err := s.storage.Roles(ctx)
+	if err != nil {
+		logger.Info("Occured the error while taking the roles", slog.Any("err", err))
+		return nil, err
+	}
+
+	result := make([]resp.Role, 0, len(roles))
+	for _, role := range roles {
+		result = append(result, view(role))
+	}
+	return result, nil
+}
+
+func (s *Service) AddRole(ctx context.Context, name string, permissions []string) (*resp.Role, error) {
+	op := "internal/services/roles.Service.AddRole"
+	logger := s.logger.With(slog.String("op", op))
+
+	role, err := newRole(name, permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	role.Id, err = s.storage.AddRole(ctx, role)
+	if err != nil {
+		logger.Info("Occured the error while adding the role", slog.Any("err", err))
+		return nil, err
+	}
+	s.reload(ctx)
+
+	result := view(role)
+	return &result, nil
+}
+
+func (s *Service) UpdateRole(ctx context.Context, id int64, name string, permissions []string) (*resp.Role, error) {
+	op := "internal/services/roles.Service.UpdateRole"
+	logger := s.logger.With(slog.String("op", op))
+
+	if valueobject.Role(id) == valueobject.AdminRole {
+		return nil, ErrRoleProtected
+	}
+
+	role, err := newRole(name, permissions)
+	if err != nil {
+		return nil, err
+	}
+	role.Id = valueobject.Role(id)
+
+	err = s.storage.UpdateRole(ctx, role)
+	if err != nil {
+		logger.Info("Occured the error while updating the role", slog.Any("err", err))
+		return nil, err
+	}
+	s.reload(ctx)
+
+	result := view(role)
+	return &result, nil
+}
+
+func (s *Service) DeleteRole(ctx context.Context, id int64) error {
+	op := "internal/services/roles.Service.DeleteRole"
+	logger := s.logger.With(slog.String("op", op))
+
+	if valueobject.Role(id) == valueobject.AdminRole {
+		return ErrRoleProtected
+	}
+
+	err := s.storage.DeleteRole(ctx, valueobject.Role(id))
+	if err != nil {
+		logger.Info("Occured the error while deleting the role", slog.Any("err", err))
+		return err
+	}
+	s.reload(ctx)
+
+	return nil
+}
+
+// SetUserRole assigns the role to the user, the role is applied to the
+// sessions opened before too.
+func (s *Service) SetUserRole(ctx context.Context, userId int64, roleId int64) error {
+	op := "internal/services/roles.Service.SetUserRole"
+	logger := s.logger.With(slog.String("op", op))
+
+	err := s.storage.SetUserRole(ctx, userId, valueobject.Role(roleId))
+	if err != nil {
+		logger.Info("Occured the error while assigning the role to the user", slog.Any("err", err))
+		return err
+	}
+
+	return nil
+}
+
+// SetUserDepartments limits the data available to the user by the persons of
+// the departments, the user with the empty list sees none of them unless the
+// role has the access to all the departments. The departments are applied to
+// the sessions opened before too.
+func (s *Service) SetUserDepartments(ctx context.Context, userId int64, departments []int64) error {
+	op := "internal/services/roles.Service.SetUserDepartments"
+	logger := s.logger.With(slog.String("op", op))
+
+	err := s.storage.SetUserDepartments(ctx, userId, departments)
+	if err != nil {
+		logger.Info("Occured the error while assigning the departments to the user", slog.Any("err", err))
+		return err
+	}
+
+	return nil
+}
+
+// reload loads the changed permissions, the failure keeps the previous ones
+// and is only logged, because the change itself is already saved.
+func (s *Service) reload(ctx context.Context) {
+	_ = s.Load(ctx)
+}
+
+func newRole(name string, permissions []string) (entity.Role, error) {
+	role := entity.Role{
+		Name: strings.TrimSpace(name),
+	}
+	if role.Name == "" {
+		return role, ErrRoleName
+	}
+
+	for _, name := range permissions {
+		permission := valueobject.Permission(name)
+		if !slices.Contains(valueobject.Permissions, permission) {
+			return role, fmt.Errorf("%w: %s", ErrUnknownPermission, name)
+		}
+		if !slices.Contains(role.Permissions, permission) {
+			role.Permissions = append(role.Permissions, permission)
+		}
+	}
+
+	return role, nil
+}
+
+func view(role entity.Role) resp.Role {
+	result := resp.Role{
+		Id:          int64(role.Id),
+		Name:        role.Name,
+		Permissions: make([]string, 0, len(role.Permissions)),
+		Protected:   role.Id == valueobject.AdminRole,
+	}
+	for _, permission := range role.Permissions {
+		result.Permissions = append(result.Permissions, string(permission))
+	}
+	return result
+}
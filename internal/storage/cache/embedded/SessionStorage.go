@@ -2,20 +2,44 @@ package embedded
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/Izumra/SKUD_OKEI/domain/entity"
+	"github.com/Izumra/SKUD_OKEI/internal/storage"
 	"github.com/Izumra/SKUD_OKEI/internal/storage/cache"
 	"github.com/google/uuid"
 )
 
+// UserSource returns the current data of the user of the session.
+type UserSource interface {
+	UserByID(ctx context.Context, id int64) (*entity.User, error)
+}
+
+type session struct {
+	user       *entity.User
+	createdAt  time.Time
+	lastSeenAt time.Time
+}
+
+// SessionStorage keeps the sessions in the memory, they are lost on the
+// restart of the server. The role and the departments of the user are read
+// from the source, so their changes apply to the opened sessions.
 type SessionStorage struct {
-	storage map[string]*entity.User
+	ttl   cache.SessionTTL
+	users UserSource
+
+	mu      sync.Mutex
+	storage map[string]*session
 }
 
-func NewSessStore() *SessionStorage {
+func NewSessStore(ttl cache.SessionTTL, users UserSource) *SessionStorage {
 	return &SessionStorage{
-		storage: make(map[string]*entity.User),
+		ttl:     ttl.WithDefaults(),
+		users:   users,
+		storage: make(map[string]*session),
 	}
 }
 
@@ -27,19 +51,53 @@ func (ss *SessionStorage) Create(ctx context.Context, data *entity.User) (sessio
 		return "", fmt.Errorf("%s:%w", op, err)
 	}
 
+	now := time.Now()
 	sessionId = randID.String()
-	ss.storage[sessionId] = data
+
+	ss.mu.Lock()
+	ss.storage[sessionId] = &session{
+		user:       data,
+		createdAt:  now,
+		lastSeenAt: now,
+	}
+	ss.mu.Unlock()
 
 	return sessionId, nil
 }
 
+// GetByID returns the current user of the session and renews the idle TTL of
+// it, the expired session and the session of the deleted user are removed.
 func (ss *SessionStorage) GetByID(ctx context.Context, sessionId string) (*entity.User, error) {
 	op := "storage/cache/embedded/SessionStorage.GetByID"
 
-	user, ok := ss.storage[sessionId]
+	ss.mu.Lock()
+	sess, ok := ss.storage[sessionId]
 	if !ok {
+		ss.mu.Unlock()
+		return nil, fmt.Errorf("%s: %w", op, cache.ErrSessionNotFound)
+	}
+
+	now := time.Now()
+	if ss.ttl.Expired(sess.createdAt, sess.lastSeenAt, now) {
+		delete(ss.storage, sessionId)
+		ss.mu.Unlock()
 		return nil, fmt.Errorf("%s: %w", op, cache.ErrSessionNotFound)
 	}
+	sess.lastSeenAt = now
+	userId := sess.user.Id
+	ss.mu.Unlock()
+
+	user, err := ss.users.UserByID(ctx, userId)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			ss.mu.Lock()
+			delete(ss.storage, sessionId)
+			ss.mu.Unlock()
+			return nil, fmt.Errorf("%s: %w", op, cache.ErrSessionNotFound)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	user.Password = ""
 
 	return user, nil
 }
@@ -47,6 +105,9 @@ func (ss *SessionStorage) GetByID(ctx context.Context, sessionId string) (*entit
 func (ss *SessionStorage) DeleteByID(ctx context.Context, sessionId string) error {
 	op := "storage/cache/embedded/SessionStorage.DeleteByID"
 
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
 	_, ok := ss.storage[sessionId]
 	if !ok {
 		return fmt.Errorf("%s: %w", op, cache.ErrSessionNotFound)
@@ -60,12 +121,31 @@ func (ss *SessionStorage) DeleteByID(ctx context.Context, sessionId string) erro
 func (ss *SessionStorage) UpdateByID(ctx context.Context, sessionId string, updatedData *entity.User) error {
 	op := "storage/cache/embedded/SessionStorage.UpdateByID"
 
-	_, ok := ss.storage[sessionId]
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	sess, ok := ss.storage[sessionId]
 	if !ok {
 		return fmt.Errorf("%s: %w", op, cache.ErrSessionNotFound)
 	}
 
-	ss.storage[sessionId] = updatedData
+	sess.user = updatedData
 
 	return nil
 }
+
+// Sweep removes the sessions expired at the moment and returns their count.
+func (ss *SessionStorage) Sweep(ctx context.Context, now time.Time) (int64, error) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	var removed int64
+	for sessionId, sess := range ss.storage {
+		if ss.ttl.Expired(sess.createdAt, sess.lastSeenAt, now) {
+			delete(ss.storage, sessionId)
+			removed++
+		}
+	}
+
+	return removed, nil
+}
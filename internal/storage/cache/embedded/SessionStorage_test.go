@@ -0,0 +1,161 @@
+package embedded
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Izumra/SKUD_OKEI/domain/entity"
+	valueobject "github.com/Izumra/SKUD_OKEI/domain/value-object"
+	"github.com/Izumra/SKUD_OKEI/internal/storage"
+	"github.com/Izumra/SKUD_OKEI/internal/storage/cache"
+)
+
+// users returns the current data of the users by their ids.
+type users map[int64]entity.User
+
+func (u users) UserByID(ctx context.Context, id int64) (*entity.User, error) {
+	user, ok := u[id]
+	if !ok {
+		return nil, storage.ErrUserNotFound
+	}
+	return &user, nil
+}
+
+func TestSessionStorageGetByID(t *testing.T) {
+	ttl := cache.SessionTTL{
+		Absolute: 8 * time.Hour,
+		Idle:     time.Hour,
+	}
+	login := entity.User{Id: 2, Username: "vova", Role: valueobject.StudentRole}
+
+	tests := []struct {
+		name       string
+		createdAgo time.Duration
+		seenAgo    time.Duration
+		current    users
+		want       *entity.User
+		wantErr    error
+	}{
+		{
+			name:    "fresh",
+			current: users{2: login},
+			want:    &login,
+		},
+		{
+			name:       "used within the idle TTL",
+			createdAgo: 7 * time.Hour,
+			seenAgo:    59 * time.Minute,
+			current:    users{2: login},
+			want:       &login,
+		},
+		{
+			name:       "idle",
+			createdAgo: 2 * time.Hour,
+			seenAgo:    time.Hour,
+			current:    users{2: login},
+			wantErr:    cache.ErrSessionNotFound,
+		},
+		{
+			name:       "older than the absolute TTL",
+			createdAgo: 8 * time.Hour,
+			seenAgo:    time.Minute,
+			current:    users{2: login},
+			wantErr:    cache.ErrSessionNotFound,
+		},
+		{
+			name: "changed user",
+			current: users{2: {
+				Id:          2,
+				Username:    "vova",
+				Password:    "hash",
+				Role:        valueobject.Role(1),
+				Departments: []int64{1},
+			}},
+			want: &entity.User{
+				Id:          2,
+				Username:    "vova",
+				Role:        valueobject.Role(1),
+				Departments: []int64{1},
+			},
+		},
+		{
+			name:    "deleted user",
+			current: users{},
+			wantErr: cache.ErrSessionNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ss := NewSessStore(ttl, tt.current)
+			ctx := context.Background()
+
+			user := login
+			sessionId, err := ss.Create(ctx, &user)
+			if err != nil {
+				t.Fatalf("Create() err = %v", err)
+			}
+			now := time.Now()
+			ss.storage[sessionId].createdAt = now.Add(-tt.createdAgo)
+			ss.storage[sessionId].lastSeenAt = now.Add(-tt.seenAgo)
+
+			got, err := ss.GetByID(ctx, sessionId)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("GetByID() err = %v, want %v", err, tt.wantErr)
+			}
+
+			// the expired session and the session of the deleted user are
+			// removed, the used one is renewed
+			sess, ok := ss.storage[sessionId]
+			if tt.wantErr != nil {
+				if ok {
+					t.Errorf("the session was not removed")
+				}
+				return
+			}
+			if !ok || now.After(sess.lastSeenAt) {
+				t.Errorf("the session was not renewed")
+			}
+			if got.Id != tt.want.Id || got.Role != tt.want.Role || got.Password != "" || len(got.Departments) != len(tt.want.Departments) {
+				t.Errorf("GetByID() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSessionStorageSweep(t *testing.T) {
+	ss := NewSessStore(cache.SessionTTL{
+		Absolute: 8 * time.Hour,
+		Idle:     time.Hour,
+	}, users{})
+	ctx := context.Background()
+
+	now := time.Now()
+	ages := []struct {
+		createdAgo time.Duration
+		seenAgo    time.Duration
+	}{
+		{0, 0},
+		{7 * time.Hour, 30 * time.Minute},
+		{2 * time.Hour, 2 * time.Hour},
+		{9 * time.Hour, time.Minute},
+	}
+	for _, age := range ages {
+		sessionId, err := ss.Create(ctx, &entity.User{Id: 2})
+		if err != nil {
+			t.Fatalf("Create() err = %v", err)
+		}
+		ss.storage[sessionId].createdAt = now.Add(-age.createdAgo)
+		ss.storage[sessionId].lastSeenAt = now.Add(-age.seenAgo)
+	}
+
+	removed, err := ss.Sweep(ctx, now)
+	if err != nil {
+		t.Fatalf("Sweep() err = %v", err)
+	}
+	if removed != 2 || len(ss.storage) != 2 {
+		t.Errorf("Sweep() = %d, %d left, want 2, 2 left", removed, len(ss.storage))
+	}
+}
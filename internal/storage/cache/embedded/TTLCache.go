@@ -0,0 +1,178 @@
+package embedded
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Izumra/SKUD_OKEI/internal/storage/cache"
+)
+
+type ttlEntry[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+// ttlLoad is the loading of the key shared by all the callers missed it.
+type ttlLoad[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// TTLCache is the read-through cache, the expired entries are kept to be
+// served when the source is unavailable.
+type TTLCache[K comparable, V any] struct {
+	name        string
+	ttl         time.Duration
+	unavailable func(err error) bool
+	clone       func(value V) V
+
+	mu      sync.RWMutex
+	entries map[K]ttlEntry[V]
+	// loads are the loadings in progress, the invalidated key loses its
+	// loading, so the value loaded before the invalidation is not stored.
+	loads map[K]*ttlLoad[V]
+
+	hits   atomic.Int64
+	misses atomic.Int64
+	stale  atomic.Int64
+}
+
+// NewTTLCache creates the cache, unavailable reports whether the error of the
+// loading allows to serve the expired entry. The values are passed to the
+// callers through clone, so the cached ones could not be changed by them.
+func NewTTLCache[K comparable, V any](name string, ttl time.Duration, unavailable func(err error) bool, clone func(value V) V) *TTLCache[K, V] {
+	if clone == nil {
+		clone = func(value V) V { return value }
+	}
+
+	return &TTLCache[K, V]{
+		name:        name,
+		ttl:         ttl,
+		unavailable: unavailable,
+		clone:       clone,
+		entries:     make(map[K]ttlEntry[V]),
+		loads:       make(map[K]*ttlLoad[V]),
+	}
+}
+
+// Get returns the cached value of the key or loads it, the concurrent misses
+// of the key wait for the single loading. The loading outlives the caller
+// started it, so the other callers are not failed by its cancellation. The
+// expired value is returned with the error wrapping cache.ErrStaleData when the
+// loading failed because the source is unavailable.
+func (tc *TTLCache[K, V]) Get(ctx context.Context, key K, load func(ctx context.Context) (V, error)) (V, error) {
+	tc.mu.RLock()
+	entry, ok := tc.entries[key]
+	tc.mu.RUnlock()
+
+	if ok && time.Now().Before(entry.expiresAt) {
+		tc.hits.Add(1)
+		return tc.clone(entry.value), nil
+	}
+	tc.misses.Add(1)
+
+	tc.mu.Lock()
+	l, loading := tc.loads[key]
+	if !loading {
+		l = &ttlLoad[V]{
+			done: make(chan struct{}),
+		}
+		tc.loads[key] = l
+	}
+	tc.mu.Unlock()
+
+	if !loading {
+		go tc.load(context.WithoutCancel(ctx), key, l, load)
+	}
+
+	select {
+	case <-l.done:
+		return tc.clone(l.value), l.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+func (tc *TTLCache[K, V]) load(ctx context.Context, key K, l *ttlLoad[V], load func(ctx context.Context) (V, error)) {
+	defer close(l.done)
+
+	value, err := load(ctx)
+
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	current := tc.loads[key] == l
+	if current {
+		delete(tc.loads, key)
+	}
+
+	if err != nil {
+		entry, ok := tc.entries[key]
+		if ok && tc.unavailable(err) {
+			tc.stale.Add(1)
+			l.value, l.err = entry.value, fmt.Errorf("%w: %v", cache.ErrStaleData, err)
+			return
+		}
+		l.value, l.err = value, err
+		return
+	}
+
+	l.value = value
+	if current {
+		tc.entries[key] = ttlEntry[V]{
+			value:     value,
+			expiresAt: time.Now().Add(tc.ttl),
+		}
+	}
+}
+
+func (tc *TTLCache[K, V]) Invalidate(keys ...K) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	for _, key := range keys {
+		delete(tc.entries, key)
+		delete(tc.loads, key)
+	}
+}
+
+// InvalidateFunc drops the matched entries, the values of the loadings in
+// progress are unknown yet, so none of them is stored.
+func (tc *TTLCache[K, V]) InvalidateFunc(match func(key K, value V) bool) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	for key, entry := range tc.entries {
+		if match(key, entry.value) {
+			delete(tc.entries, key)
+		}
+	}
+	clear(tc.loads)
+}
+
+func (tc *TTLCache[K, V]) Flush() {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	clear(tc.entries)
+	clear(tc.loads)
+}
+
+func (tc *TTLCache[K, V]) Stats() cache.Stats {
+	tc.mu.RLock()
+	entries := len(tc.entries)
+	tc.mu.RUnlock()
+
+	return cache.Stats{
+		Name:    tc.name,
+		Entries: entries,
+		Hits:    tc.hits.Load(),
+		Misses:  tc.misses.Load(),
+		Stale:   tc.stale.Load(),
+	}
+}
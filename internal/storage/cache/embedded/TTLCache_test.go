@@ -0,0 +1,290 @@
+package embedded
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Izumra/SKUD_OKEI/internal/storage/cache"
+)
+
+var (
+	errUnavailable = errors.New("unavailable")
+	errRejected    = errors.New("rejected")
+)
+
+func isUnavailable(err error) bool {
+	return errors.Is(err, errUnavailable)
+}
+
+func TestTTLCacheGet(t *testing.T) {
+	tests := []struct {
+		name string
+		// cached is the value loaded before the tested call
+		cached    string
+		expired   bool
+		loadValue string
+		loadErr   error
+		want      string
+		wantErr   error
+		wantLoads int
+		wantStats cache.Stats
+	}{
+		{
+			name:      "loads the missed key",
+			loadValue: "v1",
+			want:      "v1",
+			wantLoads: 1,
+			wantStats: cache.Stats{Entries: 1, Misses: 1},
+		},
+		{
+			name:      "serves the cached value",
+			cached:    "v1",
+			loadValue: "v2",
+			want:      "v1",
+			wantStats: cache.Stats{Entries: 1, Hits: 1, Misses: 1},
+		},
+		{
+			name:      "reloads the expired value",
+			cached:    "v1",
+			expired:   true,
+			loadValue: "v2",
+			want:      "v2",
+			wantLoads: 1,
+			wantStats: cache.Stats{Entries: 1, Misses: 2},
+		},
+		{
+			name:      "serves the expired value when the source is unavailable",
+			cached:    "v1",
+			expired:   true,
+			loadErr:   errUnavailable,
+			want:      "v1",
+			wantErr:   cache.ErrStaleData,
+			wantLoads: 1,
+			wantStats: cache.Stats{Entries: 1, Misses: 2, Stale: 1},
+		},
+		{
+			name:      "passes the error of the available source",
+			cached:    "v1",
+			expired:   true,
+			loadErr:   errRejected,
+			wantErr:   errRejected,
+			wantLoads: 1,
+			wantStats: cache.Stats{Entries: 1, Misses: 2},
+		},
+		{
+			name:      "passes the error without the cached value",
+			loadErr:   errUnavailable,
+			wantErr:   errUnavailable,
+			wantLoads: 1,
+			wantStats: cache.Stats{Misses: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ttl := time.Minute
+			if tt.expired {
+				ttl = 10 * time.Millisecond
+			}
+			tc := NewTTLCache[string, string]("test", ttl, isUnavailable, nil)
+			ctx := context.Background()
+
+			if tt.cached != "" {
+				_, err := tc.Get(ctx, "key", func(ctx context.Context) (string, error) {
+					return tt.cached, nil
+				})
+				if err != nil {
+					t.Fatalf("Get() err = %v", err)
+				}
+				if tt.expired {
+					time.Sleep(2 * ttl)
+				}
+			}
+
+			loads := 0
+			got, err := tc.Get(ctx, "key", func(ctx context.Context) (string, error) {
+				loads++
+				return tt.loadValue, tt.loadErr
+			})
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Errorf("Get() err = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Get() = %q, want %q", got, tt.want)
+			}
+			if loads != tt.wantLoads {
+				t.Errorf("loads = %d, want %d", loads, tt.wantLoads)
+			}
+
+			tt.wantStats.Name = "test"
+			if stats := tc.Stats(); stats != tt.wantStats {
+				t.Errorf("Stats() = %+v, want %+v", stats, tt.wantStats)
+			}
+		})
+	}
+}
+
+func TestTTLCacheSingleLoad(t *testing.T) {
+	tc := NewTTLCache[string, string]("test", time.Minute, isUnavailable, nil)
+
+	var loads atomic.Int64
+	release := make(chan struct{})
+	load := func(ctx context.Context) (string, error) {
+		loads.Add(1)
+		<-release
+		return "v1", nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := tc.Get(context.Background(), "key", load)
+			if err != nil || value != "v1" {
+				t.Errorf("Get() = %q, %v, want %q, nil", value, err, "v1")
+			}
+		}()
+	}
+
+	// the misses are counted before they join the loading
+	for tc.Stats().Misses != 5 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if got := loads.Load(); got != 1 {
+		t.Errorf("loads = %d, want 1", got)
+	}
+}
+
+func TestTTLCacheCanceledCaller(t *testing.T) {
+	tc := NewTTLCache[string, string]("test", time.Minute, isUnavailable, nil)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	load := func(ctx context.Context) (string, error) {
+		close(started)
+		select {
+		case <-release:
+			return "v1", nil
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+
+	// the first caller starts the loading and leaves, the second one waits
+	// for the same loading
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := tc.Get(ctx, "key", load)
+		first <- err
+	}()
+	<-started
+
+	second := make(chan string, 1)
+	go func() {
+		value, err := tc.Get(context.Background(), "key", load)
+		if err != nil {
+			t.Errorf("Get() of the second caller err = %v", err)
+		}
+		second <- value
+	}()
+	for tc.Stats().Misses != 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Errorf("Get() of the first caller err = %v, want %v", err, context.Canceled)
+	}
+
+	close(release)
+	if value := <-second; value != "v1" {
+		t.Errorf("Get() of the second caller = %q, want %q", value, "v1")
+	}
+	if stats := tc.Stats(); stats.Entries != 1 {
+		t.Errorf("the loaded value was not stored, %d entries", stats.Entries)
+	}
+}
+
+func TestTTLCacheInvalidateDuringLoad(t *testing.T) {
+	tests := []struct {
+		name       string
+		invalidate func(tc *TTLCache[string, string])
+	}{
+		{
+			name: "Invalidate",
+			invalidate: func(tc *TTLCache[string, string]) {
+				tc.Invalidate("key")
+			},
+		},
+		{
+			name: "InvalidateFunc",
+			invalidate: func(tc *TTLCache[string, string]) {
+				tc.InvalidateFunc(func(key string, value string) bool {
+					return false
+				})
+			},
+		},
+		{
+			name: "Flush",
+			invalidate: func(tc *TTLCache[string, string]) {
+				tc.Flush()
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := NewTTLCache[string, string]("test", time.Minute, isUnavailable, nil)
+			ctx := context.Background()
+
+			// the value loaded before the invalidation is returned to its
+			// callers, but is not stored
+			got, err := tc.Get(ctx, "key", func(ctx context.Context) (string, error) {
+				tt.invalidate(tc)
+				return "old", nil
+			})
+			if err != nil || got != "old" {
+				t.Fatalf("Get() = %q, %v, want %q, nil", got, err, "old")
+			}
+
+			got, err = tc.Get(ctx, "key", func(ctx context.Context) (string, error) {
+				return "new", nil
+			})
+			if err != nil || got != "new" {
+				t.Errorf("Get() = %q, %v, want %q, nil", got, err, "new")
+			}
+		})
+	}
+}
+
+func TestTTLCacheClone(t *testing.T) {
+	tc := NewTTLCache[string, []int]("test", time.Minute, isUnavailable, func(value []int) []int {
+		return append([]int(nil), value...)
+	})
+	load := func(ctx context.Context) ([]int, error) {
+		return []int{1, 2}, nil
+	}
+
+	got, err := tc.Get(context.Background(), "key", load)
+	if err != nil {
+		t.Fatalf("Get() err = %v", err)
+	}
+	got[0] = 10
+
+	got, err = tc.Get(context.Background(), "key", load)
+	if err != nil {
+		t.Fatalf("Get() err = %v", err)
+	}
+	if got[0] != 1 {
+		t.Errorf("Get() = %v, the cached value was changed by the caller", got)
+	}
+}
@@ -4,4 +4,5 @@ import "errors"
 
 var (
 	ErrSessionNotFound = errors.New("Сессия не найдена")
+	ErrStaleData       = errors.New("Служба IntegrServ недоступна, данные получены из кэша")
 )
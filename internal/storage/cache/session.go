@@ -0,0 +1,29 @@
+package cache
+
+import "time"
+
+// SessionTTL is the lifetime of the sessions, the session expires after the
+// absolute TTL from the login or after the idle TTL from the last request.
+// Every request renews the idle TTL.
+type SessionTTL struct {
+	Absolute time.Duration
+	Idle     time.Duration
+}
+
+// WithDefaults replaces the zero values by the defaults, the idle TTL is not
+// longer than the absolute one.
+func (t SessionTTL) WithDefaults() SessionTTL {
+	if t.Absolute <= 0 {
+		t.Absolute = 24 * time.Hour
+	}
+	if t.Idle <= 0 || t.Idle > t.Absolute {
+		t.Idle = t.Absolute
+	}
+	return t
+}
+
+// Expired reports whether the session created and last used at the moments
+// is expired at the moment now.
+func (t SessionTTL) Expired(createdAt, lastSeenAt, now time.Time) bool {
+	return !now.Before(createdAt.Add(t.Absolute)) || !now.Before(lastSeenAt.Add(t.Idle))
+}
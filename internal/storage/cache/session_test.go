@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionTTLWithDefaults(t *testing.T) {
+	tests := []struct {
+		name string
+		ttl  SessionTTL
+		want SessionTTL
+	}{
+		{
+			name: "zero",
+			want: SessionTTL{Absolute: 24 * time.Hour, Idle: 24 * time.Hour},
+		},
+		{
+			name: "idle within the absolute",
+			ttl:  SessionTTL{Absolute: 8 * time.Hour, Idle: time.Hour},
+			want: SessionTTL{Absolute: 8 * time.Hour, Idle: time.Hour},
+		},
+		{
+			name: "idle longer than the absolute",
+			ttl:  SessionTTL{Absolute: time.Hour, Idle: 8 * time.Hour},
+			want: SessionTTL{Absolute: time.Hour, Idle: time.Hour},
+		},
+		{
+			name: "only the idle",
+			ttl:  SessionTTL{Idle: time.Hour},
+			want: SessionTTL{Absolute: 24 * time.Hour, Idle: time.Hour},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.ttl.WithDefaults(); got != tt.want {
+				t.Errorf("WithDefaults() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSessionTTLExpired(t *testing.T) {
+	ttl := SessionTTL{Absolute: 8 * time.Hour, Idle: time.Hour}
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		createdAgo time.Duration
+		seenAgo    time.Duration
+		want       bool
+	}{
+		{name: "fresh", want: false},
+		{name: "before the idle TTL", createdAgo: time.Hour, seenAgo: time.Hour - time.Millisecond, want: false},
+		{name: "at the idle TTL", createdAgo: time.Hour, seenAgo: time.Hour, want: true},
+		{name: "before the absolute TTL", createdAgo: 8*time.Hour - time.Millisecond, want: false},
+		{name: "at the absolute TTL", createdAgo: 8 * time.Hour, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ttl.Expired(now.Add(-tt.createdAgo), now.Add(-tt.seenAgo), now); got != tt.want {
+				t.Errorf("Expired() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,9 @@
+package cache
+
+type Stats struct {
+	Name    string
+	Entries int
+	Hits    int64
+	Misses  int64
+	Stale   int64
+}
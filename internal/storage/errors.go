@@ -5,4 +5,14 @@ import "errors"
 var (
 	ErrUserNotFound = errors.New("Пользователь с такими данными не зарегестрирован")
 	ErrUserExist    = errors.New("Аккаунт с такими данными уже зарегестрирован")
+	ErrArchiveEmpty = errors.New("Архив событий еще не синхронизирован")
+
+	ErrEvacuationNotFound       = errors.New("Эвакуация не найдена")
+	ErrEvacuationActive         = errors.New("Эвакуация уже проводится")
+	ErrEvacuationFinished       = errors.New("Эвакуация уже завершена")
+	ErrEvacuationPersonNotFound = errors.New("Человек не входит в список эвакуации")
+
+	ErrRoleNotFound = errors.New("Роль не найдена")
+	ErrRoleExist    = errors.New("Роль с таким названием уже существует")
+	ErrRoleInUse    = errors.New("Роль назначена пользователям")
 )
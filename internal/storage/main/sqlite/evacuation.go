@@ -0,0 +1,260 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Izumra/SKUD_OKEI/domain/entity"
+	"github.com/Izumra/SKUD_OKEI/internal/storage"
+)
+
+// AddEvacuation saves the evacuation with its persons, only one evacuation
+// may be active at the moment.
+func (s *Storage) AddEvacuation(ctx context.Context, evacuation entity.Evacuation) (int64, error) {
+	op := "storage/sqlite/EvacuationStorage.AddEvacuation"
+	tx, err := s.write.BeginTx(ctx, nil)
+	if err != nil {
+		return -1, err
+	}
+	defer tx.Rollback()
+
+	query := "insert into evacuations(started_at,started_by)values(?,?)"
+	result, err := tx.ExecContext(ctx, query, evacuation.StartedAt.UnixMilli(), evacuation.StartedBy)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return -1, storage.ErrEvacuationActive
+		}
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+
+	query = `insert into evacuation_persons(evacuation_id,person_id,last_name,first_name,middle_name,tab_num,department_id,entered_at,access_point_id)values(?,?,?,?,?,?,?,?,?)`
+	state, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+	defer state.Close()
+
+	for _, person := range evacuation.Persons {
+		_, err = state.ExecContext(
+			ctx,
+			id,
+			person.PersonId,
+			person.LastName,
+			person.FirstName,
+			person.MiddleName,
+			person.TabNum,
+			person.DepartmentId,
+			person.EnteredAt.UnixMilli(),
+			person.AccessPointId,
+		)
+		if err != nil {
+			return -1, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return -1, err
+	}
+
+	return id, nil
+}
+
+func (s *Storage) Evacuation(ctx context.Context, id int64) (*entity.Evacuation, error) {
+	return evacuation(ctx, s.db, "storage/sqlite/EvacuationStorage.Evacuation", "where id=?", id)
+}
+
+func (s *Storage) ActiveEvacuation(ctx context.Context) (*entity.Evacuation, error) {
+	return evacuation(ctx, s.db, "storage/sqlite/EvacuationStorage.ActiveEvacuation", "where finished_at is null")
+}
+
+func evacuation(ctx context.Context, db querier, op string, where string, args ...any) (*entity.Evacuation, error) {
+	query := "select id, started_at, started_by, finished_at, finished_by from evacuations " + where
+	var evacuation entity.Evacuation
+	var startedAt int64
+	var finishedAt sql.NullInt64
+	err := db.QueryRowContext(ctx, query, args...).Scan(
+		&evacuation.Id,
+		&startedAt,
+		&evacuation.StartedBy,
+		&finishedAt,
+		&evacuation.FinishedBy,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrEvacuationNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	evacuation.StartedAt = time.UnixMilli(startedAt)
+	evacuation.FinishedAt = fromNullMilli(finishedAt)
+
+	query = `select person_id, last_name, first_name, middle_name, tab_num, department_id, entered_at, access_point_id, accounted_at, accounted_by
+		from evacuation_persons where evacuation_id=? order by last_name, first_name, middle_name`
+	rows, err := db.QueryContext(ctx, query, evacuation.Id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var person entity.EvacuationPerson
+		var enteredAt int64
+		var accountedAt sql.NullInt64
+		err = rows.Scan(
+			&person.PersonId,
+			&person.LastName,
+			&person.FirstName,
+			&person.MiddleName,
+			&person.TabNum,
+			&person.DepartmentId,
+			&enteredAt,
+			&person.AccessPointId,
+			&accountedAt,
+			&person.AccountedBy,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		person.EnteredAt = time.UnixMilli(enteredAt)
+		person.AccountedAt = fromNullMilli(accountedAt)
+
+		evacuation.Persons = append(evacuation.Persons, person)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &evacuation, nil
+}
+
+// AccountPerson marks the person of the active evacuation as accounted for,
+// the mark is removed when the accountedAt is nil.
+func (s *Storage) AccountPerson(ctx context.Context, evacuationId int64, personId int64, accountedAt *time.Time, accountedBy string) error {
+	op := "storage/sqlite/EvacuationStorage.AccountPerson"
+	tx, err := s.write.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = checkActive(ctx, tx, evacuationId)
+	if err != nil {
+		return err
+	}
+
+	var at sql.NullInt64
+	if accountedAt != nil {
+		at = sql.NullInt64{Int64: accountedAt.UnixMilli(), Valid: true}
+	}
+
+	query := "update evacuation_persons set accounted_at=?, accounted_by=? where evacuation_id=? and person_id=?"
+	result, err := tx.ExecContext(ctx, query, at, accountedBy, evacuationId, personId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return storage.ErrEvacuationPersonNotFound
+	}
+
+	return tx.Commit()
+}
+
+// FinishEvacuation finishes the active evacuation and saves its signed report,
+// the report is made by the func from the evacuation read in the same
+// transaction, so no accounting is lost between the report and the finish.
+func (s *Storage) FinishEvacuation(ctx context.Context, id int64, finishedAt time.Time, finishedBy string, makeReport func(evacuation *entity.Evacuation) ([]byte, string, error)) ([]byte, string, error) {
+	op := "storage/sqlite/EvacuationStorage.FinishEvacuation"
+	tx, err := s.write.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer tx.Rollback()
+
+	err = checkActive(ctx, tx, id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	finished, err := evacuation(ctx, tx, op, "where id=?", id)
+	if err != nil {
+		return nil, "", err
+	}
+	finished.FinishedAt = &finishedAt
+	finished.FinishedBy = finishedBy
+
+	report, signature, err := makeReport(finished)
+	if err != nil {
+		return nil, "", err
+	}
+
+	query := "update evacuations set finished_at=?, finished_by=?, report=?, signature=? where id=?"
+	_, err = tx.ExecContext(ctx, query, finishedAt.UnixMilli(), finishedBy, string(report), signature, id)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, "", err
+	}
+
+	return report, signature, nil
+}
+
+func (s *Storage) EvacuationReport(ctx context.Context, id int64) ([]byte, string, error) {
+	op := "storage/sqlite/EvacuationStorage.EvacuationReport"
+
+	query := "select report, signature from evacuations where id=?"
+	var report, signature sql.NullString
+	err := s.db.QueryRowContext(ctx, query, id).Scan(&report, &signature)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, "", storage.ErrEvacuationNotFound
+		}
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+	if !report.Valid {
+		return nil, "", storage.ErrEvacuationActive
+	}
+
+	return []byte(report.String), signature.String, nil
+}
+
+func checkActive(ctx context.Context, tx *sql.Tx, id int64) error {
+	var finishedAt sql.NullInt64
+	err := tx.QueryRowContext(ctx, "select finished_at from evacuations where id=?", id).Scan(&finishedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.ErrEvacuationNotFound
+		}
+		return err
+	}
+	if finishedAt.Valid {
+		return storage.ErrEvacuationFinished
+	}
+
+	return nil
+}
+
+func fromNullMilli(value sql.NullInt64) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+	t := time.UnixMilli(value.Int64)
+	return &t
+}
@@ -0,0 +1,202 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Izumra/SKUD_OKEI/domain/dto/integrserv"
+	"github.com/Izumra/SKUD_OKEI/domain/entity"
+	"github.com/Izumra/SKUD_OKEI/internal/storage"
+)
+
+func (s *Storage) ArchiveRange(ctx context.Context) (*entity.ArchiveRange, error) {
+	op := "storage/sqlite/EventStorage.ArchiveRange"
+
+	query := "select synced_from, synced_to from events_archive where id=1"
+	var from, to int64
+	err := s.db.QueryRowContext(ctx, query).Scan(&from, &to)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrArchiveEmpty
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &entity.ArchiveRange{
+		From: time.UnixMilli(from),
+		To:   time.UnixMilli(to),
+	}, nil
+}
+
+// ArchiveEvents saves the events and extends the synced range in the single
+// transaction, so the range never covers the events which are not saved. The
+// range is merged with the stored one, because the ingester and the backfill
+// move the opposite bounds. The events archived before the types were stored
+// get the type when they are archived again.
+func (s *Storage) ArchiveEvents(ctx context.Context, events []integrserv.Event, synced entity.ArchiveRange) error {
+	op := "storage/sqlite/EventStorage.ArchiveEvents"
+	tx, err := s.write.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `insert into events(event_id,event_date,pass_mode,last_name,first_name,middle_name,tab_num,person_id,card_no,description,access_point_id,event_type_id)values(?,?,?,?,?,?,?,?,?,?,?,?)
+		on conflict(event_id) do update set event_type_id=excluded.event_type_id where events.event_type_id is null`
+	state, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer state.Close()
+
+	for _, event := range events {
+		_, err = state.ExecContext(
+			ctx,
+			event.EventId,
+			event.EventDate.UnixMilli(),
+			event.PassMode,
+			event.LastName,
+			event.FirstName,
+			event.MiddleName,
+			event.TabNum,
+			event.PersonId,
+			event.CardNo,
+			event.Description,
+			event.AccessPointId,
+			event.EventTypeId,
+		)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	query = `insert into events_archive(id,synced_from,synced_to)values(1,?,?)
+		on conflict(id) do update set synced_from=min(synced_from, excluded.synced_from), synced_to=max(synced_to, excluded.synced_to)`
+	_, err = tx.ExecContext(ctx, query, synced.From.UnixMilli(), synced.To.UnixMilli())
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tx.Commit()
+}
+
+func (s *Storage) ArchivedEvents(ctx context.Context, filter *integrserv.EventFilter) ([]integrserv.Event, error) {
+	op := "storage/sqlite/EventStorage.ArchivedEvents"
+
+	where, args := eventsWhere(filter.BeginTime, filter.EndTime, filter.EventTypes, filter.Persons, filter.EntryPoints)
+
+	limit := filter.Count
+	if limit <= 0 {
+		limit = -1
+	}
+	query := `select event_id,event_date,pass_mode,last_name,first_name,middle_name,tab_num,person_id,card_no,description,access_point_id,event_type_id
+		from events where ` + where + ` order by event_date, seq limit ? offset ?`
+	args = append(args, limit, max(filter.Offset, 0))
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var events []integrserv.Event
+	for rows.Next() {
+		var event integrserv.Event
+		var eventDate int64
+		var eventTypeId sql.NullInt64
+		err = rows.Scan(
+			&event.EventId,
+			&eventDate,
+			&event.PassMode,
+			&event.LastName,
+			&event.FirstName,
+			&event.MiddleName,
+			&event.TabNum,
+			&event.PersonId,
+			&event.CardNo,
+			&event.Description,
+			&event.AccessPointId,
+			&eventTypeId,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		event.EventDate = time.UnixMilli(eventDate)
+		event.EventTypeId = eventTypeId.Int64
+
+		events = append(events, event)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return events, nil
+}
+
+func (s *Storage) ArchivedEventsCount(ctx context.Context, filter *integrserv.EventCountFilter) (int64, error) {
+	op := "storage/sqlite/EventStorage.ArchivedEventsCount"
+
+	where, args := eventsWhere(filter.BeginTime, filter.EndTime, filter.EventTypes, filter.Persons, filter.EntryPoints)
+
+	var count int64
+	err := s.db.QueryRowContext(ctx, "select count(*) from events where "+where, args...).Scan(&count)
+	if err != nil {
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return count, nil
+}
+
+// ArchivedUntyped reports whether the range has the events archived before
+// the types of the events were stored, the archive could not filter them by
+// the type.
+func (s *Storage) ArchivedUntyped(ctx context.Context, begin, end time.Time) (bool, error) {
+	op := "storage/sqlite/EventStorage.ArchivedUntyped"
+
+	query := "select exists(select 1 from events where event_type_id is null and event_date >= ? and event_date < ?)"
+	var untyped bool
+	err := s.db.QueryRowContext(ctx, query, begin.UnixMilli(), end.UnixMilli()).Scan(&untyped)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return untyped, nil
+}
+
+func eventsWhere(begin, end time.Time, eventTypes integrserv.EventTypes, persons integrserv.Persons, entryPoints integrserv.EntryPoints) (string, []any) {
+	conditions := []string{"event_date >= ?", "event_date < ?"}
+	args := []any{begin.UnixMilli(), end.UnixMilli()}
+
+	if len(eventTypes.EventType) != 0 {
+		placeholders := make([]string, 0, len(eventTypes.EventType))
+		for _, eventType := range eventTypes.EventType {
+			placeholders = append(placeholders, "?")
+			args = append(args, eventType.Id)
+		}
+		conditions = append(conditions, "event_type_id in ("+strings.Join(placeholders, ",")+")")
+	}
+
+	if len(persons.PersonData) != 0 {
+		placeholders := make([]string, 0, len(persons.PersonData))
+		for _, person := range persons.PersonData {
+			placeholders = append(placeholders, "?")
+			args = append(args, person.Id)
+		}
+		conditions = append(conditions, "person_id in ("+strings.Join(placeholders, ",")+")")
+	}
+
+	if len(entryPoints.EntryPoint) != 0 {
+		placeholders := make([]string, 0, len(entryPoints.EntryPoint))
+		for _, point := range entryPoints.EntryPoint {
+			placeholders = append(placeholders, "?")
+			args = append(args, point.Id)
+		}
+		conditions = append(conditions, "access_point_id in ("+strings.Join(placeholders, ",")+")")
+	}
+
+	return strings.Join(conditions, " and "), args
+}
@@ -1,7 +1,9 @@
 package sqlite
 
 import (
+	"context"
 	"database/sql"
+	"strings"
 
 	"github.com/Izumra/SKUD_OKEI/lib/config"
 	_ "github.com/mattn/go-sqlite3"
@@ -9,15 +11,38 @@ import (
 
 type Storage struct {
 	db *sql.DB
+	// write is the handle of the transactions which write, they take the
+	// write lock at the begin, so the concurrent transactions wait for each
+	// other instead of failing when the read lock is upgraded. The reads are
+	// made by the db and do not wait for the writes.
+	write *sql.DB
+}
+
+// querier is either the connection or the transaction.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
 }
 
 func NewConnetion(cfg *config.Config) *Storage {
-	db, err := sql.Open(cfg.Db.DriverName, cfg.Db.SourcePath)
+	// the requests wait for the locked database instead of failing
+	source, writeSource := cfg.Db.SourcePath, cfg.Db.SourcePath
+	if cfg.Db.DriverName == "sqlite3" && !strings.Contains(source, "?") {
+		source += "?_busy_timeout=5000"
+		writeSource = source + "&_txlock=immediate"
+	}
+
+	db, err := sql.Open(cfg.Db.DriverName, source)
+	if err != nil {
+		panic(err)
+	}
+	write, err := sql.Open(cfg.Db.DriverName, writeSource)
 	if err != nil {
 		panic(err)
 	}
 
 	return &Storage{
 		db,
+		write,
 	}
 }
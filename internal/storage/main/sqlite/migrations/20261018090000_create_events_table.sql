@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS events(
+    seq INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id VARCHAR(50) NOT NULL UNIQUE,
+    event_date INTEGER NOT NULL,
+    pass_mode INTEGER NOT NULL DEFAULT 0,
+    last_name VARCHAR(100) NOT NULL DEFAULT '',
+    first_name VARCHAR(100) NOT NULL DEFAULT '',
+    middle_name VARCHAR(100) NOT NULL DEFAULT '',
+    tab_num VARCHAR(50) NOT NULL DEFAULT '',
+    person_id INTEGER NOT NULL DEFAULT 0,
+    card_no VARCHAR(50) NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    access_point_id INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS events_event_date ON events(event_date);
+CREATE INDEX IF NOT EXISTS events_person_id ON events(person_id, event_date);
+CREATE TABLE IF NOT EXISTS events_archive(
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    synced_from INTEGER NOT NULL,
+    synced_to INTEGER NOT NULL
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS events_archive;
+DROP INDEX IF EXISTS events_person_id;
+DROP INDEX IF EXISTS events_event_date;
+DROP TABLE IF EXISTS events;
+-- +goose StatementEnd
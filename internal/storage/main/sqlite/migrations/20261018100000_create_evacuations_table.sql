@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS evacuations(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    started_at INTEGER NOT NULL,
+    started_by VARCHAR(50) NOT NULL,
+    finished_at INTEGER,
+    finished_by VARCHAR(50) NOT NULL DEFAULT '',
+    report TEXT,
+    signature VARCHAR(64)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS evacuations_active ON evacuations((finished_at IS NULL)) WHERE finished_at IS NULL;
+CREATE TABLE IF NOT EXISTS evacuation_persons(
+    evacuation_id INTEGER NOT NULL REFERENCES evacuations(id) ON DELETE CASCADE,
+    person_id INTEGER NOT NULL,
+    last_name VARCHAR(100) NOT NULL DEFAULT '',
+    first_name VARCHAR(100) NOT NULL DEFAULT '',
+    middle_name VARCHAR(100) NOT NULL DEFAULT '',
+    tab_num VARCHAR(50) NOT NULL DEFAULT '',
+    department_id INTEGER NOT NULL DEFAULT 0,
+    entered_at INTEGER NOT NULL,
+    access_point_id INTEGER NOT NULL DEFAULT 0,
+    accounted_at INTEGER,
+    accounted_by VARCHAR(50) NOT NULL DEFAULT '',
+    PRIMARY KEY(evacuation_id, person_id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS evacuation_persons;
+DROP INDEX IF EXISTS evacuations_active;
+DROP TABLE IF EXISTS evacuations;
+-- +goose StatementEnd
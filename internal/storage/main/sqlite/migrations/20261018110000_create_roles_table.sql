@@ -0,0 +1,46 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS roles(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(50) NOT NULL UNIQUE
+);
+CREATE TABLE IF NOT EXISTS role_permissions(
+    role_id INTEGER NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    permission VARCHAR(50) NOT NULL,
+    PRIMARY KEY(role_id, permission)
+);
+INSERT INTO roles(id,name) VALUES
+    (0,'Администратор'),
+    (1,'Модератор'),
+    (2,'Студент');
+INSERT INTO role_permissions(role_id,permission) VALUES
+    (0,'persons.read'),
+    (0,'persons.write'),
+    (0,'keys.read'),
+    (0,'keys.issue'),
+    (0,'keys.block'),
+    (0,'events.read'),
+    (0,'monitor.view'),
+    (0,'presence.view'),
+    (0,'evacuations.manage'),
+    (0,'users.manage'),
+    (0,'system.manage'),
+    (1,'persons.read'),
+    (1,'persons.write'),
+    (1,'keys.read'),
+    (1,'keys.issue'),
+    (1,'keys.block'),
+    (1,'events.read'),
+    (1,'monitor.view'),
+    (1,'presence.view'),
+    (1,'evacuations.manage'),
+    (2,'events.read'),
+    (2,'monitor.view');
+UPDATE users SET role=2 WHERE role NOT IN (SELECT id FROM roles);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS roles;
+-- +goose StatementEnd
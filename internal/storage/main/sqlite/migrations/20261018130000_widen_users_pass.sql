@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE users_new(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    username VARCHAR(50) NOT NULL UNIQUE,
+    pass VARCHAR(255) NOT NULL,
+    role INTEGER NOT NULL DEFAULT 2
+);
+INSERT INTO users_new(id,username,pass,role) SELECT id,username,pass,role FROM users;
+DROP TABLE users;
+ALTER TABLE users_new RENAME TO users;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE TABLE users_old(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    username VARCHAR(50) NOT NULL UNIQUE,
+    pass VARCHAR(25) NOT NULL,
+    role INTEGER NOT NULL DEFAULT 2
+);
+INSERT INTO users_old(id,username,pass,role) SELECT id,username,pass,role FROM users;
+DROP TABLE users;
+ALTER TABLE users_old RENAME TO users;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE events ADD COLUMN event_type_id INTEGER;
+CREATE INDEX IF NOT EXISTS events_event_type_id ON events(event_type_id, event_date);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS events_event_type_id;
+ALTER TABLE events DROP COLUMN event_type_id;
+-- +goose StatementEnd
@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+INSERT OR IGNORE INTO role_permissions(role_id,permission) VALUES
+    (0,'departments.all');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM role_permissions WHERE permission='departments.all';
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE sessions DROP COLUMN username;
+ALTER TABLE sessions DROP COLUMN role;
+ALTER TABLE sessions DROP COLUMN departments;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE sessions ADD COLUMN username VARCHAR(50) NOT NULL DEFAULT '';
+ALTER TABLE sessions ADD COLUMN role INTEGER NOT NULL DEFAULT 2;
+ALTER TABLE sessions ADD COLUMN departments TEXT NOT NULL DEFAULT '[]';
+UPDATE sessions SET
+    username=(SELECT username FROM users WHERE users.id=sessions.user_id),
+    role=(SELECT role FROM users WHERE users.id=sessions.user_id),
+    departments=(SELECT '['||coalesce(group_concat(department_id),'')||']' FROM user_departments WHERE user_departments.user_id=sessions.user_id);
+-- +goose StatementEnd
@@ -0,0 +1,213 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Izumra/SKUD_OKEI/domain/entity"
+	valueobject "github.com/Izumra/SKUD_OKEI/domain/value-object"
+	"github.com/Izumra/SKUD_OKEI/internal/storage"
+)
+
+// Roles returns all the roles with their permissions ordered by the id.
+func (s *Storage) Roles(ctx context.Context) ([]entity.Role, error) {
+	op := "storage/sqlite/RoleStorage.Roles"
+
+	rows, err := s.db.QueryContext(ctx, "select id, name from roles order by id")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var roles []entity.Role
+	index := map[valueobject.Role]int{}
+	for rows.Next() {
+		var role entity.Role
+		err = rows.Scan(&role.Id, &role.Name)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		index[role.Id] = len(roles)
+		roles = append(roles, role)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err = s.db.QueryContext(ctx, "select role_id, permission from role_permissions order by role_id, permission")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var roleId valueobject.Role
+		var permission valueobject.Permission
+		err = rows.Scan(&roleId, &permission)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		if i, ok := index[roleId]; ok {
+			roles[i].Permissions = append(roles[i].Permissions, permission)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return roles, nil
+}
+
+func (s *Storage) AddRole(ctx context.Context, role entity.Role) (valueobject.Role, error) {
+	op := "storage/sqlite/RoleStorage.AddRole"
+	tx, err := s.write.BeginTx(ctx, nil)
+	if err != nil {
+		return -1, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "insert into roles(name)values(?)", role.Name)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return -1, storage.ErrRoleExist
+		}
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = setPermissions(ctx, tx, valueobject.Role(id), role.Permissions)
+	if err != nil {
+		return -1, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return -1, err
+	}
+
+	return valueobject.Role(id), nil
+}
+
+// UpdateRole renames the role and replaces its permissions.
+func (s *Storage) UpdateRole(ctx context.Context, role entity.Role) error {
+	op := "storage/sqlite/RoleStorage.UpdateRole"
+	tx, err := s.write.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "update roles set name=? where id=?", role.Name, role.Id)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return storage.ErrRoleExist
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return storage.ErrRoleNotFound
+	}
+
+	_, err = tx.ExecContext(ctx, "delete from role_permissions where role_id=?", role.Id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = setPermissions(ctx, tx, role.Id, role.Permissions)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tx.Commit()
+}
+
+// DeleteRole deletes the role which is not assigned to any user.
+func (s *Storage) DeleteRole(ctx context.Context, id valueobject.Role) error {
+	op := "storage/sqlite/RoleStorage.DeleteRole"
+	tx, err := s.write.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var users int64
+	err = tx.QueryRowContext(ctx, "select count(*) from users where role=?", id).Scan(&users)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if users != 0 {
+		return storage.ErrRoleInUse
+	}
+
+	_, err = tx.ExecContext(ctx, "delete from role_permissions where role_id=?", id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	result, err := tx.ExecContext(ctx, "delete from roles where id=?", id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return storage.ErrRoleNotFound
+	}
+
+	return tx.Commit()
+}
+
+// SetUserRole assigns the existing role to the user.
+func (s *Storage) SetUserRole(ctx context.Context, userId int64, roleId valueobject.Role) error {
+	op := "storage/sqlite/RoleStorage.SetUserRole"
+	tx, err := s.write.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var id int64
+	err = tx.QueryRowContext(ctx, "select id from roles where id=?", roleId).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.ErrRoleNotFound
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	result, err := tx.ExecContext(ctx, "update users set role=? where id=?", roleId, userId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return storage.ErrUserNotFound
+	}
+
+	return tx.Commit()
+}
+
+func setPermissions(ctx context.Context, tx *sql.Tx, id valueobject.Role, permissions []valueobject.Permission) error {
+	if len(permissions) == 0 {
+		return nil
+	}
+
+	state, err := tx.PrepareContext(ctx, "insert or ignore into role_permissions(role_id,permission)values(?,?)")
+	if err != nil {
+		return err
+	}
+	defer state.Close()
+
+	for _, permission := range permissions {
+		_, err = state.ExecContext(ctx, id, permission)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Izumra/SKUD_OKEI/domain/entity"
+	"github.com/Izumra/SKUD_OKEI/internal/storage/cache"
+	"github.com/google/uuid"
+)
+
+// sessionTouch is the least period between the renewals of the session saved
+// to the database, so every request does not write to it. The period is
+// shortened for the short idle TTL.
+const sessionTouch = time.Minute
+
+// SessionStorage keeps the sessions in the database, so they survive the
+// restart of the server. Only the id of the user is saved with the session,
+// the rest of the user is read from the users, so it is always the current.
+type SessionStorage struct {
+	db  *sql.DB
+	ttl cache.SessionTTL
+}
+
+func NewSessionStorage(storage *Storage, ttl cache.SessionTTL) *SessionStorage {
+	return &SessionStorage{
+		db:  storage.db,
+		ttl: ttl.WithDefaults(),
+	}
+}
+
+func (ss *SessionStorage) Create(ctx context.Context, data *entity.User) (string, error) {
+	op := "storage/sqlite/SessionStorage.Create"
+
+	randID, err := uuid.NewV7()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := time.Now().UnixMilli()
+	sessionId := randID.String()
+	_, err = ss.db.ExecContext(ctx, "insert into sessions(id,user_id,created_at,last_seen_at)values(?,?,?,?)",
+		sessionId, data.Id, now, now)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return sessionId, nil
+}
+
+// GetByID returns the user of the session and renews the idle TTL of it, the
+// expired session is removed. The role and the departments are the current
+// ones of the user, so their changes apply to the opened sessions.
+func (ss *SessionStorage) GetByID(ctx context.Context, sessionId string) (*entity.User, error) {
+	op := "storage/sqlite/SessionStorage.GetByID"
+
+	var user entity.User
+	var createdAt, lastSeenAt int64
+	err := ss.db.QueryRowContext(ctx, `select u.id,u.username,u.role,s.created_at,s.last_seen_at
+		from sessions s join users u on u.id=s.user_id where s.id=?`, sessionId).
+		Scan(&user.Id, &user.Username, &user.Role, &createdAt, &lastSeenAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, cache.ErrSessionNotFound)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := time.Now()
+	if ss.ttl.Expired(time.UnixMilli(createdAt), time.UnixMilli(lastSeenAt), now) {
+		_, err = ss.db.ExecContext(ctx, "delete from sessions where id=?", sessionId)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		return nil, fmt.Errorf("%s: %w", op, cache.ErrSessionNotFound)
+	}
+
+	if now.Sub(time.UnixMilli(lastSeenAt)) >= min(sessionTouch, ss.ttl.Idle/10) {
+		_, err = ss.db.ExecContext(ctx, "update sessions set last_seen_at=? where id=?", now.UnixMilli(), sessionId)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	user.Departments, err = userDepartments(ctx, ss.db, user.Id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &user, nil
+}
+
+func (ss *SessionStorage) DeleteByID(ctx context.Context, sessionId string) error {
+	op := "storage/sqlite/SessionStorage.DeleteByID"
+
+	result, err := ss.db.ExecContext(ctx, "delete from sessions where id=?", sessionId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return sessionAffected(op, result)
+}
+
+func (ss *SessionStorage) UpdateByID(ctx context.Context, sessionId string, updatedData *entity.User) error {
+	op := "storage/sqlite/SessionStorage.UpdateByID"
+
+	result, err := ss.db.ExecContext(ctx, "update sessions set user_id=? where id=?", updatedData.Id, sessionId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return sessionAffected(op, result)
+}
+
+// Sweep removes the sessions expired at the moment and returns their count.
+func (ss *SessionStorage) Sweep(ctx context.Context, now time.Time) (int64, error) {
+	op := "storage/sqlite/SessionStorage.Sweep"
+
+	result, err := ss.db.ExecContext(ctx, "delete from sessions where created_at<=? or last_seen_at<=?",
+		now.Add(-ss.ttl.Absolute).UnixMilli(), now.Add(-ss.ttl.Idle).UnixMilli())
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	removed, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return removed, nil
+}
+
+func sessionAffected(op string, result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, cache.ErrSessionNotFound)
+	}
+
+	return nil
+}
@@ -0,0 +1,221 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Izumra/SKUD_OKEI/domain/entity"
+	valueobject "github.com/Izumra/SKUD_OKEI/domain/value-object"
+	"github.com/Izumra/SKUD_OKEI/internal/storage/cache"
+	"github.com/Izumra/SKUD_OKEI/lib/config"
+)
+
+// newStorage opens the empty database migrated the way goose does it.
+func newStorage(t *testing.T) *Storage {
+	t.Helper()
+
+	s := NewConnetion(&config.Config{
+		Db: config.Database{
+			DriverName: "sqlite3",
+			SourcePath: filepath.Join(t.TempDir(), "SKUD.db"),
+		},
+	})
+	t.Cleanup(func() {
+		s.db.Close()
+		s.write.Close()
+	})
+
+	files, err := filepath.Glob("migrations/*.sql")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("the migrations are not found: %v", err)
+	}
+	slices.Sort(files)
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		up, _, _ := strings.Cut(string(data), "-- +goose Down")
+		_, err = s.db.Exec(up)
+		if err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+	}
+
+	return s
+}
+
+func TestSessionStorageExpiry(t *testing.T) {
+	ttl := cache.SessionTTL{
+		Absolute: 8 * time.Hour,
+		Idle:     time.Hour,
+	}
+
+	tests := []struct {
+		name       string
+		createdAgo time.Duration
+		seenAgo    time.Duration
+		wantErr    error
+	}{
+		{
+			name: "fresh",
+		},
+		{
+			name:       "used within the idle TTL",
+			createdAgo: 7 * time.Hour,
+			seenAgo:    59 * time.Minute,
+		},
+		{
+			name:       "idle",
+			createdAgo: 2 * time.Hour,
+			seenAgo:    time.Hour,
+			wantErr:    cache.ErrSessionNotFound,
+		},
+		{
+			name:       "older than the absolute TTL",
+			createdAgo: 8 * time.Hour,
+			seenAgo:    time.Minute,
+			wantErr:    cache.ErrSessionNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newStorage(t)
+			ss := NewSessionStorage(s, ttl)
+			ctx := context.Background()
+
+			userId, err := s.AddUser(ctx, entity.User{Username: "vova", Password: "hash", Role: valueobject.StudentRole})
+			if err != nil {
+				t.Fatalf("AddUser() err = %v", err)
+			}
+			sessionId, err := ss.Create(ctx, &entity.User{Id: userId, Username: "vova", Role: valueobject.StudentRole})
+			if err != nil {
+				t.Fatalf("Create() err = %v", err)
+			}
+
+			now := time.Now()
+			_, err = s.db.Exec("update sessions set created_at=?,last_seen_at=? where id=?",
+				now.Add(-tt.createdAgo).UnixMilli(), now.Add(-tt.seenAgo).UnixMilli(), sessionId)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = ss.GetByID(ctx, sessionId)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("GetByID() err = %v, want %v", err, tt.wantErr)
+			}
+
+			// the expired session is removed, the used one is renewed
+			var count int
+			var lastSeenAt int64
+			err = s.db.QueryRow("select count(*),coalesce(max(last_seen_at),0) from sessions where id=?", sessionId).Scan(&count, &lastSeenAt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantErr != nil {
+				if count != 0 {
+					t.Errorf("the expired session was not removed")
+				}
+				return
+			}
+			if count != 1 || now.Sub(time.UnixMilli(lastSeenAt)) > time.Minute {
+				t.Errorf("the session was not renewed")
+			}
+		})
+	}
+}
+
+func TestSessionStorageSweep(t *testing.T) {
+	s := newStorage(t)
+	ss := NewSessionStorage(s, cache.SessionTTL{
+		Absolute: 8 * time.Hour,
+		Idle:     time.Hour,
+	})
+	ctx := context.Background()
+
+	userId, err := s.AddUser(ctx, entity.User{Username: "vova", Password: "hash", Role: valueobject.StudentRole})
+	if err != nil {
+		t.Fatalf("AddUser() err = %v", err)
+	}
+
+	now := time.Now()
+	ages := []struct {
+		createdAgo time.Duration
+		seenAgo    time.Duration
+	}{
+		{0, 0},
+		{7 * time.Hour, 30 * time.Minute},
+		{2 * time.Hour, 2 * time.Hour},
+		{9 * time.Hour, time.Minute},
+	}
+	for _, age := range ages {
+		sessionId, err := ss.Create(ctx, &entity.User{Id: userId, Username: "vova"})
+		if err != nil {
+			t.Fatalf("Create() err = %v", err)
+		}
+		_, err = s.db.Exec("update sessions set created_at=?,last_seen_at=? where id=?",
+			now.Add(-age.createdAgo).UnixMilli(), now.Add(-age.seenAgo).UnixMilli(), sessionId)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removed, err := ss.Sweep(ctx, now)
+	if err != nil {
+		t.Fatalf("Sweep() err = %v", err)
+	}
+	if removed != 2 {
+		t.Errorf("Sweep() = %d, want 2", removed)
+	}
+}
+
+func TestSessionStorageCurrentUser(t *testing.T) {
+	s := newStorage(t)
+	ss := NewSessionStorage(s, cache.SessionTTL{})
+	ctx := context.Background()
+
+	userId, err := s.AddUser(ctx, entity.User{Username: "vova", Password: "hash", Role: valueobject.StudentRole})
+	if err != nil {
+		t.Fatalf("AddUser() err = %v", err)
+	}
+	sessionId, err := ss.Create(ctx, &entity.User{Id: userId, Username: "vova", Role: valueobject.StudentRole})
+	if err != nil {
+		t.Fatalf("Create() err = %v", err)
+	}
+
+	// the role and the departments changed after the login apply to the
+	// opened session
+	err = s.SetUserRole(ctx, userId, valueobject.Role(1))
+	if err != nil {
+		t.Fatalf("SetUserRole() err = %v", err)
+	}
+	err = s.SetUserDepartments(ctx, userId, []int64{3, 1})
+	if err != nil {
+		t.Fatalf("SetUserDepartments() err = %v", err)
+	}
+
+	user, err := ss.GetByID(ctx, sessionId)
+	if err != nil {
+		t.Fatalf("GetByID() err = %v", err)
+	}
+	if user.Role != valueobject.Role(1) || !slices.Equal(user.Departments, []int64{1, 3}) || user.Password != "" {
+		t.Errorf("GetByID() = %+v, want the role 1 and the departments [1 3] without the password", user)
+	}
+
+	err = s.DeleteUserById(ctx, userId)
+	if err != nil {
+		t.Fatalf("DeleteUserById() err = %v", err)
+	}
+	_, err = ss.GetByID(ctx, sessionId)
+	if !errors.Is(err, cache.ErrSessionNotFound) {
+		t.Errorf("GetByID() of the deleted user err = %v, want %v", err, cache.ErrSessionNotFound)
+	}
+}
@@ -17,6 +17,7 @@ func (s *Storage) UserByID(ctx context.Context, id int64) (*entity.User, error)
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback()
 
 	query := "select * from users where id=?"
 	state, err := tx.PrepareContext(ctx, query)
@@ -42,6 +43,12 @@ func (s *Storage) UserByID(ctx context.Context, id int64) (*entity.User, error)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
+	results.Close()
+
+	user.Departments, err = userDepartments(ctx, tx, user.Id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
 
 	err = tx.Commit()
 	if err != nil {
@@ -56,6 +63,7 @@ func (s *Storage) UserByUsername(ctx context.Context, username string) (*entity.
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback()
 
 	query := "select * from users where username=?"
 	state, err := tx.PrepareContext(ctx, query)
@@ -81,6 +89,12 @@ func (s *Storage) UserByUsername(ctx context.Context, username string) (*entity.
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
+	results.Close()
+
+	user.Departments, err = userDepartments(ctx, tx, user.Id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
 
 	err = tx.Commit()
 	if err != nil {
@@ -91,15 +105,15 @@ func (s *Storage) UserByUsername(ctx context.Context, username string) (*entity.
 
 func (s *Storage) AddUser(ctx context.Context, data entity.User) (int64, error) {
 	op := "storage/sqlite/UserStorage.AddUser"
-	tx, err := s.db.Begin()
+	tx, err := s.write.Begin()
 	if err != nil {
 		return -1, err
 	}
+	defer tx.Rollback()
 
 	query := "insert into users(username,pass,role)values(?,?,?)"
 	state, err := tx.PrepareContext(ctx, query)
 	if err != nil {
-		tx.Rollback()
 		return -1, fmt.Errorf("%s: %w", op, err)
 	}
 	defer state.Close()
@@ -123,12 +137,32 @@ func (s *Storage) AddUser(ctx context.Context, data entity.User) (int64, error)
 
 	return id, nil
 }
+func (s *Storage) UpdatePassword(ctx context.Context, id int64, password string) error {
+	op := "storage/sqlite/UserStorage.UpdatePassword"
+
+	result, err := s.db.ExecContext(ctx, "update users set pass=? where id=?", password, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return storage.ErrUserNotFound
+	}
+
+	return nil
+}
+
 func (s *Storage) DeleteUserById(ctx context.Context, id int64) error {
 	op := "storage/sqlite/UserStorage.DeleteUserById"
-	tx, err := s.db.Begin()
+	tx, err := s.write.Begin()
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
 	query := "delete from users where id=?"
 	state, err := tx.PrepareContext(ctx, query)
@@ -141,6 +175,16 @@ func (s *Storage) DeleteUserById(ctx context.Context, id int64) error {
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
+
+	_, err = tx.ExecContext(ctx, "delete from user_departments where user_id=?", id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, "delete from sessions where user_id=?", id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
 	err = tx.Commit()
 	if err != nil {
 		return err
@@ -148,3 +192,56 @@ func (s *Storage) DeleteUserById(ctx context.Context, id int64) error {
 
 	return nil
 }
+
+// SetUserDepartments replaces the departments the user is linked to.
+func (s *Storage) SetUserDepartments(ctx context.Context, userId int64, departments []int64) error {
+	op := "storage/sqlite/UserStorage.SetUserDepartments"
+	tx, err := s.write.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var id int64
+	err = tx.QueryRowContext(ctx, "select id from users where id=?", userId).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.ErrUserNotFound
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, "delete from user_departments where user_id=?", userId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, department := range departments {
+		_, err = tx.ExecContext(ctx, "insert or ignore into user_departments(user_id,department_id)values(?,?)", userId, department)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func userDepartments(ctx context.Context, db querier, userId int64) ([]int64, error) {
+	rows, err := db.QueryContext(ctx, "select department_id from user_departments where user_id=? order by department_id", userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var departments []int64
+	for rows.Next() {
+		var department int64
+		err = rows.Scan(&department)
+		if err != nil {
+			return nil, err
+		}
+		departments = append(departments, department)
+	}
+
+	return departments, rows.Err()
+}
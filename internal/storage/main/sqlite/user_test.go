@@ -0,0 +1,69 @@
+package sqlite
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Izumra/SKUD_OKEI/domain/entity"
+	valueobject "github.com/Izumra/SKUD_OKEI/domain/value-object"
+)
+
+func TestUserByIDDuringWrite(t *testing.T) {
+	s := newStorage(t)
+	ctx := context.Background()
+
+	userId, err := s.AddUser(ctx, entity.User{Username: "vova", Password: "hash", Role: valueobject.StudentRole})
+	if err != nil {
+		t.Fatalf("AddUser() err = %v", err)
+	}
+
+	// the write transaction holds the write lock until the end of the test
+	tx, err := s.write.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	_, err = tx.ExecContext(ctx, "insert into user_departments(user_id,department_id)values(?,?)", userId, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	user, err := s.UserByID(ctx, userId)
+	if err != nil {
+		t.Fatalf("UserByID() err = %v", err)
+	}
+	if user.Departments != nil {
+		t.Errorf("UserByID() departments = %v, the uncommitted write is read", user.Departments)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("UserByID() waited for the write for %s", elapsed)
+	}
+}
+
+func TestConcurrentWrites(t *testing.T) {
+	s := newStorage(t)
+	ctx := context.Background()
+
+	userId, err := s.AddUser(ctx, entity.User{Username: "vova", Password: "hash", Role: valueobject.StudentRole})
+	if err != nil {
+		t.Fatalf("AddUser() err = %v", err)
+	}
+
+	// the transactions read before they write, they must not fail when the
+	// read lock is upgraded
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(department int64) {
+			defer wg.Done()
+			err := s.SetUserDepartments(ctx, userId, []int64{department})
+			if err != nil {
+				t.Errorf("SetUserDepartments() err = %v", err)
+			}
+		}(int64(i))
+	}
+	wg.Wait()
+}
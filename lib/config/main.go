@@ -1,8 +1,12 @@
 package config
 
 import (
+	"errors"
 	"flag"
+	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -10,12 +14,89 @@ import (
 type Config struct {
 	IntegerServer IntegerServer `yaml:"integer_server"`
 	Server        Server        `yaml:"server"`
+	Session       Session       `yaml:"session"`
+	Password      Password      `yaml:"password"`
 	Db            Database      `yaml:"db"`
+	Cache         Cache         `yaml:"cache"`
+	Archive       Archive       `yaml:"archive"`
+	Poller        Poller        `yaml:"poller"`
+	Monitor       Monitor       `yaml:"monitor"`
+	Presence      Presence      `yaml:"presence"`
+	Evacuation    Evacuation    `yaml:"evacuation"`
 }
 
 type IntegerServer struct {
-	Addr         string `yaml:"address"`
-	TitleService string `yaml:"title_service"`
+	Addr             string        `yaml:"address"`
+	TitleService     string        `yaml:"title_service"`
+	Timeout          time.Duration `yaml:"timeout"`
+	Retries          int           `yaml:"retries"`
+	RetryBackoff     time.Duration `yaml:"retry_backoff"`
+	BreakerThreshold int           `yaml:"breaker_threshold"`
+	BreakerCooldown  time.Duration `yaml:"breaker_cooldown"`
+	MaxConcurrent    int           `yaml:"max_concurrent"`
+	HealthInterval   time.Duration `yaml:"health_interval"`
+	Recovery         Recovery      `yaml:"recovery"`
+}
+
+type Recovery struct {
+	// Strategy is scm, command, http or noop, the empty one is scm on Windows
+	// and noop on the other platforms.
+	Strategy    string        `yaml:"strategy"`
+	Delay       time.Duration `yaml:"delay"`
+	Command     []string      `yaml:"command"`
+	HookURL     string        `yaml:"hook_url"`
+	HookMethod  string        `yaml:"hook_method"`
+	Window      time.Duration `yaml:"window"`
+	MaxAttempts int           `yaml:"max_attempts"`
+}
+
+type Cache struct {
+	DepartmentsTTL time.Duration `yaml:"departments_ttl"`
+	PersonsTTL     time.Duration `yaml:"persons_ttl"`
+	KeysTTL        time.Duration `yaml:"keys_ttl"`
+}
+
+type Archive struct {
+	Interval time.Duration `yaml:"interval"`
+	Lag      time.Duration `yaml:"lag"`
+}
+
+type Poller struct {
+	Interval time.Duration `yaml:"interval"`
+	Overlap  time.Duration `yaml:"overlap"`
+	History  int           `yaml:"history"`
+}
+
+type Monitor struct {
+	DayStart time.Duration `yaml:"day_start"`
+}
+
+type Presence struct {
+	ExpireAfter time.Duration `yaml:"expire_after"`
+}
+
+// Evacuation keeps the secret signing the reports of the evacuations, the
+// secret is never kept in the config, it is taken from the environment
+// variable EVACUATION_SECRET or from the file which is not committed.
+type Evacuation struct {
+	Secret     string `yaml:"-"`
+	SecretFile string `yaml:"secret_file"`
+}
+
+// LoadSecret takes the secret of the reports, the environment variable wins
+// over the file. The missing secret is not an error, the reports are refused
+// to be signed until it is set.
+func (e *Evacuation) LoadSecret() error {
+	e.Secret = strings.TrimSpace(os.Getenv("EVACUATION_SECRET"))
+	if e.Secret == "" && e.SecretFile != "" {
+		secret, err := os.ReadFile(e.SecretFile)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to read the secret of the evacuation reports: %w", err)
+		}
+		e.Secret = strings.TrimSpace(string(secret))
+	}
+
+	return nil
 }
 
 type Server struct {
@@ -23,6 +104,23 @@ type Server struct {
 	IntegerServAddr string `yaml:"integrserv"`
 }
 
+type Session struct {
+	Secret        string        `yaml:"secret"`
+	Storage       string        `yaml:"storage"`
+	TTL           time.Duration `yaml:"ttl"`
+	IdleTTL       time.Duration `yaml:"idle_ttl"`
+	SweepInterval time.Duration `yaml:"sweep_interval"`
+}
+
+type Password struct {
+	MinLength      int  `yaml:"min_length"`
+	RequireUpper   bool `yaml:"require_upper"`
+	RequireLower   bool `yaml:"require_lower"`
+	RequireDigit   bool `yaml:"require_digit"`
+	RequireSpecial bool `yaml:"require_special"`
+	Cost           int  `yaml:"cost"`
+}
+
 type Database struct {
 	DriverName string `yaml:"driver"`
 	SourcePath string `yaml:"source"`
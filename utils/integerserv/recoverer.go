@@ -0,0 +1,133 @@
+package integrserv
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/Izumra/SKUD_OKEI/lib/config"
+)
+
+const (
+	StrategySCM     = "scm"
+	StrategyCommand = "command"
+	StrategyHTTP    = "http"
+	StrategyNoop    = "noop"
+)
+
+var (
+	ErrStrategyUnsupported = errors.New("способ восстановления службы IntegrServ не поддерживается на этой платформе")
+	ErrUnknownStrategy     = errors.New("неизвестный способ восстановления службы IntegrServ")
+	ErrRecoveryConfig      = errors.New("неверные настройки восстановления службы IntegrServ")
+)
+
+type Recoverer interface {
+	Recover(ctx context.Context) error
+}
+
+type Reboot func(ctx context.Context) error
+
+func (r Reboot) Recover(ctx context.Context) error {
+	return r(ctx)
+}
+
+// FromConfig makes the recoverer of the configured strategy, the strategy is
+// the manager of the services on Windows and none on the other platforms by
+// default. The manager of the services configured off Windows is replaced by
+// none instead of failing every attempt.
+func FromConfig(logger *slog.Logger, cfg config.IntegerServer) (*Throttled, error) {
+	op := "utils/integerserv.FromConfig"
+	logger = logger.With(slog.String("op", op))
+
+	recovery := cfg.Recovery
+	if recovery.Strategy == "" {
+		recovery.Strategy = defaultStrategy
+	}
+
+	delay := recovery.Delay
+	if delay <= 0 {
+		delay = 20 * time.Second
+	}
+
+	var recoverer Recoverer
+	switch recovery.Strategy {
+	case StrategySCM:
+		if !scmSupported {
+			logger.Info("The recovery strategy is not supported on the platform, IntegrServ is not restarted", slog.Any("err", fmt.Errorf("%w: %s", ErrStrategyUnsupported, recovery.Strategy)))
+			recovery.Strategy = StrategyNoop
+			recoverer = Noop()
+			break
+		}
+		recoverer = RebootManager(cfg.TitleService, delay)
+	case StrategyCommand:
+		if len(recovery.Command) == 0 {
+			return nil, fmt.Errorf("%w: не задана команда перезапуска", ErrRecoveryConfig)
+		}
+		recoverer = CommandRecoverer(recovery.Command, delay)
+	case StrategyHTTP:
+		if recovery.HookURL == "" {
+			return nil, fmt.Errorf("%w: не задан адрес HTTP хука", ErrRecoveryConfig)
+		}
+		recoverer = HTTPHookRecoverer(recovery.HookMethod, recovery.HookURL, delay)
+	case StrategyNoop:
+		recoverer = Noop()
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownStrategy, recovery.Strategy)
+	}
+
+	return NewThrottled(recovery.Strategy, recoverer, recovery.Window, recovery.MaxAttempts), nil
+}
+
+func CommandRecoverer(command []string, timeout time.Duration) Reboot {
+	return func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		output, err := exec.CommandContext(ctx, command[0], command[1:]...).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("Команда перезапуска службы завершилась с ошибкой: %w: %s", err, strings.TrimSpace(string(output)))
+		}
+
+		return nil
+	}
+}
+
+func HTTPHookRecoverer(method string, url string, timeout time.Duration) Reboot {
+	if method == "" {
+		method = http.MethodPost
+	}
+	client := &http.Client{
+		Timeout: timeout,
+	}
+
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(nil))
+		if err != nil {
+			return err
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return fmt.Errorf("Ошибка вызова HTTP хука перезапуска службы: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return fmt.Errorf("HTTP хук перезапуска службы ответил статусом %s", resp.Status)
+		}
+
+		return nil
+	}
+}
+
+func Noop() Reboot {
+	return func(ctx context.Context) error {
+		return nil
+	}
+}
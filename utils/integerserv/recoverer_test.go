@@ -0,0 +1,70 @@
+package integrserv
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/Izumra/SKUD_OKEI/lib/config"
+)
+
+func TestFromConfig(t *testing.T) {
+	scm := StrategyNoop
+	if scmSupported {
+		scm = StrategySCM
+	}
+
+	tests := []struct {
+		name         string
+		recovery     config.Recovery
+		wantStrategy string
+		wantErr      error
+	}{
+		{
+			name:         "default of the platform",
+			wantStrategy: defaultStrategy,
+		},
+		{
+			name:         "manager of the services",
+			recovery:     config.Recovery{Strategy: StrategySCM},
+			wantStrategy: scm,
+		},
+		{
+			name:         "command",
+			recovery:     config.Recovery{Strategy: StrategyCommand, Command: []string{"true"}},
+			wantStrategy: StrategyCommand,
+		},
+		{
+			name:     "command without the command",
+			recovery: config.Recovery{Strategy: StrategyCommand},
+			wantErr:  ErrRecoveryConfig,
+		},
+		{
+			name:     "hook without the address",
+			recovery: config.Recovery{Strategy: StrategyHTTP},
+			wantErr:  ErrRecoveryConfig,
+		},
+		{
+			name:     "unknown",
+			recovery: config.Recovery{Strategy: "reboot"},
+			wantErr:  ErrUnknownStrategy,
+		},
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recoverer, err := FromConfig(logger, config.IntegerServer{
+				TitleService: "Orion Pro Integration Service",
+				Recovery:     tt.recovery,
+			})
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("FromConfig() err = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && recoverer.Strategy() != tt.wantStrategy {
+				t.Errorf("Strategy() = %s, want %s", recoverer.Strategy(), tt.wantStrategy)
+			}
+		})
+	}
+}
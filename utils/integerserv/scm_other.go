@@ -0,0 +1,23 @@
+//go:build !windows
+// +build !windows
+
+package integrserv
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// scmSupported reports whether the services can be restarted by the
+	// manager of the services on the platform.
+	scmSupported = false
+	// defaultStrategy is used when the strategy is not configured.
+	defaultStrategy = StrategyNoop
+)
+
+func RebootManager(titleService string, delayTry time.Duration) Reboot {
+	return func(ctx context.Context) error {
+		return ErrStrategyUnsupported
+	}
+}
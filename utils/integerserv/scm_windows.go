@@ -19,7 +19,14 @@ var (
 	errServiceControl = errors.New("ошибка отправки сигнала службе")
 )
 
-type Reboot func(ctx context.Context) error
+const (
+	// scmSupported reports whether the services can be restarted by the
+	// manager of the services on the platform.
+	scmSupported = true
+	// defaultStrategy is used when the strategy is not configured, the
+	// service is restarted by the manager of the services as before.
+	defaultStrategy = StrategySCM
+)
 
 func RebootManager(titleService string, delayTry time.Duration) Reboot {
 	var lock sync.Mutex
@@ -0,0 +1,100 @@
+package integrserv
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const historyLimit = 100
+
+var (
+	ErrRecoveryThrottled = errors.New("слишком частые попытки перезапуска службы IntegrServ")
+)
+
+type Attempt struct {
+	Strategy  string
+	StartedAt time.Time
+	Duration  time.Duration
+	Success   bool
+	Error     string
+}
+
+// Throttled records the outcome of every recovery attempt and refuses to run
+// more than maxAttempts of them within the window.
+type Throttled struct {
+	run         sync.Mutex
+	mu          sync.Mutex
+	strategy    string
+	recoverer   Recoverer
+	window      time.Duration
+	maxAttempts int
+	history     []Attempt
+}
+
+func NewThrottled(strategy string, recoverer Recoverer, window time.Duration, maxAttempts int) *Throttled {
+	if window <= 0 {
+		window = 10 * time.Minute
+	}
+	if maxAttempts < 1 {
+		maxAttempts = 3
+	}
+
+	return &Throttled{
+		strategy:    strategy,
+		recoverer:   recoverer,
+		window:      window,
+		maxAttempts: maxAttempts,
+	}
+}
+
+func (t *Throttled) Strategy() string {
+	return t.strategy
+}
+
+func (t *Throttled) Recover(ctx context.Context) error {
+	t.run.Lock()
+	defer t.run.Unlock()
+
+	since := time.Now().Add(-t.window)
+	var recent int
+	for _, attempt := range t.History() {
+		if attempt.StartedAt.After(since) {
+			recent++
+		}
+	}
+	if recent >= t.maxAttempts {
+		return ErrRecoveryThrottled
+	}
+
+	attempt := Attempt{
+		Strategy:  t.strategy,
+		StartedAt: time.Now(),
+	}
+	err := t.recoverer.Recover(ctx)
+	attempt.Duration = time.Since(attempt.StartedAt)
+	attempt.Success = err == nil
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+
+	t.mu.Lock()
+	t.history = append(t.history, attempt)
+	if len(t.history) > historyLimit {
+		t.history = t.history[len(t.history)-historyLimit:]
+	}
+	t.mu.Unlock()
+
+	return err
+}
+
+func (t *Throttled) History() []Attempt {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	history := make([]Attempt, len(t.history))
+	copy(history, t.history)
+
+	return history
+}